@@ -0,0 +1,126 @@
+// Command powsolve solves a hashcash challenge offline and prints the
+// counter, to check difficulty settings or test other implementations.
+//
+//	powsolve -nonce 00112233445566778899aabbccddeeff -difficulty 16
+//	echo '{"nonce":"0011...","difficulty":16,"algorithm":"argon2id"}' | powsolve -stdin
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrInvalidNonce = errors.New("nonce must be 16 hex-encoded bytes")
+	ErrSolveTimeout = errors.New("solve timed out")
+)
+
+type challengeInput struct {
+	Nonce      string `json:"nonce"`
+	Difficulty int    `json:"difficulty"`
+	Algorithm  string `json:"algorithm"`
+	Argon2     *struct {
+		Time      uint32 `json:"time"`
+		MemoryKiB uint32 `json:"memory_kib"`
+		Threads   uint8  `json:"threads"`
+	} `json:"argon2,omitempty"`
+}
+
+type solutionOutput struct {
+	Counter      uint64  `json:"counter"`
+	Attempts     uint64  `json:"attempts"`
+	Duration     string  `json:"duration"`
+	HashesPerSec float64 `json:"hashes_per_sec"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "powsolve:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	input := challengeInput{}
+	flags := flag.NewFlagSet("powsolve", flag.ContinueOnError)
+	fromStdin := flags.Bool("stdin", false, "read the challenge as JSON from stdin")
+	flags.StringVar(&input.Nonce, "nonce", "", "challenge nonce, hex encoded")
+	flags.IntVar(&input.Difficulty, "difficulty", 0, "required leading zero bits")
+	flags.StringVar(&input.Algorithm, "algorithm", protocol.AlgoSHA256.String(), "sha256 or argon2id")
+	timeout := flags.Duration("timeout", time.Minute, "give up after this long")
+	maxAttempts := flags.Uint64("max-attempts", 0, "give up after this many counters; 0 for no limit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *fromStdin {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &input); err != nil {
+			return err
+		}
+	}
+
+	challenge, err := input.challenge()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	solution, stats, err := protocol.SolveChallengeContext(ctx, challenge, protocol.SolveOptions{MaxAttempts: *maxAttempts})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrSolveTimeout
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(stdout).Encode(solutionOutput{
+		Counter:      solution.Counter,
+		Attempts:     stats.Attempts,
+		Duration:     stats.Duration.String(),
+		HashesPerSec: stats.HashesPerSec,
+	})
+}
+
+func (in challengeInput) challenge() (protocol.Challenge, error) {
+	nonce, err := hex.DecodeString(in.Nonce)
+	if err != nil || len(nonce) != protocol.NonceSize {
+		return protocol.Challenge{}, ErrInvalidNonce
+	}
+
+	algorithm, err := protocol.ParseAlgorithm(in.Algorithm)
+	if err != nil {
+		return protocol.Challenge{}, err
+	}
+
+	challenge := protocol.Challenge{
+		Difficulty: in.Difficulty,
+		Algorithm:  algorithm,
+	}
+	copy(challenge.Nonce[:], nonce)
+	if algorithm == protocol.AlgoArgon2id {
+		challenge.Argon2 = protocol.DefaultArgon2Params
+		if in.Argon2 != nil {
+			challenge.Argon2 = protocol.Argon2Params{
+				Time:      in.Argon2.Time,
+				MemoryKiB: in.Argon2.MemoryKiB,
+				Threads:   in.Argon2.Threads,
+			}
+		}
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+)
+
+const testNonce = "00112233445566778899aabbccddeeff"
+
+func TestRunSolvesChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		difficulty int
+		algorithm  protocol.Algorithm
+		argon2     protocol.Argon2Params
+		err        error
+	}{
+		{name: "flags", args: []string{"-nonce", testNonce, "-difficulty", "8"}, difficulty: 8},
+		{
+			name:       "stdin json",
+			args:       []string{"-stdin"},
+			stdin:      `{"nonce":"` + testNonce + `","difficulty":8}`,
+			difficulty: 8,
+		},
+		{
+			name:       "argon2id from stdin",
+			args:       []string{"-stdin"},
+			stdin:      `{"nonce":"` + testNonce + `","difficulty":2,"algorithm":"argon2id","argon2":{"time":1,"memory_kib":64,"threads":1}}`,
+			difficulty: 2,
+			algorithm:  protocol.AlgoArgon2id,
+			argon2:     protocol.Argon2Params{Time: 1, MemoryKiB: 64, Threads: 1},
+		},
+		{name: "short nonce", args: []string{"-nonce", "0011", "-difficulty", "8"}, err: ErrInvalidNonce},
+		{name: "unknown algorithm", args: []string{"-nonce", testNonce, "-algorithm", "md5"}, err: protocol.ErrUnknownAlgorithm},
+		{
+			name: "attempts exhausted",
+			args: []string{"-nonce", testNonce, "-difficulty", "30", "-max-attempts", "100"},
+			err:  protocol.ErrSolveExhausted,
+		},
+		{
+			name: "timeout",
+			args: []string{"-nonce", testNonce, "-difficulty", "60", "-timeout", "10ms"},
+			err:  ErrSolveTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			err := run(tt.args, strings.NewReader(tt.stdin), stdout)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("run = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			out := solutionOutput{}
+			if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+				t.Fatalf("output %q: %v", stdout, err)
+			}
+			challenge := protocol.Challenge{Difficulty: tt.difficulty, Algorithm: tt.algorithm, Argon2: tt.argon2}
+			nonce, _ := hex.DecodeString(testNonce)
+			copy(challenge.Nonce[:], nonce)
+			if !challenge.Satisfies(protocol.Solution{Nonce: challenge.Nonce, Counter: out.Counter}) {
+				t.Fatalf("counter %d does not solve the challenge", out.Counter)
+			}
+			if out.Attempts == 0 || out.Attempts < out.Counter {
+				t.Fatalf("reported %d attempts for counter %d", out.Attempts, out.Counter)
+			}
+		})
+	}
+}
@@ -35,15 +35,14 @@ func RequestWisdom(ctx *client_context.ClientContext) error {
 	}
 
 	challengeRes := responses.ChallengeResponse{}
-	if err := challengeRes.Decode(msg.Data); err != nil {
+	if err := msg.DecodePayload(&challengeRes); err != nil {
 		return err
 	}
 
 	challenge := pow.Challenge{
-		Data:           challengeRes.Data,
-		Timestamp:      challengeRes.Timestamp,
-		Difficulty:     challengeRes.Difficulty,
-		ExpectedPrefix: challengeRes.ExpectedPrefix,
+		Data:       challengeRes.Data,
+		Timestamp:  challengeRes.Timestamp,
+		Difficulty: challengeRes.Difficulty,
 	}
 
 	started := time.Now()
@@ -68,7 +67,7 @@ func RequestWisdom(ctx *client_context.ClientContext) error {
 	}
 
 	wisdomRes := responses.WisdomResponse{}
-	if err := wisdomRes.Decode(msg.Data); err != nil {
+	if err := msg.DecodePayload(&wisdomRes); err != nil {
 		return err
 	}
 
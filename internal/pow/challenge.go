@@ -1,59 +1,70 @@
 package pow
 
 import (
-	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"math/bits"
 	"math/rand"
-	"strings"
 	"time"
 )
 
+var (
+	ErrInvalidDifficulty = errors.New("invalid difficulty")
+)
+
+// Challenge is solved by a nonce whose hash has at least Difficulty leading
+// zero bits, counted MSB-first, so each step doubles the expected work.
 type Challenge struct {
-	Data           [16]byte
-	Timestamp      uint64
-	Difficulty     uint64
-	ExpectedPrefix []byte
+	Data       [16]byte
+	Timestamp  uint64
+	Difficulty uint64
 }
 
 func GenerateChallenge(difficulty uint64) *Challenge {
 	return &Challenge{
-		Data:           generateRandom16xHash(),
-		Timestamp:      uint64(time.Now().Unix()),
-		Difficulty:     difficulty,
-		ExpectedPrefix: generateExpectedPrefix(difficulty),
+		Data:       generateRandom16xHash(),
+		Timestamp:  uint64(time.Now().Unix()),
+		Difficulty: difficulty,
 	}
 }
 
 func NewChallenge(data [16]byte, timestamp uint64, difficulty uint64) *Challenge {
 	return &Challenge{
-		Data:           data,
-		Timestamp:      timestamp,
-		Difficulty:     difficulty,
-		ExpectedPrefix: generateExpectedPrefix(difficulty),
+		Data:       data,
+		Timestamp:  timestamp,
+		Difficulty: difficulty,
 	}
 }
 
-func generateExpectedPrefix(difficulty uint64) []byte {
-	return []byte(strings.Repeat("0", int(difficulty)))
-}
-
 func (c *Challenge) Solve() (uint64, error) {
-	target := c.ExpectedPrefix
-	nonce := uint64(0)
+	if c.Difficulty > sha256.Size*8 {
+		return 0, ErrInvalidDifficulty
+	}
 
-	for {
-		hash := calculateHash(c.Data, c.Timestamp, nonce)
-		if bytes.Equal(hash[:len(target)], target) {
-			return nonce, nil
-		}
+	nonce := uint64(0)
+	for !c.Verify(nonce) {
 		nonce++
 	}
+
+	return nonce, nil
 }
 
 func (c *Challenge) Verify(nonce uint64) bool {
 	hash := calculateHash(c.Data, c.Timestamp, nonce)
-	return bytes.Equal(hash[:len(c.ExpectedPrefix)], c.ExpectedPrefix)
+	return leadingZeroBits(hash) >= c.Difficulty
+}
+
+func leadingZeroBits(hash [sha256.Size]byte) uint64 {
+	zeros := 0
+	for _, b := range hash {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+
+	return uint64(zeros)
 }
 
 func calculateHash(data [16]byte, timestamp uint64, nonce uint64) [32]byte {
@@ -0,0 +1,102 @@
+package pow
+
+import (
+	"math/bits"
+	"slices"
+	"sync"
+	"time"
+)
+
+type DifficultyConfig struct {
+	MinDifficulty   int
+	MaxDifficulty   int
+	TargetSolveTime time.Duration
+	// SamplesWindow is how many observed solve times are collected before
+	// the base difficulty is re-evaluated against TargetSolveTime.
+	SamplesWindow int
+	// RateWindow and RateThreshold describe the connection rate considered
+	// normal; each doubling above it adds one bit of difficulty.
+	RateWindow    time.Duration
+	RateThreshold int
+}
+
+// DifficultyController adapts challenge difficulty to client solve times
+// and to the rate at which challenges are being requested.
+type DifficultyController struct {
+	cfg DifficultyConfig
+
+	mu      sync.Mutex
+	base    int
+	samples []time.Duration
+	issued  []time.Time
+	now     func() time.Time
+}
+
+func NewDifficultyController(cfg DifficultyConfig) *DifficultyController {
+	if cfg.SamplesWindow <= 0 {
+		cfg.SamplesWindow = 1
+	}
+	if cfg.RateThreshold <= 0 {
+		cfg.RateThreshold = 1
+	}
+
+	return &DifficultyController{
+		cfg:     cfg,
+		base:    cfg.MinDifficulty,
+		samples: make([]time.Duration, 0, cfg.SamplesWindow),
+		now:     time.Now,
+	}
+}
+
+// Next records a challenge issuance and returns the difficulty to use for it.
+func (d *DifficultyController) Next() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	d.issued = append(d.issued, now)
+	d.pruneIssued(now)
+
+	difficulty := d.base
+	if rate := len(d.issued); rate > d.cfg.RateThreshold {
+		difficulty += bits.Len(uint(rate/d.cfg.RateThreshold)) - 1
+	}
+
+	return d.clamp(difficulty)
+}
+
+// Observe feeds back how long a client took to solve a challenge.
+func (d *DifficultyController) Observe(solveTime time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.samples = append(d.samples, solveTime)
+	if len(d.samples) < d.cfg.SamplesWindow {
+		return
+	}
+
+	sorted := slices.Clone(d.samples)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+	d.samples = d.samples[:0]
+
+	switch {
+	case median < d.cfg.TargetSolveTime/2:
+		d.base = d.clamp(d.base + 1)
+	case median > d.cfg.TargetSolveTime*2:
+		d.base = d.clamp(d.base - 1)
+	}
+}
+
+func (d *DifficultyController) pruneIssued(now time.Time) {
+	cutoff := now.Add(-d.cfg.RateWindow)
+	idx := 0
+	for idx < len(d.issued) && d.issued[idx].Before(cutoff) {
+		idx++
+	}
+	d.issued = d.issued[idx:]
+}
+
+func (d *DifficultyController) clamp(difficulty int) int {
+	return max(d.cfg.MinDifficulty, min(d.cfg.MaxDifficulty, difficulty))
+}
@@ -0,0 +1,114 @@
+package pow
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeNow makes d read time from the returned advance func.
+func fakeNow(d *DifficultyController) func(time.Duration) {
+	now := time.Unix(0, 0)
+	d.now = func() time.Time { return now }
+	return func(step time.Duration) { now = now.Add(step) }
+}
+
+func TestDifficultyControllerFollowsConnectionRate(t *testing.T) {
+	d := NewDifficultyController(DifficultyConfig{
+		MinDifficulty: 16,
+		MaxDifficulty: 22,
+		RateWindow:    time.Second,
+		RateThreshold: 10,
+	})
+	advance := fakeNow(d)
+
+	// A burst of 80 connections within the window is three doublings over
+	// the threshold.
+	var burst []int
+	for range 80 {
+		burst = append(burst, d.Next())
+		advance(time.Millisecond)
+	}
+	if got := burst[9]; got != 16 {
+		t.Fatalf("difficulty at threshold = %d, want 16", got)
+	}
+	for i := 1; i < len(burst); i++ {
+		if burst[i] < burst[i-1] {
+			t.Fatalf("difficulty dropped during burst: %v", burst)
+		}
+	}
+	if got := burst[len(burst)-1]; got != 19 {
+		t.Fatalf("difficulty after burst = %d, want 19", got)
+	}
+
+	advance(2 * time.Second)
+	if got := d.Next(); got != 16 {
+		t.Fatalf("difficulty after the burst passed = %d, want 16", got)
+	}
+}
+
+func TestDifficultyControllerFollowsSolveTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		solveTime time.Duration
+		want      []int
+	}{
+		{name: "fast clients", solveTime: 10 * time.Millisecond, want: []int{18, 18, 18}},
+		{name: "on target", solveTime: 200 * time.Millisecond, want: []int{17, 17, 17}},
+		{name: "slow clients", solveTime: 2 * time.Second, want: []int{16, 15, 15}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDifficultyController(DifficultyConfig{
+				MinDifficulty:   15,
+				MaxDifficulty:   18,
+				TargetSolveTime: 200 * time.Millisecond,
+				SamplesWindow:   3,
+				RateWindow:      time.Second,
+				RateThreshold:   1000,
+			})
+			d.base = 17
+
+			for i, want := range tt.want {
+				for range 3 {
+					d.Observe(tt.solveTime)
+				}
+				if got := d.Next(); got != want {
+					t.Fatalf("window %d: difficulty = %d, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDifficultyControllerClimbsThenDecays(t *testing.T) {
+	d := NewDifficultyController(DifficultyConfig{
+		MinDifficulty:   16,
+		MaxDifficulty:   24,
+		TargetSolveTime: 200 * time.Millisecond,
+		SamplesWindow:   1,
+		RateWindow:      time.Second,
+		RateThreshold:   5,
+	})
+	advance := fakeNow(d)
+
+	peak := 0
+	for range 40 {
+		peak = max(peak, d.Next())
+		d.Observe(50 * time.Millisecond)
+		advance(10 * time.Millisecond)
+	}
+	if peak <= 16 {
+		t.Fatalf("difficulty never climbed under the flood, peak %d", peak)
+	}
+
+	last := peak
+	for range 40 {
+		advance(time.Second)
+		d.Observe(5 * time.Second)
+		last = d.Next()
+	}
+	if last != 16 {
+		t.Fatalf("difficulty after the flood = %d, want it to decay to 16", last)
+	}
+}
@@ -0,0 +1,101 @@
+package pow
+
+import (
+	"errors"
+	"sync"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrReplayedChallenge = errors.New("challenge already solved")
+)
+
+// DefaultNonceCacheTTL is used when NewNonceCache is given no positive ttl.
+const DefaultNonceCacheTTL = time.Minute
+
+// NonceCache remembers accepted challenge nonces for ttl so that a valid
+// solution cannot be submitted twice. The ttl should match challenge expiry:
+// after that VerifySolution rejects the challenge anyway.
+type NonceCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[[protocol.NonceSize]byte]time.Time
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewNonceCache(ttl time.Duration) *NonceCache {
+	if ttl <= 0 {
+		ttl = DefaultNonceCacheTTL
+	}
+
+	c := &NonceCache{
+		ttl:     ttl,
+		entries: make(map[[protocol.NonceSize]byte]time.Time),
+		done:    make(chan struct{}),
+	}
+
+	go c.sweep()
+
+	return c
+}
+
+// Seen reports whether the nonce was already recorded and records it if not.
+func (c *NonceCache) Seen(nonce [protocol.NonceSize]byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if expiresAt, ok := c.entries[nonce]; ok && now.Before(expiresAt) {
+		return true
+	}
+	c.entries[nonce] = now.Add(c.ttl)
+
+	return false
+}
+
+// Verify checks the solution and rejects challenges that were already accepted.
+func (c *NonceCache) Verify(challenge protocol.Challenge, solution protocol.Solution, now time.Time) error {
+	if err := protocol.VerifySolution(challenge, solution, now); err != nil {
+		return err
+	}
+	if c.Seen(challenge.Nonce) {
+		return ErrReplayedChallenge
+	}
+
+	return nil
+}
+
+func (c *NonceCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *NonceCache) sweep() {
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.evictExpired(now)
+		}
+	}
+}
+
+func (c *NonceCache) evictExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for nonce, expiresAt := range c.entries {
+		if !now.Before(expiresAt) {
+			delete(c.entries, nonce)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package pow
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestNonceCacheEvictsAfterTTL(t *testing.T) {
+	const ttl = time.Minute
+	cache := NewNonceCache(ttl)
+	defer cache.Close()
+
+	nonce := [protocol.NonceSize]byte{1}
+	if cache.Seen(nonce) {
+		t.Fatal("Seen() = true for a new nonce")
+	}
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		kept    bool
+	}{
+		{name: "before ttl", elapsed: ttl - time.Second, kept: true},
+		{name: "at ttl", elapsed: ttl, kept: false},
+		{name: "after ttl", elapsed: 2 * ttl, kept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.mu.Lock()
+			cache.entries[nonce] = time.Now().Add(ttl)
+			cache.mu.Unlock()
+
+			cache.evictExpired(time.Now().Add(tt.elapsed))
+
+			cache.mu.Lock()
+			_, kept := cache.entries[nonce]
+			cache.mu.Unlock()
+			if kept != tt.kept {
+				t.Fatalf("entry kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestNonceCacheSeenExpiresEntry(t *testing.T) {
+	cache := NewNonceCache(time.Minute)
+	defer cache.Close()
+
+	nonce := [protocol.NonceSize]byte{2}
+	cache.Seen(nonce)
+	if !cache.Seen(nonce) {
+		t.Fatal("Seen() = false for a recorded nonce")
+	}
+
+	cache.mu.Lock()
+	cache.entries[nonce] = time.Now().Add(-time.Second)
+	cache.mu.Unlock()
+	if cache.Seen(nonce) {
+		t.Fatal("Seen() = true for an expired nonce")
+	}
+}
+
+func TestNonceCacheVerifyRejectsReplay(t *testing.T) {
+	cache := NewNonceCache(time.Minute)
+	defer cache.Close()
+
+	challenge, err := protocol.NewChallenge(4, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution, err := protocol.SolveChallenge(challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Verify(challenge, solution, time.Now()); err != nil {
+		t.Fatalf("first Verify() = %v", err)
+	}
+	if err := cache.Verify(challenge, solution, time.Now()); !errors.Is(err, ErrReplayedChallenge) {
+		t.Fatalf("second Verify() = %v, want ErrReplayedChallenge", err)
+	}
+}
+
+func TestNewNonceCacheDefaultsTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		cache := NewNonceCache(ttl)
+		if cache.ttl != DefaultNonceCacheTTL {
+			t.Errorf("NewNonceCache(%v).ttl = %v, want %v", ttl, cache.ttl, DefaultNonceCacheTTL)
+		}
+		cache.Close()
+	}
+}
@@ -13,7 +13,7 @@ func GetServerConfig() *ServerConfig {
 	return &ServerConfig{
 		Address:                   "127.0.0.1:12345",
 		MaxMessageSizeBytes:       1024,
-		ChallengeDifficulty:       3,
+		ChallengeDifficulty:       24,
 		MaxConnectionsPerClient:   1000,
 		WorkersAmount:             100,
 		ClientTimeoutMilliseconds: 30000,
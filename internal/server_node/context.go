@@ -17,6 +17,8 @@ type ServerContext struct {
 
 	clientTimeout       time.Duration
 	maxMessageSizeBytes int
+	// requestID of the last received message, echoed back in replies.
+	requestID uint32
 }
 
 func NewServerContext(ctx context.Context, conn net.Conn, maxMessageSizeBytes int, clientTimeout time.Duration) *ServerContext {
@@ -35,25 +37,47 @@ var (
 	ErrClientTimeout       = errors.New("client timeout")
 )
 
+// WaitMessage returns the next client message, answering heartbeat pings
+// transparently so handlers never see them.
 func (ctx *ServerContext) WaitMessage() (*protocol.RawMessage, error) {
+	for {
+		msg, err := ctx.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if msg.Opcode != protocol.OpcodePing {
+			return msg, nil
+		}
+		if err := ctx.SendSuccessMessage(protocol.OpcodePong, nil); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (ctx *ServerContext) readMessage() (*protocol.RawMessage, error) {
 	ctx.Conn.SetReadDeadline(time.Now().Add(ctx.clientTimeout))
-	messageBuff := make([]byte, ctx.maxMessageSizeBytes)
 
 	log.Printf("Waiting for message from client: %s for %s", ctx.Conn.RemoteAddr(), ctx.clientTimeout)
 
-	bytesMessage, err := ctx.Conn.Read(messageBuff)
+	msg, err := protocol.ReadMessage(ctx.Conn, ctx.maxMessageSizeBytes)
 	if err != nil {
-		if errors.Is(err, io.EOF) {
-			return nil, ErrConnectionClosed
-		}
-		if errors.Is(err, os.ErrDeadlineExceeded) {
-			return nil, ErrClientTimeout
-		}
-		return nil, errors.Join(err, ErrFailedToReadMessage)
+		return nil, wrapReadError(err)
 	}
-	log.Printf("Received message from client. [SIZE: %d bytes]", bytesMessage)
+	log.Printf("Received message from client. [SIZE: %d bytes]", len(msg.Frame))
+
+	ctx.requestID = msg.RequestID
+
+	return msg, nil
+}
 
-	return protocol.ParseRawMessage(messageBuff[:bytesMessage])
+func wrapReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrConnectionClosed
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrClientTimeout
+	}
+	return errors.Join(err, ErrFailedToReadMessage)
 }
 
 func (ctx *ServerContext) SendSuccessMessage(opcode uint32, msg protocol.MessageEncoder) error {
@@ -65,11 +89,11 @@ func (ctx *ServerContext) SendFailMessage(opcode uint32, msg protocol.MessageEnc
 }
 
 func (ctx *ServerContext) SendError(opcode uint32) error {
-	return ctx.sendMessage(false, opcode, nil)
+	return ctx.sendMessage(false, opcode, protocol.ErrorResponse{Code: opcode})
 }
 
 func (ctx *ServerContext) sendMessage(success bool, opcode uint32, payload protocol.MessageEncoder) error {
-	rawMessage, err := protocol.BuildRawMessage(success, opcode, payload)
+	rawMessage, err := protocol.BuildRawMessage(success, opcode, payload, protocol.WithRequestID(ctx.requestID))
 	if err != nil {
 		return err
 	}
@@ -20,10 +20,9 @@ func NewServerHandlers(challengeDifficulty uint64) *serverHandlers {
 func (h *serverHandlers) handleRequestWisdom(svrCtx *ServerContext) error {
 	challenge := pow.GenerateChallenge(h.challengeDifficulty)
 	challengeResponse := responses.ChallengeResponse{
-		Data:           challenge.Data,
-		Timestamp:      uint64(challenge.Timestamp),
-		Difficulty:     uint64(challenge.Difficulty),
-		ExpectedPrefix: challenge.ExpectedPrefix,
+		Data:       challenge.Data,
+		Timestamp:  uint64(challenge.Timestamp),
+		Difficulty: uint64(challenge.Difficulty),
 	}
 	svrCtx.SendSuccessMessage(responses.RES_CODE_CHALLENGE, &challengeResponse)
 
@@ -37,7 +36,7 @@ func (h *serverHandlers) handleRequestWisdom(svrCtx *ServerContext) error {
 	}
 
 	challengeProofRequest := requests.ChallengeProofRequest{}
-	if err := challengeProofRequest.Decode(message.Data); err != nil {
+	if err := message.DecodePayload(&challengeProofRequest); err != nil {
 		return err
 	}
 
@@ -2,7 +2,6 @@ package server_node
 
 import (
 	"context"
-	"encoding/binary"
 	"errors"
 	"log"
 	"net"
@@ -135,16 +134,20 @@ func (s *TcpServer) handleNewConnection(conn net.Conn) {
 				log.Printf("Client %s timed out", clientAddress)
 				return
 			}
-			log.Printf("Failed to wait for message: %v", err)
-			continue
+			// A frame that could not be read leaves the stream at an unknown
+			// offset, so nothing after it can be trusted.
+			if errors.Is(err, protocol.ErrMessageTooLarge) {
+				log.Printf("Client %s declared a frame above the size limit, closing connection", clientAddress)
+				return
+			}
+			log.Printf("Failed to wait for message from %s, closing connection: %v", clientAddress, err)
+			return
 		}
 
 		handler, ok := s.handlers[msg.Opcode]
 		if !ok {
 			log.Printf("No handler found for opcode: %d", msg.Opcode)
-			msgBuf := make([]byte, 4)
-			binary.BigEndian.PutUint32(msgBuf, protocol.ERR_CODE_INVALID_OPCODE)
-			conn.Write(msgBuf)
+			serverCtx.SendError(protocol.ERR_CODE_INVALID_OPCODE)
 			continue
 		}
 		handler(serverCtx)
@@ -0,0 +1,70 @@
+package server_node
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestTcpServerClosesConnectionOnProtocolError(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame func(t *testing.T) []byte
+	}{
+		{
+			name: "declared length above limit",
+			frame: func(t *testing.T) []byte {
+				frame, err := protocol.BuildRawMessage(true, protocol.OpcodePing, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				binary.BigEndian.PutUint32(frame[10:14], 1<<20)
+				return frame
+			},
+		},
+		{
+			name: "unsupported version",
+			frame: func(t *testing.T) []byte {
+				frame, err := protocol.BuildRawMessage(true, protocol.OpcodePing, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				frame[0] = 0xff
+				return frame
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewTcpServer(context.Background(), &ServerConfig{
+				MaxMessageSizeBytes:       1024,
+				MaxConnectionsPerClient:   1,
+				WorkersAmount:             1,
+				ClientTimeoutMilliseconds: 5000,
+			})
+
+			client, conn := net.Pipe()
+			defer client.Close()
+			done := make(chan struct{})
+			go func() {
+				server.handleNewConnection(conn)
+				close(done)
+			}()
+
+			client.SetDeadline(time.Now().Add(2 * time.Second))
+			if _, err := client.Write(tt.frame(t)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if _, err := client.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+				t.Fatalf("read = %v, want EOF from the server closing", err)
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrUnknownAlgorithm    = errors.New("unknown pow algorithm")
+	ErrInvalidArgon2Params = errors.New("invalid argon2 parameters")
+)
+
+type Algorithm uint8
+
+const (
+	// AlgoSHA256 is hashcash over sha256(nonce || counter).
+	AlgoSHA256 Algorithm = iota
+	// AlgoArgon2id is hashcash over argon2id(counter, salt = nonce). It is
+	// memory-hard, which narrows the gap between ASICs and ordinary clients.
+	AlgoArgon2id
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgoSHA256:
+		return "sha256"
+	case AlgoArgon2id:
+		return "argon2id"
+	default:
+		return "unknown"
+	}
+}
+
+// CheckInterval is how many counters a solver should try between context
+// checks and progress reports. A memory-hard Argon2id hash costs
+// milliseconds, so it is checked after every one.
+func (a Algorithm) CheckInterval() uint64 {
+	if a == AlgoArgon2id {
+		return 1
+	}
+	return ProgressInterval
+}
+
+// ParseAlgorithm is the inverse of Algorithm.String.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for _, a := range []Algorithm{AlgoSHA256, AlgoArgon2id} {
+		if a.String() == name {
+			return a, nil
+		}
+	}
+
+	return 0, ErrUnknownAlgorithm
+}
+
+type Argon2Params struct {
+	Time      uint32
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// Bounds on Argon2 memory a challenge may ask for. The minimum is what
+// argon2 needs per thread; the maximum keeps a hostile server from making
+// each solver worker allocate gigabytes.
+const (
+	MinArgon2MemoryKiB = 8
+	MaxArgon2MemoryKiB = 256 * 1024
+)
+
+// Validate rejects parameters argon2 cannot run with, or that would cost a
+// solver unbounded memory.
+func (p Argon2Params) Validate() error {
+	if p.Time == 0 || p.Threads == 0 ||
+		p.MemoryKiB < MinArgon2MemoryKiB*uint32(p.Threads) || p.MemoryKiB > MaxArgon2MemoryKiB {
+		return ErrInvalidArgon2Params
+	}
+
+	return nil
+}
+
+// DefaultArgon2Params keeps a single hash in the low milliseconds so that
+// difficulty, not the per-hash cost, dominates the solve time.
+var DefaultArgon2Params = Argon2Params{
+	Time:      1,
+	MemoryKiB: 8 * 1024,
+	Threads:   1,
+}
+
+func (c Challenge) hash(counter uint64) ([sha256.Size]byte, error) {
+	switch c.Algorithm {
+	case AlgoSHA256:
+		return hashcash(c.Nonce, counter), nil
+	case AlgoArgon2id:
+		if err := c.Argon2.Validate(); err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		return argon2idHash(c.Nonce, counter, c.Argon2), nil
+	default:
+		return [sha256.Size]byte{}, ErrUnknownAlgorithm
+	}
+}
+
+func argon2idHash(nonce [NonceSize]byte, counter uint64, params Argon2Params) [sha256.Size]byte {
+	var input [8]byte
+	binary.BigEndian.PutUint64(input[:], counter)
+
+	var out [sha256.Size]byte
+	copy(out[:], argon2.IDKey(input[:], nonce[:], params.Time, params.MemoryKiB, params.Threads, sha256.Size))
+	return out
+}
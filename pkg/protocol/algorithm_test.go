@@ -0,0 +1,62 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArgon2ParamsRejectedFromWire(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		params Argon2Params
+		valid  bool
+	}{
+		{name: "default", params: DefaultArgon2Params, valid: true},
+		{name: "zero time", params: Argon2Params{Time: 0, MemoryKiB: 8 * 1024, Threads: 1}},
+		{name: "zero threads", params: Argon2Params{Time: 1, MemoryKiB: 8 * 1024, Threads: 0}},
+		{name: "memory below minimum", params: Argon2Params{Time: 1, MemoryKiB: MinArgon2MemoryKiB - 1, Threads: 1}},
+		{name: "memory below per-thread minimum", params: Argon2Params{Time: 1, MemoryKiB: MinArgon2MemoryKiB, Threads: 4}},
+		{name: "memory above maximum", params: Argon2Params{Time: 1, MemoryKiB: MaxArgon2MemoryKiB + 1, Threads: 1}},
+		{name: "all zero", params: Argon2Params{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Challenge{
+				Nonce:      [NonceSize]byte{1},
+				Difficulty: 1,
+				Expiry:     now.Add(time.Minute),
+				Algorithm:  AlgoArgon2id,
+				Argon2:     tt.params,
+			}
+
+			err := c.ValidateAt(now)
+			if tt.valid != (err == nil) {
+				t.Fatalf("ValidateAt() = %v, want valid %v", err, tt.valid)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidChallenge) {
+				t.Fatalf("ValidateAt() = %v, want ErrInvalidChallenge", err)
+			}
+
+			buff, err := c.Encode()
+			if err != nil {
+				t.Fatalf("Encode() = %v", err)
+			}
+			err = (&Challenge{}).Decode(buff)
+			if tt.valid != (err == nil) {
+				t.Fatalf("Decode() = %v, want valid %v", err, tt.valid)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidChallenge) {
+				t.Fatalf("Decode() = %v, want ErrInvalidChallenge", err)
+			}
+
+			if !tt.valid {
+				if _, err := SolveChallenge(c, 1); !errors.Is(err, ErrInvalidArgon2Params) {
+					t.Fatalf("SolveChallenge() = %v, want ErrInvalidArgon2Params", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var (
+	ErrInvalidBatchSize             = errors.New("invalid batch size")
+	ErrInvalidBatchChallengePayload = errors.New("invalid batch challenge payload")
+)
+
+// MaxBatchSize is the largest number of quotes one batch can authorize.
+const MaxBatchSize = 1 << 10
+
+var (
+	_ MessageEncoder = BatchChallenge{}
+	_ MessageDecoder = (*BatchChallenge)(nil)
+)
+
+// BatchDifficulty is the difficulty of a challenge authorizing count quotes:
+// every extra bit doubles the expected work, so difficulty+ceil(log2(count))
+// costs about as much as count separate challenges. It is capped at
+// MaxDifficulty.
+func BatchDifficulty(difficulty, count int) int {
+	if count <= 1 {
+		return difficulty
+	}
+
+	return min(difficulty+bits.Len(uint(count-1)), MaxDifficulty)
+}
+
+// BatchChallenge is a challenge whose solution authorizes Count quotes.
+type BatchChallenge struct {
+	Challenge Challenge
+	Count     int
+}
+
+// BatchSolution answers a BatchChallenge. It is an ordinary solution; the
+// batch size is bound by the challenge it solves.
+type BatchSolution = Solution
+
+// Batch challenge payload: count (2 bytes) | challenge payload.
+func (b BatchChallenge) EncodedLen() int {
+	return 2 + challengePayloadSize
+}
+
+func (b BatchChallenge) Encode() ([]byte, error) {
+	if b.Count < 1 || b.Count > MaxBatchSize {
+		return nil, ErrInvalidBatchSize
+	}
+
+	challenge, err := b.Challenge.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	buff := binary.BigEndian.AppendUint16(make([]byte, 0, b.EncodedLen()), uint16(b.Count))
+	return append(buff, challenge...), nil
+}
+
+func (b *BatchChallenge) Decode(buff []byte) error {
+	if len(buff) != b.EncodedLen() {
+		return ErrInvalidBatchChallengePayload
+	}
+
+	count := int(binary.BigEndian.Uint16(buff[:2]))
+	if count < 1 || count > MaxBatchSize {
+		return ErrInvalidBatchSize
+	}
+	if err := b.Challenge.Decode(buff[2:]); err != nil {
+		return errors.Join(err, ErrInvalidBatchChallengePayload)
+	}
+
+	b.Count = count
+	return nil
+}
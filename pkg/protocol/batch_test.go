@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBatchDifficulty(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty int
+		count      int
+		want       int
+	}{
+		{name: "single quote", difficulty: 16, count: 1, want: 16},
+		{name: "two quotes", difficulty: 16, count: 2, want: 17},
+		{name: "four quotes", difficulty: 16, count: 4, want: 18},
+		{name: "five quotes round up", difficulty: 16, count: 5, want: 19},
+		{name: "largest batch", difficulty: 16, count: MaxBatchSize, want: 26},
+		{name: "capped at max difficulty", difficulty: MaxDifficulty - 1, count: 4, want: MaxDifficulty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BatchDifficulty(tt.difficulty, tt.count); got != tt.want {
+				t.Fatalf("BatchDifficulty(%d, %d) = %d, want %d", tt.difficulty, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchChallengeRoundTrip(t *testing.T) {
+	challenge := seededChallenges(1, 18)[0]
+	challenge.Expiry = time.Unix(0, challenge.Expiry.UnixNano())
+
+	tests := []struct {
+		name  string
+		count int
+		err   error
+	}{
+		{name: "batch of 4", count: 4},
+		{name: "largest batch", count: MaxBatchSize},
+		{name: "empty batch", count: 0, err: ErrInvalidBatchSize},
+		{name: "oversized batch", count: MaxBatchSize + 1, err: ErrInvalidBatchSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := BatchChallenge{Challenge: challenge, Count: tt.count}
+			buff, err := sent.Encode()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Encode() = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			got := BatchChallenge{}
+			if err := got.Decode(buff); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, sent) {
+				t.Fatalf("decoded %+v, want %+v", got, sent)
+			}
+		})
+	}
+}
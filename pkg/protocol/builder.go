@@ -0,0 +1,73 @@
+package protocol
+
+import "errors"
+
+var (
+	ErrMissingOpcode  = errors.New("message opcode not set")
+	ErrMissingOutcome = errors.New("message success or failure not set")
+)
+
+// MessageBuilder is a fluent front end to BuildRawMessage:
+//
+//	frame, err := protocol.NewMessage().WithOpcode(op).WithSuccess().WithPayload(enc).Build()
+//
+// The opcode and the outcome (WithSuccess or WithFailure) must be set.
+type MessageBuilder struct {
+	success    bool
+	outcomeSet bool
+	opcode     uint32
+	opcodeSet  bool
+	payload    MessageEncoder
+	opts       []BuildOption
+}
+
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{}
+}
+
+func (b *MessageBuilder) WithOpcode(opcode uint32) *MessageBuilder {
+	b.opcode = opcode
+	b.opcodeSet = true
+	return b
+}
+
+func (b *MessageBuilder) WithSuccess() *MessageBuilder {
+	b.success = true
+	b.outcomeSet = true
+	return b
+}
+
+func (b *MessageBuilder) WithFailure() *MessageBuilder {
+	b.success = false
+	b.outcomeSet = true
+	return b
+}
+
+func (b *MessageBuilder) WithPayload(payload MessageEncoder) *MessageBuilder {
+	b.payload = payload
+	return b
+}
+
+// WithFlags sets extension flags in the header; see the WithFlags option.
+func (b *MessageBuilder) WithFlags(flags MessageFlags) *MessageBuilder {
+	b.opts = append(b.opts, WithFlags(flags))
+	return b
+}
+
+// WithOptions adds build options such as WithChecksum or WithRequestID.
+func (b *MessageBuilder) WithOptions(opts ...BuildOption) *MessageBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build validates the builder and returns the raw frame.
+func (b *MessageBuilder) Build() ([]byte, error) {
+	if !b.opcodeSet {
+		return nil, ErrMissingOpcode
+	}
+	if !b.outcomeSet {
+		return nil, ErrMissingOutcome
+	}
+
+	return BuildRawMessage(b.success, b.opcode, b.payload, b.opts...)
+}
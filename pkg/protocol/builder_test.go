@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() *MessageBuilder
+		// want is the equivalent BuildRawMessage call; nil when Build fails.
+		want func() ([]byte, error)
+		err  error
+	}{
+		{
+			name:  "missing opcode",
+			build: func() *MessageBuilder { return NewMessage().WithSuccess().WithPayload(bytesPayload("x")) },
+			err:   ErrMissingOpcode,
+		},
+		{
+			name:  "missing opcode and outcome",
+			build: func() *MessageBuilder { return NewMessage() },
+			err:   ErrMissingOpcode,
+		},
+		{
+			name:  "missing outcome",
+			build: func() *MessageBuilder { return NewMessage().WithOpcode(3) },
+			err:   ErrMissingOutcome,
+		},
+		{
+			name:  "zero opcode set explicitly",
+			build: func() *MessageBuilder { return NewMessage().WithOpcode(0).WithSuccess() },
+			want:  func() ([]byte, error) { return BuildRawMessage(true, 0, nil) },
+		},
+		{
+			name: "success with payload",
+			build: func() *MessageBuilder {
+				return NewMessage().WithOpcode(3).WithSuccess().WithPayload(bytesPayload("wisdom"))
+			},
+			want: func() ([]byte, error) { return BuildRawMessage(true, 3, bytesPayload("wisdom")) },
+		},
+		{
+			name: "failure with options",
+			build: func() *MessageBuilder {
+				return NewMessage().WithFailure().WithOpcode(ERR_CODE_RATE_LIMITED).
+					WithOptions(WithChecksum(), WithRequestID(9))
+			},
+			want: func() ([]byte, error) {
+				return BuildRawMessage(false, ERR_CODE_RATE_LIMITED, nil, WithChecksum(), WithRequestID(9))
+			},
+		},
+		{
+			name: "last outcome wins",
+			build: func() *MessageBuilder {
+				return NewMessage().WithOpcode(1).WithFailure().WithSuccess()
+			},
+			want: func() ([]byte, error) { return BuildRawMessage(true, 1, nil) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := tt.build().Build()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Build() = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if frame != nil {
+					t.Fatalf("Build() returned a frame with %v", err)
+				}
+				return
+			}
+
+			want, err := tt.want()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(frame, want) {
+				t.Fatalf("built % x, want % x", frame, want)
+			}
+		})
+	}
+}
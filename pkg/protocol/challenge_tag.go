@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidChallengeTag = errors.New("invalid challenge tag")
+)
+
+const TagSize = sha256.Size
+
+// Sign sets the challenge tag to an HMAC-SHA256 of its fields under secret,
+// so the server can recognise its own challenges without storing them.
+func (c *Challenge) Sign(secret []byte) {
+	c.Tag = c.computeTag(secret)
+}
+
+// VerifyTag checks the challenge tag in constant time.
+func (c Challenge) VerifyTag(secret []byte) bool {
+	expected := c.computeTag(secret)
+	return subtle.ConstantTimeCompare(expected[:], c.Tag[:]) == 1
+}
+
+// VerifySignedSolution rejects forged or tampered challenges before doing
+// the proof-of-work check.
+func VerifySignedSolution(c Challenge, s Solution, secret []byte, now time.Time) error {
+	if !c.VerifyTag(secret) {
+		return ErrInvalidChallengeTag
+	}
+
+	return VerifySolution(c, s, now)
+}
+
+func (c Challenge) computeTag(secret []byte) [TagSize]byte {
+	unsigned := c
+	unsigned.Tag = [TagSize]byte{}
+	fields, _ := unsigned.Encode()
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(fields[:challengeFieldsSize])
+
+	var tag [TagSize]byte
+	copy(tag[:], mac.Sum(nil))
+	return tag
+}
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifySignedSolutionRejectsTampering(t *testing.T) {
+	secret := []byte("server secret")
+	signed := seededChallenges(1, 8)[0]
+	signed.Sign(secret)
+	solution, err := SolveChallenge(signed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := signed.Expiry.Add(-time.Second)
+
+	tests := []struct {
+		name   string
+		tamper func(c *Challenge)
+		secret []byte
+		err    error
+	}{
+		{name: "untouched", tamper: func(*Challenge) {}, secret: secret},
+		{name: "tampered nonce", tamper: func(c *Challenge) { c.Nonce[0] ^= 1 }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "lowered difficulty", tamper: func(c *Challenge) { c.Difficulty = 1 }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "extended expiry", tamper: func(c *Challenge) { c.Expiry = c.Expiry.Add(time.Hour) }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "switched algorithm", tamper: func(c *Challenge) { c.Algorithm = AlgoArgon2id }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "tampered tag", tamper: func(c *Challenge) { c.Tag[TagSize-1] ^= 1 }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "unsigned", tamper: func(c *Challenge) { c.Tag = [TagSize]byte{} }, secret: secret, err: ErrInvalidChallengeTag},
+		{name: "wrong secret", tamper: func(*Challenge) {}, secret: []byte("other secret"), err: ErrInvalidChallengeTag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := signed
+			tt.tamper(&challenge)
+
+			err := VerifySignedSolution(challenge, solution, tt.secret, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestChallengeTagSurvivesWire(t *testing.T) {
+	secret := []byte("server secret")
+	challenge := seededChallenges(1, 8)[0]
+	challenge.Sign(secret)
+
+	buff, err := challenge.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := Challenge{}
+	if err := decoded.Decode(buff); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.VerifyTag(secret) {
+		t.Fatal("tag no longer verifies after an encode/decode round trip")
+	}
+}
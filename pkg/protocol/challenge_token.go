@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownChallengeKey = errors.New("unknown challenge key")
+	ErrInvalidTokenPayload = errors.New("invalid challenge token payload")
+)
+
+type ChallengeKey struct {
+	ID     uint32
+	Secret []byte
+}
+
+// Keyring maps key ids to secrets. Keep retired keys in it for at least one
+// challenge TTL after rotating so outstanding tokens still verify.
+type Keyring map[uint32][]byte
+
+// ChallengeToken is a self-contained, signed challenge. The server hands it
+// out and verifies it when it comes back with a solution, without keeping
+// any per-challenge state, so it works across instances sharing a keyring.
+type ChallengeToken struct {
+	KeyID     uint32
+	Challenge Challenge
+}
+
+// IssueChallenge creates a fresh challenge signed under key.
+func IssueChallenge(key ChallengeKey, difficulty int, ttl time.Duration) (ChallengeToken, error) {
+	challenge, err := NewChallenge(difficulty, ttl)
+	if err != nil {
+		return ChallengeToken{}, err
+	}
+
+	token := ChallengeToken{KeyID: key.ID, Challenge: challenge}
+	token.Sign(key.Secret)
+	return token, nil
+}
+
+// Sign sets the challenge tag to an HMAC-SHA256 of the key id and the
+// challenge fields under secret, for challenges built by hand rather than
+// by IssueChallenge.
+func (t *ChallengeToken) Sign(secret []byte) {
+	t.Challenge.Tag = t.computeTag(secret)
+}
+
+// VerifyChallenge checks the token signature with the key it names, then
+// the challenge expiry and the solution.
+func VerifyChallenge(keys Keyring, token ChallengeToken, solution Solution, now time.Time) error {
+	secret, ok := keys[token.KeyID]
+	if !ok {
+		return ErrUnknownChallengeKey
+	}
+
+	expected := token.computeTag(secret)
+	if subtle.ConstantTimeCompare(expected[:], token.Challenge.Tag[:]) != 1 {
+		return ErrInvalidChallengeTag
+	}
+
+	return VerifySolution(token.Challenge, solution, now)
+}
+
+// VerifyTokenSolution verifies a solution that carries its encoded
+// ChallengeToken, so the server needs nothing but the keyring.
+func VerifyTokenSolution(keys Keyring, solution Solution, now time.Time) error {
+	token := ChallengeToken{}
+	if err := token.Decode(solution.Token); err != nil {
+		return err
+	}
+
+	return VerifyChallenge(keys, token, solution, now)
+}
+
+func (t ChallengeToken) computeTag(secret []byte) [TagSize]byte {
+	unsigned := t.Challenge
+	unsigned.Tag = [TagSize]byte{}
+	fields, _ := unsigned.Encode()
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(binary.BigEndian.AppendUint32(nil, t.KeyID))
+	mac.Write(fields[:challengeFieldsSize])
+
+	var tag [TagSize]byte
+	copy(tag[:], mac.Sum(nil))
+	return tag
+}
+
+// Token payload: key id (4 bytes) | challenge payload.
+func (t ChallengeToken) Encode() ([]byte, error) {
+	challenge, err := t.Challenge.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(binary.BigEndian.AppendUint32(nil, t.KeyID), challenge...), nil
+}
+
+func (t *ChallengeToken) Decode(buff []byte) error {
+	if len(buff) < 4 {
+		return ErrInvalidTokenPayload
+	}
+
+	challenge := Challenge{}
+	if err := challenge.Decode(buff[4:]); err != nil {
+		return errors.Join(err, ErrInvalidTokenPayload)
+	}
+
+	t.KeyID = binary.BigEndian.Uint32(buff[:4])
+	t.Challenge = challenge
+	return nil
+}
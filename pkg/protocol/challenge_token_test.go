@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyChallengeToken(t *testing.T) {
+	oldKey := ChallengeKey{ID: 1, Secret: []byte("retired secret")}
+	newKey := ChallengeKey{ID: 2, Secret: []byte("current secret")}
+	keys := Keyring{oldKey.ID: oldKey.Secret, newKey.ID: newKey.Secret}
+
+	issue := func(t *testing.T, key ChallengeKey) (ChallengeToken, Solution) {
+		t.Helper()
+
+		token, err := IssueChallenge(key, 8, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		solution, err := SolveChallenge(token.Challenge, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token, solution
+	}
+
+	tests := []struct {
+		name   string
+		key    ChallengeKey
+		tamper func(token *ChallengeToken)
+		keys   Keyring
+		now    time.Duration
+		err    error
+	}{
+		{name: "current key", key: newKey, keys: keys},
+		{name: "rotated out key still in keyring", key: oldKey, keys: keys},
+		{name: "expired", key: newKey, keys: keys, now: 2 * time.Minute, err: ErrChallengeExpired},
+		{name: "tampered difficulty", key: newKey, keys: keys, tamper: func(tk *ChallengeToken) { tk.Challenge.Difficulty = 1 }, err: ErrInvalidChallengeTag},
+		{name: "tampered expiry", key: newKey, keys: keys, now: 2 * time.Minute, tamper: func(tk *ChallengeToken) {
+			tk.Challenge.Expiry = tk.Challenge.Expiry.Add(time.Hour)
+		}, err: ErrInvalidChallengeTag},
+		{name: "relabelled key id", key: newKey, keys: keys, tamper: func(tk *ChallengeToken) { tk.KeyID = oldKey.ID }, err: ErrInvalidChallengeTag},
+		{name: "wrong key", key: ChallengeKey{ID: newKey.ID, Secret: []byte("guessed")}, keys: keys, err: ErrInvalidChallengeTag},
+		{name: "unknown key id", key: ChallengeKey{ID: 9, Secret: newKey.Secret}, keys: keys, err: ErrUnknownChallengeKey},
+		{name: "key removed from keyring", key: oldKey, keys: Keyring{newKey.ID: newKey.Secret}, err: ErrUnknownChallengeKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, solution := issue(t, tt.key)
+			if tt.tamper != nil {
+				tt.tamper(&token)
+			}
+			now := time.Now().Add(tt.now)
+
+			if err := VerifyChallenge(tt.keys, token, solution, now); !errors.Is(err, tt.err) {
+				t.Fatalf("VerifyChallenge: got %v, want %v", err, tt.err)
+			}
+
+			// The same token travelling inside the solution.
+			encoded, err := token.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			solution.Token = encoded
+			if err := VerifyTokenSolution(tt.keys, solution, now); !errors.Is(err, tt.err) {
+				t.Fatalf("VerifyTokenSolution: got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenSolutionRejectsGarbage(t *testing.T) {
+	for _, token := range [][]byte{nil, {0, 0, 0, 1}, {0, 0, 0, 1, 0xff}} {
+		err := VerifyTokenSolution(Keyring{1: []byte("secret")}, Solution{Token: token}, time.Now())
+		if !errors.Is(err, ErrInvalidTokenPayload) {
+			t.Errorf("token %v: got %v, want ErrInvalidTokenPayload", token, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+)
+
+var (
+	ErrFailedToDecompress = errors.New("failed to decompress payload")
+)
+
+// MaxDecompressedPayloadSize bounds how far a compressed payload may expand,
+// so a small malicious frame cannot exhaust memory.
+const MaxDecompressedPayloadSize = 16 << 20
+
+func compressPayload(payload []byte) ([]byte, error) {
+	var buff bytes.Buffer
+	w := zlib.NewWriter(&buff)
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+func decompressPayload(payload []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToDecompress)
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(r, MaxDecompressedPayloadSize+1))
+	if err != nil {
+		return nil, errors.Join(err, ErrFailedToDecompress)
+	}
+	if len(decompressed) > MaxDecompressedPayloadSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	return decompressed, nil
+}
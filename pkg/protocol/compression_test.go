@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// bytesPayload encodes as itself.
+type bytesPayload []byte
+
+func (p bytesPayload) Encode() ([]byte, error) {
+	return p, nil
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	const minSize = 128
+
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name       string
+		payload    []byte
+		compressed bool
+	}{
+		{name: "1MB of quotes", payload: bytes.Repeat([]byte("Stay hungry, stay foolish. "), (1<<20)/27), compressed: true},
+		{name: "below threshold", payload: bytes.Repeat([]byte("a"), minSize-1)},
+		{name: "incompressible", payload: random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(true, 1, bytesPayload(tt.payload), WithCompression(minSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := msg.HasFlag(MSG_COMPRESSED_FLAG); got != tt.compressed {
+				t.Fatalf("compressed flag %v, want %v", got, tt.compressed)
+			}
+			if wire := len(frame) - HeaderSize; tt.compressed && wire >= len(tt.payload)/10 {
+				t.Fatalf("%d byte payload took %d bytes on the wire", len(tt.payload), wire)
+			} else if !tt.compressed && wire != len(tt.payload) {
+				t.Fatalf("uncompressed payload of %d bytes took %d on the wire", len(tt.payload), wire)
+			}
+			if !bytes.Equal(msg.Data, tt.payload) {
+				t.Fatal("payload changed in the round trip")
+			}
+		})
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	bomb := make([]byte, MaxDecompressedPayloadSize+1)
+	frame, err := BuildRawMessage(true, 1, bytesPayload(bomb), WithCompression(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseRawMessage(frame); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("got %v, want ErrMessageTooLarge", err)
+	}
+}
+
+func TestCorruptCompressedPayload(t *testing.T) {
+	frame, err := BuildRawMessage(true, 1, bytesPayload(bytes.Repeat([]byte("a"), 256)), WithCompression(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame[HeaderSize] ^= 0xff
+
+	if _, err := ParseRawMessage(frame); !errors.Is(err, ErrFailedToDecompress) {
+		t.Fatalf("got %v, want ErrFailedToDecompress", err)
+	}
+}
@@ -0,0 +1,11 @@
+package protocol
+
+// Control opcodes are reserved at the top of the opcode space and are
+// handled by the transport layer on both sides rather than by handlers.
+const (
+	OpcodePing uint32 = 0xFFFFFF00
+	OpcodePong uint32 = 0xFFFFFF01
+	// OpcodeHealthCheck is a liveness probe the server answers right away,
+	// with a success frame of the same opcode, without any PoW.
+	OpcodeHealthCheck uint32 = 0xFFFFFF02
+)
@@ -0,0 +1,28 @@
+package protocol
+
+import (
+	"math"
+	"time"
+)
+
+// ExpectedAttempts is the mean number of counters a solver tries before
+// finding a hash with difficulty leading zero bits.
+func ExpectedAttempts(difficulty int) float64 {
+	return math.Exp2(float64(difficulty))
+}
+
+// EstimateSolveTime is the mean time to solve a challenge of the given
+// difficulty at hashesPerSec. It returns zero for a non-positive rate and
+// saturates instead of overflowing.
+func EstimateSolveTime(difficulty int, hashesPerSec float64) time.Duration {
+	if hashesPerSec <= 0 {
+		return 0
+	}
+
+	seconds := ExpectedAttempts(difficulty) / hashesPerSec
+	if seconds >= float64(math.MaxInt64)/float64(time.Second) {
+		return math.MaxInt64
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExpectedAttempts(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty int
+		want       float64
+	}{
+		{name: "difficulty 0", difficulty: 0, want: 1},
+		{name: "difficulty 1", difficulty: 1, want: 2},
+		{name: "difficulty 10", difficulty: 10, want: 1024},
+		{name: "difficulty 20", difficulty: 20, want: 1 << 20},
+		{name: "max difficulty", difficulty: MaxDifficulty, want: math.Exp2(MaxDifficulty)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpectedAttempts(tt.difficulty); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateSolveTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		difficulty   int
+		hashesPerSec float64
+		want         time.Duration
+	}{
+		{name: "one second of work", difficulty: 20, hashesPerSec: 1 << 20, want: time.Second},
+		{name: "sub-second", difficulty: 10, hashesPerSec: 1 << 20, want: time.Second / 1024},
+		{name: "slow hasher", difficulty: 4, hashesPerSec: 2, want: 8 * time.Second},
+		{name: "zero rate", difficulty: 20, want: 0},
+		{name: "negative rate", difficulty: 20, hashesPerSec: -1, want: 0},
+		{name: "saturates", difficulty: MaxDifficulty, hashesPerSec: 1, want: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateSolveTime(tt.difficulty, tt.hashesPerSec); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateSolveTimeScales(t *testing.T) {
+	const rate = 1e6
+	for difficulty := 1; difficulty <= 30; difficulty++ {
+		prev := EstimateSolveTime(difficulty-1, rate)
+		got := EstimateSolveTime(difficulty, rate)
+		// Truncation to whole nanoseconds allows one nanosecond of slack.
+		if diff := got - 2*prev; diff < -1 || diff > 1 {
+			t.Fatalf("difficulty %d: %v is not twice %v", difficulty, got, prev)
+		}
+		if doubled := EstimateSolveTime(difficulty, 2*rate); doubled-got/2 < -1 || doubled-got/2 > 1 {
+			t.Fatalf("difficulty %d: doubling the rate gave %v, want half of %v", difficulty, doubled, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrInvalidErrorPayload = errors.New("invalid error response payload")
+)
+
+// ErrorResponse is the payload of a failure frame. The frame opcode carries
+// the same code so clients that ignore the payload still see it.
+type ErrorResponse struct {
+	Code    uint32
+	Message string
+}
+
+// Error response payload: code (4 bytes) | message (UTF-8, the rest, may be
+// empty).
+func (e ErrorResponse) Encode() ([]byte, error) {
+	buff := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(e.Message)), e.Code)
+	return append(buff, e.Message...), nil
+}
+
+func (e *ErrorResponse) Decode(buff []byte) error {
+	if len(buff) < 4 {
+		return ErrInvalidErrorPayload
+	}
+
+	e.Code = binary.BigEndian.Uint32(buff[:4])
+	e.Message = string(buff[4:])
+	return nil
+}
+
+// IsError reports whether the frame is a failure, the inverse of IsSuccess.
+func (m *RawMessage) IsError() bool {
+	return m.IsFailure()
+}
+
+// ErrorPayload returns the code and message of a failure frame. Frames
+// without an ErrorResponse payload yield the frame opcode and no message.
+func (m *RawMessage) ErrorPayload() (code uint32, message string) {
+	resp := ErrorResponse{}
+	if err := m.DecodePayload(&resp); err != nil {
+		return m.Opcode, ""
+	}
+
+	return resp.Code, resp.Message
+}
@@ -0,0 +1,76 @@
+package protocol
+
+import "testing"
+
+func TestDecodeErrorFrame(t *testing.T) {
+	tests := []struct {
+		name        string
+		success     bool
+		opcode      uint32
+		payload     MessageEncoder
+		wantError   bool
+		wantCode    uint32
+		wantMessage string
+	}{
+		{
+			name:        "code and message",
+			opcode:      ERR_CODE_RATE_LIMITED,
+			payload:     ErrorResponse{Code: ERR_CODE_RATE_LIMITED, Message: "slow down"},
+			wantError:   true,
+			wantCode:    ERR_CODE_RATE_LIMITED,
+			wantMessage: "slow down",
+		},
+		{
+			name:      "empty message",
+			opcode:    ERR_CODE_SERVER_BUSY,
+			payload:   ErrorResponse{Code: ERR_CODE_SERVER_BUSY},
+			wantError: true,
+			wantCode:  ERR_CODE_SERVER_BUSY,
+		},
+		{
+			name:      "no payload falls back to the opcode",
+			opcode:    ERR_CODE_INVALID_OPCODE,
+			wantError: true,
+			wantCode:  ERR_CODE_INVALID_OPCODE,
+		},
+		{
+			name:      "short payload falls back to the opcode",
+			opcode:    ERR_CODE_INVALID_SESSION,
+			payload:   bytesPayload{0, 1},
+			wantError: true,
+			wantCode:  ERR_CODE_INVALID_SESSION,
+		},
+		{
+			name:    "success frame",
+			success: true,
+			opcode:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(tt.success, tt.opcode, tt.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if msg.IsError() != tt.wantError {
+				t.Fatalf("IsError() = %v, want %v", msg.IsError(), tt.wantError)
+			}
+			if msg.IsError() == msg.IsSuccess() {
+				t.Fatal("IsError() is not the inverse of IsSuccess()")
+			}
+			if !tt.wantError {
+				return
+			}
+			code, message := msg.ErrorPayload()
+			if code != tt.wantCode || message != tt.wantMessage {
+				t.Fatalf("ErrorPayload() = %d, %q; want %d, %q", code, message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -3,4 +3,10 @@ package protocol
 const (
 	ERR_CODE_INVALID_OPCODE          uint32 = 1
 	ERR_CODE_INVALID_CHALLENGE_PROOF uint32 = 2
+	ERR_CODE_UNSUPPORTED_ALGORITHM   uint32 = 3
+	ERR_CODE_RATE_LIMITED            uint32 = 4
+	ERR_CODE_INVALID_SESSION         uint32 = 5
+	ERR_CODE_SERVER_BUSY             uint32 = 6
+	ERR_CODE_UNKNOWN_CATEGORY        uint32 = 7
+	ERR_CODE_INVALID_BATCH           uint32 = 8
 )
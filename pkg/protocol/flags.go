@@ -7,20 +7,30 @@ func EmptyMessageFlags() MessageFlags {
 }
 
 // Message flags
-// Only first flag is used for now to identify success/failure of message.
+// First flag identifies success/failure of message.
+// Second flag marks that a CRC32 checksum trails the payload.
+// Third flag marks that the payload is JSON encoded.
+// Fourth flag marks that the payload is zlib compressed.
 // Other flags are reserved for future use.
 // All operations for operating flags are implemented using bitwise operations.
 const (
-	MSG_FAIL_FLAG MessageFlags = 1 << iota // 00000001
-	FLAG_2                                 // 00000010
-	FLAG_3                                 // 00000100
-	FLAG_4                                 // 00001000
-	FLAG_5                                 // 00010000
-	FLAG_6                                 // 00100000
-	FLAG_7                                 // 01000000
-	FLAG_8                                 // 10000000
+	MSG_FAIL_FLAG       MessageFlags = 1 << iota // 00000001
+	MSG_CHECKSUM_FLAG                            // 00000010
+	MSG_JSON_FLAG                                // 00000100
+	MSG_COMPRESSED_FLAG                          // 00001000
+	FLAG_5                                       // 00010000
+	FLAG_6                                       // 00100000
+	FLAG_7                                       // 01000000
+	FLAG_8                                       // 10000000
 )
 
+// MSG_ERROR_FLAG is the name error handling code uses for MSG_FAIL_FLAG.
+const MSG_ERROR_FLAG = MSG_FAIL_FLAG
+
+// builtinFlags are derived by BuildRawMessage from the outcome, options and
+// payload; WithFlags cannot set them.
+const builtinFlags = MSG_FAIL_FLAG | MSG_CHECKSUM_FLAG | MSG_JSON_FLAG | MSG_COMPRESSED_FLAG
+
 func (f *MessageFlags) SetFlag(flag MessageFlags) {
 	*f = *f | flag
 }
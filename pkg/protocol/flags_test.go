@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+var allFlags = []MessageFlags{
+	MSG_FAIL_FLAG, MSG_CHECKSUM_FLAG, MSG_JSON_FLAG, MSG_COMPRESSED_FLAG,
+	FLAG_5, FLAG_6, FLAG_7, FLAG_8,
+}
+
+// TestFlagCombinationsRoundTrip builds a frame for every combination of
+// header flags, deriving each built-in flag the way callers set it, and
+// checks the parsed frame reports exactly that combination.
+func TestFlagCombinationsRoundTrip(t *testing.T) {
+	// Compressible, so WithCompression always sets MSG_COMPRESSED_FLAG.
+	text := bytes.Repeat([]byte("flag "), 64)
+
+	for combination := range 1 << len(allFlags) {
+		want := MessageFlags(combination)
+		t.Run(fmt.Sprintf("%08b", combination), func(t *testing.T) {
+			var payload MessageEncoder = bytesPayload(text)
+			if want.HasFlag(MSG_JSON_FLAG) {
+				payload = JSONEncoder{Value: string(text)}
+			}
+			opts := []BuildOption{WithFlags(want &^ builtinFlags)}
+			if want.HasFlag(MSG_CHECKSUM_FLAG) {
+				opts = append(opts, WithChecksum())
+			}
+			if want.HasFlag(MSG_COMPRESSED_FLAG) {
+				opts = append(opts, WithCompression(0))
+			}
+
+			frame, err := BuildRawMessage(!want.HasFlag(MSG_FAIL_FLAG), 1, payload, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if MessageFlags(msg.Flags) != want {
+				t.Fatalf("flags %08b, want %08b", msg.Flags, want)
+			}
+			for _, flag := range allFlags {
+				if msg.HasFlag(flag) != want.HasFlag(flag) {
+					t.Fatalf("HasFlag(%08b) = %v, want %v", flag, msg.HasFlag(flag), want.HasFlag(flag))
+				}
+			}
+			if msg.IsFailure() != want.HasFlag(MSG_FAIL_FLAG) || msg.IsJSON() != want.HasFlag(MSG_JSON_FLAG) {
+				t.Fatalf("IsFailure %v, IsJSON %v for flags %08b", msg.IsFailure(), msg.IsJSON(), want)
+			}
+
+			wantData, err := payload.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(msg.Data, wantData) {
+				t.Fatalf("payload %q, want %q", msg.Data, wantData)
+			}
+		})
+	}
+}
+
+func TestWithFlagsIgnoresBuiltinFlags(t *testing.T) {
+	frame, err := BuildRawMessage(true, 1, bytesPayload("x"), WithFlags(builtinFlags|FLAG_6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ParseRawMessage(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MessageFlags(msg.Flags) != FLAG_6 {
+		t.Fatalf("flags %08b, want only FLAG_6", msg.Flags)
+	}
+}
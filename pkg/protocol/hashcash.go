@@ -0,0 +1,232 @@
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var (
+	ErrInvalidDifficulty   = errors.New("invalid difficulty")
+	ErrSolveExhausted      = errors.New("solve attempts exhausted")
+	ErrChallengeExpired    = errors.New("challenge expired")
+	ErrInvalidSolution     = errors.New("invalid solution")
+	ErrInvalidChallenge    = errors.New("invalid challenge")
+	ErrInsufficientEntropy = errors.New("nonce source ran out of bytes")
+
+	ErrZeroDifficulty = fmt.Errorf("%w: zero difficulty", ErrInvalidChallenge)
+	ErrEmptyNonce     = fmt.Errorf("%w: empty nonce", ErrInvalidChallenge)
+)
+
+const (
+	NonceSize     = 16
+	MaxDifficulty = sha256.Size * 8
+)
+
+// Challenge is a hashcash proof-of-work puzzle: find a counter such that
+// hash(nonce, counter) has at least Difficulty leading zero bits, where the
+// hash is chosen by Algorithm.
+type Challenge struct {
+	Nonce      [NonceSize]byte
+	Difficulty int
+	Expiry     time.Time
+	Algorithm  Algorithm
+	// Argon2 is only used when Algorithm is AlgoArgon2id.
+	Argon2 Argon2Params
+	// Tag is an HMAC over the other fields, see Sign. Zero when unsigned.
+	Tag [TagSize]byte
+}
+
+// Solution carries the nonce of the challenge it solves, so it can be
+// matched to that challenge, and optionally the token the challenge came
+// with, see VerifyTokenSolution.
+type Solution struct {
+	Nonce   [NonceSize]byte
+	Counter uint64
+	// Category of the quote asked for; empty for any.
+	Category string
+	Token    []byte
+}
+
+func NewChallenge(difficulty int, ttl time.Duration) (Challenge, error) {
+	return NewChallengeFrom(rand.Reader, difficulty, ttl)
+}
+
+// NewChallengeFrom is NewChallenge with the nonce read from source, e.g. a
+// fixed reader for reproducible tests. A source that runs dry before
+// NonceSize bytes fails with ErrInsufficientEntropy.
+func NewChallengeFrom(source io.Reader, difficulty int, ttl time.Duration) (Challenge, error) {
+	if difficulty < 0 || difficulty > MaxDifficulty {
+		return Challenge{}, ErrInvalidDifficulty
+	}
+
+	c := Challenge{
+		Difficulty: difficulty,
+		Expiry:     time.Now().Add(ttl),
+	}
+	if _, err := io.ReadFull(source, c.Nonce[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Challenge{}, ErrInsufficientEntropy
+		}
+		return Challenge{}, err
+	}
+
+	return c, nil
+}
+
+// ProgressInterval is how many SHA-256 counters SolveChallengeContext tries
+// between progress callbacks and context checks. Argon2id hashes are
+// checked one by one; see Algorithm.CheckInterval.
+const ProgressInterval = 1 << 12
+
+type SolveOptions struct {
+	// MaxAttempts gives up with ErrSolveExhausted after that many counters,
+	// which bounds the CPU a server can make a client spend. Zero means no
+	// limit.
+	MaxAttempts uint64
+	// OnProgress, when set, is called on the solving goroutine with the
+	// number of counters tried so far, every CheckInterval counters.
+	OnProgress func(attempts uint64)
+}
+
+// SolveStats describes how much work a solve took.
+type SolveStats struct {
+	// Attempts is the number of counters hashed.
+	Attempts     uint64
+	Duration     time.Duration
+	HashesPerSec float64
+}
+
+func newSolveStats(attempts uint64, started time.Time) SolveStats {
+	stats := SolveStats{Attempts: attempts, Duration: time.Since(started)}
+	if stats.Duration > 0 {
+		stats.HashesPerSec = float64(attempts) / stats.Duration.Seconds()
+	}
+	return stats
+}
+
+// SolveChallenge tries counters from zero up. With a non-zero maxAttempts
+// it gives up with ErrSolveExhausted after that many.
+func SolveChallenge(c Challenge, maxAttempts uint64) (Solution, error) {
+	solution, _, err := SolveChallengeContext(context.Background(), c, SolveOptions{MaxAttempts: maxAttempts})
+	return solution, err
+}
+
+// SolveChallengeContext is SolveChallenge with progress reporting and solve
+// statistics, giving up with ctx.Err() once ctx is done. ctx is checked
+// every Algorithm.CheckInterval counters. The stats are also returned with an
+// error, covering the work done until then.
+func SolveChallengeContext(ctx context.Context, c Challenge, opts SolveOptions) (Solution, SolveStats, error) {
+	if c.Difficulty < 0 || c.Difficulty > MaxDifficulty {
+		return Solution{}, SolveStats{}, ErrInvalidDifficulty
+	}
+	if _, err := c.hash(0); err != nil {
+		return Solution{}, SolveStats{}, err
+	}
+
+	started := time.Now()
+	maxAttempts := opts.MaxAttempts
+	interval := c.Algorithm.CheckInterval()
+	for counter := uint64(0); ; counter++ {
+		if counter > 0 && counter%interval == 0 {
+			if err := ctx.Err(); err != nil {
+				return Solution{}, newSolveStats(counter, started), err
+			}
+			if opts.OnProgress != nil {
+				opts.OnProgress(counter)
+			}
+		}
+		if c.Satisfies(Solution{Counter: counter}) {
+			return Solution{Nonce: c.Nonce, Counter: counter}, newSolveStats(counter+1, started), nil
+		}
+		if counter == math.MaxUint64 || (maxAttempts > 0 && counter+1 >= maxAttempts) {
+			return Solution{}, newSolveStats(counter+1, started), ErrSolveExhausted
+		}
+	}
+}
+
+// VerifySolution checks that the challenge has not expired at now and that
+// the solution meets its difficulty. The hash is recomputed from the
+// challenge's own nonce and the fixed-width counter, nonce || big-endian
+// uint64, so nothing else in the solution influences the preimage.
+func VerifySolution(c Challenge, s Solution, now time.Time) error {
+	if now.After(c.Expiry) {
+		return ErrChallengeExpired
+	}
+	if s.Nonce != c.Nonce || !c.Satisfies(s) {
+		return ErrInvalidSolution
+	}
+
+	return nil
+}
+
+// Validate sanity checks a received challenge before any work is spent on
+// it. A zero difficulty or nonce points at a misconfigured server rather
+// than a real challenge.
+func (c Challenge) Validate() error {
+	return c.ValidateAt(time.Now())
+}
+
+// ValidateAt is Validate with expiry checked against now.
+func (c Challenge) ValidateAt(now time.Time) error {
+	switch {
+	case c.Difficulty == 0:
+		return ErrZeroDifficulty
+	case c.Difficulty < 0 || c.Difficulty > MaxDifficulty:
+		return errors.Join(ErrInvalidChallenge, ErrInvalidDifficulty)
+	case c.Nonce == [NonceSize]byte{}:
+		return ErrEmptyNonce
+	case !now.Before(c.Expiry):
+		return errors.Join(ErrInvalidChallenge, ErrChallengeExpired)
+	case c.Algorithm == AlgoArgon2id && c.Argon2.Validate() != nil:
+		return errors.Join(ErrInvalidChallenge, ErrInvalidArgon2Params)
+	}
+
+	return nil
+}
+
+// Satisfies reports whether the solution meets the challenge difficulty,
+// ignoring expiry.
+func (c Challenge) Satisfies(s Solution) bool {
+	if c.Difficulty < 0 || c.Difficulty > MaxDifficulty {
+		return false
+	}
+
+	hash, err := c.hash(s.Counter)
+	if err != nil {
+		return false
+	}
+
+	return hasLeadingZeroBits(hash, c.Difficulty)
+}
+
+func hashcash(nonce [NonceSize]byte, counter uint64) [sha256.Size]byte {
+	var input [NonceSize + 8]byte
+	copy(input[:NonceSize], nonce[:])
+	binary.BigEndian.PutUint64(input[NonceSize:], counter)
+	return sha256.Sum256(input[:])
+}
+
+// hasLeadingZeroBits counts zero bits MSB-first across the hash bytes.
+func hasLeadingZeroBits(hash [sha256.Size]byte, difficulty int) bool {
+	zeros := 0
+	for _, b := range hash {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+		if zeros >= difficulty {
+			break
+		}
+	}
+
+	return zeros >= difficulty
+}
@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidChallengePayload = errors.New("invalid challenge payload")
+	ErrInvalidSolutionPayload  = errors.New("invalid solution payload")
+	ErrCategoryTooLong         = errors.New("quote category too long")
+	// ErrMalformedSolution is returned, joined with
+	// ErrInvalidSolutionPayload, for any solution payload that does not
+	// follow the layout exactly.
+	ErrMalformedSolution = errors.New("malformed solution")
+)
+
+// ChallengeMessage is the payload of a responses.RES_CODE_POW_CHALLENGE
+// frame: the challenge itself, difficulty, algorithm and expiry included.
+type ChallengeMessage = Challenge
+
+var (
+	_ MessageEncoder = Challenge{}
+	_ MessageDecoder = (*Challenge)(nil)
+	_ MessageEncoder = Solution{}
+	_ MessageDecoder = (*Solution)(nil)
+)
+
+// Challenge payload: nonce (16 bytes) | difficulty (8 bytes) | expiry unix nanoseconds (8 bytes) |
+// algorithm (1 byte) | argon2 time (4 bytes) | argon2 memory KiB (4 bytes) | argon2 threads (1 byte) |
+// tag (32 bytes).
+const (
+	challengeFieldsSize  = NonceSize + 8 + 8 + 1 + 4 + 4 + 1
+	challengePayloadSize = challengeFieldsSize + TagSize
+)
+
+func (c Challenge) EncodedLen() int {
+	return challengePayloadSize
+}
+
+func (c Challenge) Encode() ([]byte, error) {
+	buff := make([]byte, challengePayloadSize)
+	copy(buff[:NonceSize], c.Nonce[:])
+	binary.BigEndian.PutUint64(buff[16:24], uint64(c.Difficulty))
+	binary.BigEndian.PutUint64(buff[24:32], uint64(c.Expiry.UnixNano()))
+	buff[32] = byte(c.Algorithm)
+	binary.BigEndian.PutUint32(buff[33:37], c.Argon2.Time)
+	binary.BigEndian.PutUint32(buff[37:41], c.Argon2.MemoryKiB)
+	buff[41] = c.Argon2.Threads
+	copy(buff[challengeFieldsSize:], c.Tag[:])
+	return buff, nil
+}
+
+func (c *Challenge) Decode(buff []byte) error {
+	if len(buff) != challengePayloadSize {
+		return ErrInvalidChallengePayload
+	}
+
+	difficulty := binary.BigEndian.Uint64(buff[16:24])
+	if difficulty > MaxDifficulty {
+		return ErrInvalidDifficulty
+	}
+
+	algorithm := Algorithm(buff[32])
+	argon2Params := Argon2Params{
+		Time:      binary.BigEndian.Uint32(buff[33:37]),
+		MemoryKiB: binary.BigEndian.Uint32(buff[37:41]),
+		Threads:   buff[41],
+	}
+	if algorithm == AlgoArgon2id {
+		if err := argon2Params.Validate(); err != nil {
+			return errors.Join(ErrInvalidChallenge, err)
+		}
+	}
+
+	copy(c.Nonce[:], buff[:NonceSize])
+	c.Difficulty = int(difficulty)
+	c.Expiry = time.Unix(0, int64(binary.BigEndian.Uint64(buff[24:32])))
+	c.Algorithm = algorithm
+	c.Argon2 = argon2Params
+	copy(c.Tag[:], buff[challengeFieldsSize:])
+	return nil
+}
+
+// Solution payload: nonce (16 bytes) | counter (8 bytes) |
+// category length (1 byte) | category | token (the rest, may be empty).
+const (
+	solutionFieldsSize = NonceSize + 8 + 1
+	MaxCategoryLength  = 255
+)
+
+func (s Solution) Encode() ([]byte, error) {
+	if len(s.Category) > MaxCategoryLength {
+		return nil, ErrCategoryTooLong
+	}
+
+	buff := make([]byte, solutionFieldsSize, solutionFieldsSize+len(s.Category)+len(s.Token))
+	copy(buff[:NonceSize], s.Nonce[:])
+	binary.BigEndian.PutUint64(buff[NonceSize:NonceSize+8], s.Counter)
+	buff[NonceSize+8] = byte(len(s.Category))
+	buff = append(buff, s.Category...)
+	return append(buff, s.Token...), nil
+}
+
+// Decode reads the counter as exactly 8 big-endian bytes, so every counter
+// has one encoding and the verifier hashes the same preimage the solver did.
+func (s *Solution) Decode(buff []byte) error {
+	if len(buff) < solutionFieldsSize {
+		return errors.Join(ErrInvalidSolutionPayload, ErrMalformedSolution)
+	}
+	categoryEnd := solutionFieldsSize + int(buff[NonceSize+8])
+	if len(buff) < categoryEnd {
+		return errors.Join(ErrInvalidSolutionPayload, ErrMalformedSolution)
+	}
+
+	copy(s.Nonce[:], buff[:NonceSize])
+	s.Counter = binary.BigEndian.Uint64(buff[NonceSize : NonceSize+8])
+	s.Category = string(buff[solutionFieldsSize:categoryEnd])
+	s.Token = nil
+	if len(buff) > categoryEnd {
+		s.Token = append([]byte(nil), buff[categoryEnd:]...)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Opcodes mirrored from the requests and responses packages, which this
+// package cannot import.
+const (
+	submitSolutionOpcode uint32 = 3
+	powChallengeOpcode   uint32 = 3
+)
+
+func TestSolutionRoundTrip(t *testing.T) {
+	challenge := seededChallenges(1, 8)[0]
+	solved, err := SolveChallenge(challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		token    []byte
+	}{
+		{name: "nonce and counter"},
+		{name: "with token", token: []byte("opaque challenge token")},
+		{name: "with category and token", category: "stoic", token: []byte{0, 1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := solved
+			sent.Category = tt.category
+			sent.Token = tt.token
+
+			frame, err := BuildRawMessage(true, submitSolutionOpcode, sent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Opcode != submitSolutionOpcode {
+				t.Fatalf("opcode = %d, want %d", msg.Opcode, submitSolutionOpcode)
+			}
+
+			var got Solution
+			if err := got.Decode(msg.Data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, sent) {
+				t.Fatalf("decoded %+v, want %+v", got, sent)
+			}
+			if err := VerifySolution(challenge, got, time.Now()); err != nil {
+				t.Fatalf("decoded solution does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestChallengeMessageRoundTrip(t *testing.T) {
+	base := seededChallenges(1, 20)[0]
+	// Decoding keeps only the wall clock, so start from one without a
+	// monotonic reading.
+	base.Expiry = time.Unix(0, base.Expiry.UnixNano())
+
+	signed := base
+	signed.Sign([]byte("challenge secret"))
+	argon2 := base
+	argon2.Algorithm = AlgoArgon2id
+	argon2.Argon2 = DefaultArgon2Params
+	hardest := base
+	hardest.Difficulty = MaxDifficulty
+
+	tests := []struct {
+		name      string
+		challenge ChallengeMessage
+	}{
+		{name: "sha256", challenge: base},
+		{name: "signed", challenge: signed},
+		{name: "argon2id", challenge: argon2},
+		{name: "max difficulty", challenge: hardest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(true, powChallengeOpcode, tt.challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got ChallengeMessage
+			if err := got.Decode(msg.Data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.challenge) {
+				t.Fatalf("decoded %+v, want %+v", got, tt.challenge)
+			}
+		})
+	}
+}
+
+func TestSolutionDecodeRejectsMalformed(t *testing.T) {
+	valid, err := Solution{Counter: 1 << 40, Category: "stoic"}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{name: "empty", payload: nil},
+		{name: "counter cut short", payload: valid[:NonceSize+4]},
+		{name: "no category length", payload: valid[:NonceSize+8]},
+		{name: "category cut short", payload: valid[:solutionFieldsSize+2]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Solution{}).Decode(tt.payload)
+			if !errors.Is(err, ErrMalformedSolution) || !errors.Is(err, ErrInvalidSolutionPayload) {
+				t.Fatalf("got %v, want ErrMalformedSolution", err)
+			}
+		})
+	}
+}
+
+// FuzzSolutionDecode checks that every payload either fails as malformed or
+// decodes to a solution that encodes back to exactly the same bytes, so no
+// two payloads name the same counter.
+func FuzzSolutionDecode(f *testing.F) {
+	for _, seed := range []Solution{
+		{},
+		{Counter: 42},
+		{Counter: ^uint64(0), Category: "tech", Token: []byte("token")},
+	} {
+		payload, err := seed.Encode()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(payload)
+	}
+	f.Add([]byte{})
+	f.Add(make([]byte, solutionFieldsSize-1))
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		solution := Solution{}
+		if err := solution.Decode(payload); err != nil {
+			if !errors.Is(err, ErrMalformedSolution) {
+				t.Fatalf("decode failed with %v, want ErrMalformedSolution", err)
+			}
+			return
+		}
+
+		encoded, err := solution.Encode()
+		if err != nil {
+			t.Fatalf("decoded %+v does not encode: %v", solution, err)
+		}
+		if !bytes.Equal(encoded, payload) {
+			t.Fatalf("payload % x re-encodes as % x", payload, encoded)
+		}
+	})
+}
@@ -0,0 +1,432 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// benchSeed fixes the challenge nonces so benchmark runs are comparable.
+const benchSeed = 42
+
+var benchDifficulties = []int{10, 16, 20, 24}
+
+// seededChallenges returns n challenges whose nonces only depend on
+// benchSeed, so every run solves the same puzzles.
+func seededChallenges(n int, difficulty int) []Challenge {
+	rng := rand.New(rand.NewSource(benchSeed))
+	challenges := make([]Challenge, n)
+	for i := range challenges {
+		challenges[i] = Challenge{
+			Difficulty: difficulty,
+			Expiry:     time.Now().Add(time.Hour),
+		}
+		rng.Read(challenges[i].Nonce[:])
+	}
+
+	return challenges
+}
+
+func TestVerifySolutionEnforcesExpiry(t *testing.T) {
+	challenge := seededChallenges(1, 8)[0]
+	solution, err := SolveChallenge(challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		err  error
+	}{
+		{name: "well before expiry", now: challenge.Expiry.Add(-time.Minute)},
+		{name: "at expiry", now: challenge.Expiry},
+		{name: "one nanosecond past expiry", now: challenge.Expiry.Add(time.Nanosecond), err: ErrChallengeExpired},
+		{name: "long past expiry", now: challenge.Expiry.Add(time.Hour), err: ErrChallengeExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifySolution(challenge, solution, tt.now); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHasLeadingZeroBitsAtByteBoundary(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     []byte
+		difficulty int
+		want       bool
+	}{
+		{name: "7 zeros at difficulty 7", prefix: []byte{0x01}, difficulty: 7, want: true},
+		{name: "7 zeros at difficulty 8", prefix: []byte{0x01}, difficulty: 8},
+		{name: "6 zeros at difficulty 7", prefix: []byte{0x02}, difficulty: 7},
+		{name: "8 zeros at difficulty 8", prefix: []byte{0x00, 0x80}, difficulty: 8, want: true},
+		{name: "8 zeros at difficulty 9", prefix: []byte{0x00, 0x80}, difficulty: 9},
+		{name: "9 zeros at difficulty 9", prefix: []byte{0x00, 0x40}, difficulty: 9, want: true},
+		{name: "9 zeros at difficulty 7", prefix: []byte{0x00, 0x40}, difficulty: 7, want: true},
+		{name: "all zeros at max difficulty", difficulty: MaxDifficulty, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hash [sha256.Size]byte
+			copy(hash[:], tt.prefix)
+			if got := hasLeadingZeroBits(hash, tt.difficulty); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolverAndVerifierAgreeOnBits(t *testing.T) {
+	for _, difficulty := range []int{7, 8, 9} {
+		t.Run(fmt.Sprintf("difficulty %d", difficulty), func(t *testing.T) {
+			for _, challenge := range seededChallenges(8, difficulty) {
+				solution, err := SolveChallenge(challenge, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				hash := hashcash(challenge.Nonce, solution.Counter)
+				zeros := bits.LeadingZeros64(binary.BigEndian.Uint64(hash[:8]))
+				if zeros < difficulty {
+					t.Fatalf("counter %d has %d leading zero bits, want at least %d", solution.Counter, zeros, difficulty)
+				}
+				if err := VerifySolution(challenge, solution, time.Now()); err != nil {
+					t.Fatalf("solution does not verify: %v", err)
+				}
+
+				harder := challenge
+				harder.Difficulty = zeros + 1
+				if harder.Satisfies(solution) {
+					t.Fatalf("%d leading zero bits satisfied difficulty %d", zeros, harder.Difficulty)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveChallengeMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name        string
+		difficulty  int
+		maxAttempts uint64
+		err         error
+	}{
+		{name: "difficulty 30 capped", difficulty: 30, maxAttempts: 1000, err: ErrSolveExhausted},
+		{name: "single attempt", difficulty: 30, maxAttempts: 1, err: ErrSolveExhausted},
+		{name: "cap above the work needed", difficulty: 4, maxAttempts: 1 << 20},
+		{name: "no cap", difficulty: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := seededChallenges(1, tt.difficulty)[0]
+			solution, stats, err := SolveChallengeContext(context.Background(), challenge, SolveOptions{MaxAttempts: tt.maxAttempts})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if stats.Attempts != tt.maxAttempts {
+					t.Fatalf("tried %d counters, want exactly %d", stats.Attempts, tt.maxAttempts)
+				}
+				return
+			}
+			if err := VerifySolution(challenge, solution, time.Now()); err != nil {
+				t.Fatalf("solution does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestSolveChallengeContextCancelled(t *testing.T) {
+	argon2id := seededChallenges(1, 30)[0]
+	argon2id.Algorithm = AlgoArgon2id
+	argon2id.Argon2 = DefaultArgon2Params
+
+	tests := []struct {
+		name      string
+		challenge Challenge
+		attempts  uint64
+	}{
+		{name: "sha256", challenge: seededChallenges(1, 30)[0], attempts: ProgressInterval},
+		// A memory-hard hash is checked after every attempt.
+		{name: "argon2id", challenge: argon2id, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			// Without a cap only the context stops a solve this hard.
+			_, stats, err := SolveChallengeContext(ctx, tt.challenge, SolveOptions{})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want context.Canceled", err)
+			}
+			if stats.Attempts != tt.attempts {
+				t.Fatalf("tried %d counters before noticing ctx, want %d", stats.Attempts, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSolveStatsMatchSolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty int
+	}{
+		{name: "difficulty 0", difficulty: 0},
+		{name: "difficulty 8", difficulty: 8},
+		{name: "difficulty 16", difficulty: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := seededChallenges(1, tt.difficulty)[0]
+			solution, stats, err := SolveChallengeContext(context.Background(), challenge, SolveOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stats.Attempts != solution.Counter+1 {
+				t.Fatalf("attempts = %d, want counter %d + 1", stats.Attempts, solution.Counter)
+			}
+			if stats.Duration <= 0 {
+				t.Fatalf("duration = %v, want it positive", stats.Duration)
+			}
+			if want := float64(stats.Attempts) / stats.Duration.Seconds(); stats.HashesPerSec != want {
+				t.Fatalf("hashes/sec = %v, want attempts/duration %v", stats.HashesPerSec, want)
+			}
+
+			plain, err := SolveChallenge(challenge, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain.Counter != solution.Counter {
+				t.Fatalf("SolveChallenge found counter %d, want %d", plain.Counter, solution.Counter)
+			}
+		})
+	}
+}
+
+func TestChallengeValidate(t *testing.T) {
+	now := time.Now()
+	valid := seededChallenges(1, 16)[0]
+	valid.Expiry = now.Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		modify func(c *Challenge)
+		err    error
+	}{
+		{name: "valid", modify: func(*Challenge) {}},
+		{name: "zero difficulty", modify: func(c *Challenge) { c.Difficulty = 0 }, err: ErrZeroDifficulty},
+		{name: "negative difficulty", modify: func(c *Challenge) { c.Difficulty = -1 }, err: ErrInvalidDifficulty},
+		{name: "absurd difficulty", modify: func(c *Challenge) { c.Difficulty = MaxDifficulty + 1 }, err: ErrInvalidDifficulty},
+		{name: "max difficulty", modify: func(c *Challenge) { c.Difficulty = MaxDifficulty }},
+		{name: "empty nonce", modify: func(c *Challenge) { c.Nonce = [NonceSize]byte{} }, err: ErrEmptyNonce},
+		{name: "already expired", modify: func(c *Challenge) { c.Expiry = now.Add(-time.Second) }, err: ErrChallengeExpired},
+		{name: "expires now", modify: func(c *Challenge) { c.Expiry = now }, err: ErrChallengeExpired},
+		{name: "argon2id", modify: func(c *Challenge) {
+			c.Algorithm = AlgoArgon2id
+			c.Argon2 = DefaultArgon2Params
+		}},
+		{name: "argon2id without params", modify: func(c *Challenge) { c.Algorithm = AlgoArgon2id }, err: ErrInvalidArgon2Params},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := valid
+			tt.modify(&challenge)
+
+			err := challenge.ValidateAt(now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil && !errors.Is(err, ErrInvalidChallenge) {
+				t.Fatalf("%v does not match ErrInvalidChallenge", err)
+			}
+		})
+	}
+}
+
+func TestNewChallengeFromSource(t *testing.T) {
+	fixed := []byte("0123456789abcdef")
+	errSource := errors.New("source failed")
+
+	tests := []struct {
+		name   string
+		source func() io.Reader
+		err    error
+	}{
+		{name: "fixed bytes", source: func() io.Reader { return bytes.NewReader(fixed) }},
+		{name: "fixed bytes one at a time", source: func() io.Reader { return iotest.OneByteReader(bytes.NewReader(fixed)) }},
+		{name: "short source", source: func() io.Reader { return bytes.NewReader(fixed[:NonceSize-1]) }, err: ErrInsufficientEntropy},
+		{name: "empty source", source: func() io.Reader { return bytes.NewReader(nil) }, err: ErrInsufficientEntropy},
+		{name: "failing source", source: func() io.Reader { return iotest.ErrReader(errSource) }, err: errSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := NewChallengeFrom(tt.source(), 8, time.Minute)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			second, err := NewChallengeFrom(tt.source(), 8, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(first.Nonce[:], fixed) || second.Nonce != first.Nonce {
+				t.Fatalf("nonces %x and %x, want %x both times", first.Nonce, second.Nonce, fixed)
+			}
+		})
+	}
+}
+
+func TestSolveChallengeContextProgress(t *testing.T) {
+	tests := []struct {
+		name string
+		// cancelAt cancels the solve from the progress callback after that
+		// many calls; zero lets it run to MaxAttempts.
+		cancelAt    int
+		maxAttempts uint64
+		err         error
+		want        []uint64
+	}{
+		{
+			name:        "fires every interval",
+			maxAttempts: 3*ProgressInterval + 1,
+			err:         ErrSolveExhausted,
+			want:        []uint64{ProgressInterval, 2 * ProgressInterval, 3 * ProgressInterval},
+		},
+		{
+			name:        "silent below one interval",
+			maxAttempts: ProgressInterval,
+			err:         ErrSolveExhausted,
+		},
+		{
+			name:     "cancelled from the callback",
+			cancelAt: 2,
+			err:      context.Canceled,
+			want:     []uint64{ProgressInterval, 2 * ProgressInterval},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var calls []uint64
+			_, stats, err := SolveChallengeContext(ctx, seededChallenges(1, MaxDifficulty)[0], SolveOptions{
+				MaxAttempts: tt.maxAttempts,
+				OnProgress: func(attempts uint64) {
+					calls = append(calls, attempts)
+					if len(calls) == tt.cancelAt {
+						cancel()
+					}
+				},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(calls, tt.want) {
+				t.Fatalf("progress reported %v, want %v", calls, tt.want)
+			}
+			// Cancellation is noticed at the next check, within one
+			// interval of the cancel.
+			if tt.cancelAt > 0 && stats.Attempts != uint64(tt.cancelAt+1)*ProgressInterval {
+				t.Fatalf("stopped after %d attempts, want %d", stats.Attempts, uint64(tt.cancelAt+1)*ProgressInterval)
+			}
+		})
+	}
+}
+
+func BenchmarkSolveChallenge(b *testing.B) {
+	for _, difficulty := range benchDifficulties {
+		b.Run(fmt.Sprintf("difficulty=%d", difficulty), func(b *testing.B) {
+			challenges := seededChallenges(b.N, difficulty)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if _, err := SolveChallenge(challenges[i], 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSolveAlgorithms compares the cost of SHA-256 and Argon2id solves
+// at the same difficulty; run with -benchmem to see the memory Argon2id
+// trades for resistance to custom hardware.
+func BenchmarkSolveAlgorithms(b *testing.B) {
+	const difficulty = 4
+	algorithms := []struct {
+		name   string
+		algo   Algorithm
+		params Argon2Params
+	}{
+		{name: "sha256", algo: AlgoSHA256},
+		{name: "argon2id", algo: AlgoArgon2id, params: DefaultArgon2Params},
+	}
+
+	for _, a := range algorithms {
+		b.Run(fmt.Sprintf("algorithm=%s", a.name), func(b *testing.B) {
+			challenges := seededChallenges(b.N, difficulty)
+			for i := range challenges {
+				challenges[i].Algorithm = a.algo
+				challenges[i].Argon2 = a.params
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				if _, err := SolveChallenge(challenges[i], 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifySolution(b *testing.B) {
+	for _, difficulty := range benchDifficulties {
+		b.Run(fmt.Sprintf("difficulty=%d", difficulty), func(b *testing.B) {
+			challenge := seededChallenges(1, difficulty)[0]
+			solution, err := SolveChallenge(challenge, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			now := time.Now()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := VerifySolution(challenge, solution, now); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
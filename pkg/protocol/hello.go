@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+var (
+	ErrNoCommonAlgorithm   = errors.New("no common pow algorithm")
+	ErrInvalidHelloPayload = errors.New("invalid hello payload")
+)
+
+// HelloMessage is sent by the server before the challenge to advertise the
+// PoW algorithms it accepts and the minimum difficulty it will ask for.
+type HelloMessage struct {
+	Algorithms    []Algorithm
+	MinDifficulty int
+}
+
+// HelloAck is the client's pick among the advertised algorithms.
+type HelloAck struct {
+	Algorithm Algorithm
+}
+
+// Hello payload: algorithms count (1 byte) | algorithms (1 byte each) | min difficulty (8 bytes).
+func (h HelloMessage) Encode() ([]byte, error) {
+	if len(h.Algorithms) > 255 {
+		return nil, ErrInvalidHelloPayload
+	}
+
+	buff := make([]byte, 0, 1+len(h.Algorithms)+8)
+	buff = append(buff, byte(len(h.Algorithms)))
+	for _, algo := range h.Algorithms {
+		buff = append(buff, byte(algo))
+	}
+	buff = binary.BigEndian.AppendUint64(buff, uint64(h.MinDifficulty))
+	return buff, nil
+}
+
+func (h *HelloMessage) Decode(buff []byte) error {
+	if len(buff) < 1 {
+		return ErrInvalidHelloPayload
+	}
+
+	count := int(buff[0])
+	if len(buff) != 1+count+8 {
+		return ErrInvalidHelloPayload
+	}
+
+	minDifficulty := binary.BigEndian.Uint64(buff[1+count:])
+	if minDifficulty > MaxDifficulty {
+		return ErrInvalidDifficulty
+	}
+
+	h.Algorithms = make([]Algorithm, count)
+	for i := range count {
+		h.Algorithms[i] = Algorithm(buff[1+i])
+	}
+	h.MinDifficulty = int(minDifficulty)
+	return nil
+}
+
+func (a HelloAck) Encode() ([]byte, error) {
+	return []byte{byte(a.Algorithm)}, nil
+}
+
+func (a *HelloAck) Decode(buff []byte) error {
+	if len(buff) != 1 {
+		return ErrInvalidHelloPayload
+	}
+
+	a.Algorithm = Algorithm(buff[0])
+	return nil
+}
+
+// NegotiateAlgorithm picks the first algorithm from supported, in the
+// client's order of preference, that the server advertised.
+func NegotiateAlgorithm(hello HelloMessage, supported []Algorithm) (Algorithm, error) {
+	for _, algo := range supported {
+		if slices.Contains(hello.Algorithms, algo) {
+			return algo, nil
+		}
+	}
+
+	return 0, ErrNoCommonAlgorithm
+}
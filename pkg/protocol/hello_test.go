@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNegotiateAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		offered   []Algorithm
+		supported []Algorithm
+		want      Algorithm
+		err       error
+	}{
+		{name: "sha256 client, both offered", offered: []Algorithm{AlgoArgon2id, AlgoSHA256}, supported: []Algorithm{AlgoSHA256}, want: AlgoSHA256},
+		{name: "client preference wins", offered: []Algorithm{AlgoSHA256, AlgoArgon2id}, supported: []Algorithm{AlgoArgon2id, AlgoSHA256}, want: AlgoArgon2id},
+		{name: "no common algorithm", offered: []Algorithm{AlgoSHA256}, supported: []Algorithm{AlgoArgon2id}, err: ErrNoCommonAlgorithm},
+		{name: "nothing offered", supported: []Algorithm{AlgoSHA256}, err: ErrNoCommonAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NegotiateAlgorithm(HelloMessage{Algorithms: tt.offered, MinDifficulty: 20}, tt.supported)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelloRoundTrip(t *testing.T) {
+	hello := HelloMessage{Algorithms: []Algorithm{AlgoSHA256, AlgoArgon2id}, MinDifficulty: 22}
+	buff, err := hello.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := HelloMessage{}
+	if err := got.Decode(buff); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, hello) {
+		t.Fatalf("got %+v, want %+v", got, hello)
+	}
+
+	for _, bad := range [][]byte{nil, {2, 0}, append(buff, 0)} {
+		if err := (&HelloMessage{}).Decode(bad); !errors.Is(err, ErrInvalidHelloPayload) {
+			t.Errorf("decode %v: got %v, want ErrInvalidHelloPayload", bad, err)
+		}
+	}
+
+	ack := HelloAck{}
+	if err := ack.Decode([]byte{byte(AlgoArgon2id)}); err != nil || ack.Algorithm != AlgoArgon2id {
+		t.Fatalf("ack: got %v, %v", ack.Algorithm, err)
+	}
+}
@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrNotJSONPayload = errors.New("payload is not json encoded")
+)
+
+// JSONEncoder encodes any value as a JSON payload and marks the frame with
+// MSG_JSON_FLAG. Only the payload changes; the binary header stays the same.
+// JSON is handy for debugging on the wire but is considerably larger than
+// the binary encoders: the hashcash challenge is 32 bytes in binary and
+// roughly three times that as JSON.
+type JSONEncoder struct {
+	Value any
+}
+
+func (e JSONEncoder) Encode() ([]byte, error) {
+	return json.Marshal(e.Value)
+}
+
+func (e JSONEncoder) jsonPayload() {}
+
+// JSONDecoder decodes a JSON payload into Value, which must be a pointer.
+type JSONDecoder struct {
+	Value any
+}
+
+func (d JSONDecoder) Decode(buff []byte) error {
+	return json.Unmarshal(buff, d.Value)
+}
+
+type jsonPayloadEncoder interface {
+	jsonPayload()
+}
+
+func (m *RawMessage) IsJSON() bool {
+	f := MessageFlags(m.Flags)
+	return f.HasFlag(MSG_JSON_FLAG)
+}
+
+// DecodeJSON decodes the payload into v, failing if the frame was not sent
+// with a JSONEncoder.
+func (m *RawMessage) DecodeJSON(v any) error {
+	if !m.IsJSON() {
+		return ErrNotJSONPayload
+	}
+
+	return m.DecodePayload(JSONDecoder{Value: v})
+}
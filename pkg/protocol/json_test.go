@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type jsonQuote struct {
+	Text   string
+	Author *jsonAuthor
+	Tags   []string
+}
+
+type jsonAuthor struct {
+	Name  string
+	Born  time.Time
+	Works map[string]int
+}
+
+func TestJSONPayloadRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []BuildOption
+	}{
+		{name: "plain"},
+		{name: "checksum", opts: []BuildOption{WithChecksum()}},
+		{name: "compressed", opts: []BuildOption{WithCompression(0)}},
+	}
+
+	sent := jsonQuote{
+		Text: "Luck is what happens when preparation meets opportunity",
+		Author: &jsonAuthor{
+			Name:  "Anonymous",
+			Born:  time.Date(1965, 4, 1, 0, 0, 0, 0, time.UTC),
+			Works: map[string]int{"letters": 124},
+		},
+		Tags: []string{"stoic", "latin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(true, 9, JSONEncoder{Value: sent}, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !msg.IsJSON() || msg.Opcode != 9 {
+				t.Fatalf("json flag %v, opcode %d", msg.IsJSON(), msg.Opcode)
+			}
+
+			got := jsonQuote{}
+			if err := msg.DecodeJSON(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, sent) {
+				t.Fatalf("got %+v, want %+v", got, sent)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONRejectsBinaryPayload(t *testing.T) {
+	frame, err := BuildRawMessage(true, 9, StringEncoder("binary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ParseRawMessage(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := msg.DecodeJSON(&jsonQuote{}); !errors.Is(err, ErrNotJSONPayload) {
+		t.Fatalf("got %v, want ErrNotJSONPayload", err)
+	}
+}
@@ -3,17 +3,45 @@ package protocol
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
 )
 
 var (
 	ErrFailedToEncodeMessage = errors.New("failed to encode message")
 	ErrMessageTooShort       = errors.New("message is too short")
+	ErrUnsupportedVersion    = errors.New("unsupported protocol version")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrMessageTooLarge       = errors.New("message is too large")
+	// Both truncation errors also match ErrMessageTooShort.
+	ErrTruncatedHeader  = fmt.Errorf("%w: truncated header", ErrMessageTooShort)
+	ErrTruncatedPayload = fmt.Errorf("%w: truncated payload", ErrMessageTooShort)
+	ErrTrailingData     = errors.New("unexpected data after frame")
+
+	ErrInconsistentLength = errors.New("payload length does not match the encoded payload")
+)
+
+// CurrentVersion is written as the first byte of every frame.
+const CurrentVersion byte = 0x01
+
+// Frame layout: version (1 byte) | flags (1 byte) | opcode (4 bytes) | request id (4 bytes) | payload length (4 bytes) | payload | [crc32 (4 bytes)].
+// The trailing checksum is only present when MSG_CHECKSUM_FLAG is set.
+// All multi-byte fields are big-endian.
+const (
+	HeaderSize   = 1 + 1 + 4 + 4 + 4
+	ChecksumSize = 4
 )
 
 type RawMessage struct {
-	Flags  byte
-	Opcode uint32
-	Data   []byte
+	Version byte
+	Flags   byte
+	Opcode  uint32
+	// RequestID correlates a reply with its request; zero means untagged.
+	RequestID uint32
+	Data      []byte
+	// Frame is the complete raw frame the message was parsed from.
+	Frame []byte
 }
 
 type ParsedMessage[T any] struct {
@@ -23,13 +51,21 @@ type ParsedMessage[T any] struct {
 }
 
 type MessageDecoder interface {
-	Decode(data []byte) (any, error)
+	Decode(data []byte) error
 }
 
 type MessageEncoder interface {
 	Encode() ([]byte, error)
 }
 
+// SizedEncoder is a MessageEncoder with a fixed payload size.
+// BuildRawMessage rejects an Encode result of any other length with
+// ErrInconsistentLength.
+type SizedEncoder interface {
+	MessageEncoder
+	EncodedLen() int
+}
+
 func (m *RawMessage) IsSuccess() bool {
 	f := MessageFlags(m.Flags)
 	return !f.HasFlag(MSG_FAIL_FLAG)
@@ -40,39 +76,172 @@ func (m *RawMessage) IsFailure() bool {
 	return f.HasFlag(MSG_FAIL_FLAG)
 }
 
-func BuildRawMessage(success bool, opcode uint32, payload MessageEncoder) ([]byte, error) {
-	messageBuff := make([]byte, 5)
+func (m *RawMessage) HasFlag(flag MessageFlags) bool {
+	f := MessageFlags(m.Flags)
+	return f.HasFlag(flag)
+}
+
+func (m *RawMessage) DecodePayload(decoder MessageDecoder) error {
+	return decoder.Decode(m.Data)
+}
+
+func BuildRawMessage(success bool, opcode uint32, payload MessageEncoder, opts ...BuildOption) ([]byte, error) {
+	options := buildOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	messageBuff := make([]byte, HeaderSize)
 
-	flags := EmptyMessageFlags()
+	flags := options.flags &^ builtinFlags
 	if !success {
 		flags.SetFlag(MSG_FAIL_FLAG)
 	}
-	messageBuff[0] = byte(flags)
+	if options.checksum {
+		flags.SetFlag(MSG_CHECKSUM_FLAG)
+	}
+	if _, ok := payload.(jsonPayloadEncoder); ok {
+		flags.SetFlag(MSG_JSON_FLAG)
+	}
+	messageBuff[0] = CurrentVersion
+	messageBuff[1] = byte(flags)
 
-	binary.BigEndian.PutUint32(messageBuff[1:5], opcode)
+	binary.BigEndian.PutUint32(messageBuff[2:6], opcode)
+	binary.BigEndian.PutUint32(messageBuff[6:10], options.requestID)
 
 	if payload != nil {
 		buff, err := payload.Encode()
 		if err != nil {
 			return nil, errors.Join(err, ErrFailedToEncodeMessage)
 		}
+		if sized, ok := payload.(SizedEncoder); ok && sized.EncodedLen() != len(buff) {
+			return nil, ErrInconsistentLength
+		}
+		if uint64(len(buff)) > math.MaxUint32 {
+			return nil, ErrMessageTooLarge
+		}
+
+		if options.compress && len(buff) >= options.compressionMinSize {
+			compressed, err := compressPayload(buff)
+			if err != nil {
+				return nil, errors.Join(err, ErrFailedToEncodeMessage)
+			}
+			if len(compressed) < len(buff) {
+				buff = compressed
+				flags.SetFlag(MSG_COMPRESSED_FLAG)
+				messageBuff[1] = byte(flags)
+			}
+		}
 
 		messageBuff = append(messageBuff, buff...)
 	}
 
+	binary.BigEndian.PutUint32(messageBuff[10:14], uint32(len(messageBuff)-HeaderSize))
+
+	if options.checksum {
+		messageBuff = binary.BigEndian.AppendUint32(messageBuff, crc32.ChecksumIEEE(messageBuff))
+	}
+
 	return messageBuff, nil
 }
 
+// PayloadLength returns the payload length declared in a frame header. A
+// header of another protocol version is rejected with ErrUnsupportedVersion
+// first, since its layout, and so its length field, may differ.
+func PayloadLength(header []byte) (int, error) {
+	if len(header) < HeaderSize {
+		return 0, ErrTruncatedHeader
+	}
+	if header[0] != CurrentVersion {
+		return 0, ErrUnsupportedVersion
+	}
+
+	// On 32-bit platforms a declared length can overflow int and the frame
+	// size computed from it.
+	length := uint64(binary.BigEndian.Uint32(header[10:14]))
+	if length > uint64(math.MaxInt-HeaderSize-ChecksumSize) {
+		return 0, ErrMessageTooLarge
+	}
+
+	return int(length), nil
+}
+
+// PeekOpcode returns the opcode declared in a frame header.
+func PeekOpcode(header []byte) (uint32, error) {
+	if len(header) < HeaderSize {
+		return 0, ErrTruncatedHeader
+	}
+
+	return binary.BigEndian.Uint32(header[2:6]), nil
+}
+
+// PeekRequestID returns the request id declared in a frame header.
+func PeekRequestID(header []byte) (uint32, error) {
+	if len(header) < HeaderSize {
+		return 0, ErrTruncatedHeader
+	}
+
+	return binary.BigEndian.Uint32(header[6:10]), nil
+}
+
+// FrameSize returns the total frame size declared by a frame header,
+// including the trailing checksum if present.
+func FrameSize(header []byte) (int, error) {
+	payloadLength, err := PayloadLength(header)
+	if err != nil {
+		return 0, err
+	}
+
+	size := HeaderSize + payloadLength
+	flags := MessageFlags(header[1])
+	if flags.HasFlag(MSG_CHECKSUM_FLAG) {
+		size += ChecksumSize
+	}
+
+	return size, nil
+}
+
 func ParseRawMessage(rawMessage []byte) (*RawMessage, error) {
-	if len(rawMessage) < 5 {
-		return nil, ErrMessageTooShort
+	payloadLength, err := PayloadLength(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	frameSize, err := FrameSize(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawMessage) < frameSize {
+		return nil, ErrTruncatedPayload
+	}
+	if len(rawMessage) > frameSize {
+		return nil, ErrTrailingData
+	}
+
+	version := rawMessage[0]
+	flags := MessageFlags(rawMessage[1])
+	if flags.HasFlag(MSG_CHECKSUM_FLAG) {
+		checksumOffset := HeaderSize + payloadLength
+		expected := binary.BigEndian.Uint32(rawMessage[checksumOffset:frameSize])
+		if crc32.ChecksumIEEE(rawMessage[:checksumOffset]) != expected {
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	data := rawMessage[HeaderSize : HeaderSize+payloadLength]
+	if flags.HasFlag(MSG_COMPRESSED_FLAG) {
+		if data, err = decompressPayload(data); err != nil {
+			return nil, err
+		}
 	}
 
-	flags := rawMessage[0]
-	opcode := binary.BigEndian.Uint32(rawMessage[1:5])
+	opcode := binary.BigEndian.Uint32(rawMessage[2:6])
+	requestID := binary.BigEndian.Uint32(rawMessage[6:10])
 	return &RawMessage{
-		Flags:  flags,
-		Opcode: opcode,
-		Data:   rawMessage[5:],
+		Version:   version,
+		Flags:     byte(flags),
+		Opcode:    opcode,
+		RequestID: requestID,
+		Data:      data,
+		Frame:     rawMessage[:frameSize],
 	}, nil
 }
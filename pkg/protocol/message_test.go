@@ -0,0 +1,319 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// sizedPayload reports len as its size but encodes payload.
+type sizedPayload struct {
+	payload []byte
+	len     int
+}
+
+func (p sizedPayload) Encode() ([]byte, error) {
+	return p.payload, nil
+}
+
+func (p sizedPayload) EncodedLen() int {
+	return p.len
+}
+
+func TestBuildRawMessageChecksEncodedLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload MessageEncoder
+		err     error
+	}{
+		{name: "consistent", payload: sizedPayload{payload: []byte("abc"), len: 3}},
+		{name: "short encode", payload: sizedPayload{payload: []byte("ab"), len: 3}, err: ErrInconsistentLength},
+		{name: "long encode", payload: sizedPayload{payload: []byte("abcd"), len: 3}, err: ErrInconsistentLength},
+		{name: "string", payload: StringEncoder("wisdom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(true, 1, tt.payload)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			declared, err := PayloadLength(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if declared != len(frame)-HeaderSize {
+				t.Fatalf("header declares %d bytes, frame carries %d", declared, len(frame)-HeaderSize)
+			}
+		})
+	}
+}
+
+func TestParseRawMessageDetectsFlippedBits(t *testing.T) {
+	// Flips are kept off the version, flags and length bytes, which fail
+	// framing before the checksum is looked at.
+	tests := []struct {
+		name     string
+		checksum bool
+		// span returns the range of frame bytes flipped.
+		span func(frame []byte) (int, int)
+		err  error
+	}{
+		{
+			name:     "opcode and request id",
+			checksum: true,
+			span:     func([]byte) (int, int) { return 2, 10 },
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name:     "payload",
+			checksum: true,
+			span:     func(f []byte) (int, int) { return HeaderSize, len(f) - ChecksumSize },
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name:     "checksum",
+			checksum: true,
+			span:     func(f []byte) (int, int) { return len(f) - ChecksumSize, len(f) },
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name: "payload without checksum",
+			span: func(f []byte) (int, int) { return HeaderSize, len(f) },
+		},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []BuildOption
+			if tt.checksum {
+				opts = append(opts, WithChecksum())
+			}
+			frame, err := BuildRawMessage(true, 7, StringEncoder("a flaky link garbles this"), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseRawMessage(frame); err != nil {
+				t.Fatalf("intact frame: %v", err)
+			}
+
+			first, last := tt.span(frame)
+			for range 100 {
+				garbled := bytes.Clone(frame)
+				bit := first*8 + rng.Intn((last-first)*8)
+				garbled[bit/8] ^= 1 << (bit % 8)
+
+				_, err := ParseRawMessage(garbled)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("bit %d flipped: got %v, want %v", bit, err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRawMessageTruncation(t *testing.T) {
+	frame, err := BuildRawMessage(true, 1, StringEncoder("truncate me"), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		keep int
+		err  error
+	}{
+		{name: "empty", keep: 0, err: ErrTruncatedHeader},
+		{name: "version only", keep: 1, err: ErrTruncatedHeader},
+		{name: "through opcode", keep: 6, err: ErrTruncatedHeader},
+		{name: "one byte short of header", keep: HeaderSize - 1, err: ErrTruncatedHeader},
+		{name: "header only", keep: HeaderSize, err: ErrTruncatedPayload},
+		{name: "half the payload", keep: HeaderSize + 6, err: ErrTruncatedPayload},
+		{name: "missing checksum", keep: len(frame) - ChecksumSize, err: ErrTruncatedPayload},
+		{name: "one byte short of checksum", keep: len(frame) - 1, err: ErrTruncatedPayload},
+		{name: "complete", keep: len(frame)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRawMessage(frame[:tt.keep])
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil && !errors.Is(err, ErrMessageTooShort) {
+				t.Fatalf("%v does not match ErrMessageTooShort", err)
+			}
+		})
+	}
+
+	if _, err := ParseRawMessage(append(bytes.Clone(frame), 0)); !errors.Is(err, ErrTrailingData) {
+		t.Fatalf("extra byte: got %v, want ErrTrailingData", err)
+	}
+}
+
+func TestParseRawMessageRejectsOtherVersions(t *testing.T) {
+	frame, err := BuildRawMessage(true, 1, StringEncoder("from the future"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// withVersion returns frame cut or padded to size, under version.
+	withVersion := func(version byte, size int) []byte {
+		other := make([]byte, size)
+		copy(other, frame)
+		other[0] = version
+		return other
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+		err   error
+	}{
+		{name: "current version", frame: withVersion(CurrentVersion, len(frame))},
+		{name: "next version, same layout", frame: withVersion(CurrentVersion+1, len(frame)), err: ErrUnsupportedVersion},
+		// A longer header in a later version reads as a truncated or
+		// padded payload under the current layout.
+		{name: "next version, longer frame", frame: withVersion(CurrentVersion+1, len(frame)+8), err: ErrUnsupportedVersion},
+		{name: "next version, shorter frame", frame: withVersion(CurrentVersion+1, HeaderSize), err: ErrUnsupportedVersion},
+		{name: "version zero", frame: withVersion(0, len(frame)), err: ErrUnsupportedVersion},
+		{name: "short header wins", frame: withVersion(CurrentVersion+1, HeaderSize-1), err: ErrTruncatedHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRawMessage(tt.frame); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func FuzzParseRawMessage(f *testing.F) {
+	seeds := []struct {
+		payload MessageEncoder
+		opts    []BuildOption
+	}{
+		{},
+		{payload: StringEncoder("wisdom")},
+		{payload: StringEncoder("wisdom"), opts: []BuildOption{WithChecksum()}},
+		{payload: StringEncoder(string(bytes.Repeat([]byte("z"), 300))), opts: []BuildOption{WithCompression(0)}},
+		{payload: JSONEncoder{Value: map[string]int{"a": 1}}, opts: []BuildOption{WithRequestID(7)}},
+	}
+	for _, seed := range seeds {
+		frame, err := BuildRawMessage(true, 1, seed.payload, seed.opts...)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(frame)
+		f.Add(frame[:len(frame)/2])
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		msg, err := ParseRawMessage(frame)
+		if err != nil {
+			if msg != nil {
+				t.Fatalf("got a message along with %v", err)
+			}
+			return
+		}
+		if !bytes.Equal(msg.Frame, frame) {
+			t.Fatal("parsed frame is not the input")
+		}
+		if len(msg.Data) > MaxDecompressedPayloadSize {
+			t.Fatalf("payload of %d bytes exceeds the decompression limit", len(msg.Data))
+		}
+	})
+}
+
+// TestFrameHeaderIsBigEndian pins every multi-byte header field to network
+// byte order with hand-written frames, so the result does not depend on the
+// byte order of the host running the test.
+func TestFrameHeaderIsBigEndian(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 0x0102)
+
+	tests := []struct {
+		name      string
+		opcode    uint32
+		payload   []byte
+		opts      []BuildOption
+		frame     []byte
+		requestID uint32
+	}{
+		{
+			name:    "opcode and length",
+			opcode:  0x01020304,
+			payload: []byte("hi"),
+			frame: append([]byte{
+				CurrentVersion, 0,
+				0x01, 0x02, 0x03, 0x04,
+				0, 0, 0, 0,
+				0, 0, 0, 2,
+			}, "hi"...),
+		},
+		{
+			name:      "request id",
+			opcode:    1,
+			payload:   []byte("hi"),
+			opts:      []BuildOption{WithRequestID(0x0a0b0c0d)},
+			requestID: 0x0a0b0c0d,
+			frame: append([]byte{
+				CurrentVersion, 0,
+				0, 0, 0, 1,
+				0x0a, 0x0b, 0x0c, 0x0d,
+				0, 0, 0, 2,
+			}, "hi"...),
+		},
+		{
+			name:    "multi-byte length",
+			opcode:  1,
+			payload: long,
+			frame: append([]byte{
+				CurrentVersion, 0,
+				0, 0, 0, 1,
+				0, 0, 0, 0,
+				0, 0, 0x01, 0x02,
+			}, long...),
+		},
+		{
+			name:      "checksum",
+			opcode:    5,
+			payload:   []byte("hi"),
+			opts:      []BuildOption{WithChecksum(), WithRequestID(0x0a0b0c0d)},
+			requestID: 0x0a0b0c0d,
+			frame: append(append([]byte{
+				CurrentVersion, byte(MSG_CHECKSUM_FLAG),
+				0, 0, 0, 5,
+				0x0a, 0x0b, 0x0c, 0x0d,
+				0, 0, 0, 2,
+			}, "hi"...), 0xa2, 0xc3, 0x51, 0xb1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := BuildRawMessage(true, tt.opcode, bytesPayload(tt.payload), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(frame, tt.frame) {
+				t.Fatalf("built % x, want % x", frame, tt.frame)
+			}
+
+			msg, err := ParseRawMessage(tt.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Opcode != tt.opcode || msg.RequestID != tt.requestID || !bytes.Equal(msg.Data, tt.payload) {
+				t.Fatalf("parsed opcode %#x, request id %#x, %d byte payload; want %#x, %#x, %d bytes",
+					msg.Opcode, msg.RequestID, len(msg.Data), tt.opcode, tt.requestID, len(tt.payload))
+			}
+		})
+	}
+}
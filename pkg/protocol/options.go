@@ -0,0 +1,43 @@
+package protocol
+
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	checksum           bool
+	requestID          uint32
+	compress           bool
+	compressionMinSize int
+	flags              MessageFlags
+}
+
+// WithChecksum appends a CRC32 (IEEE) of header and payload to the frame.
+func WithChecksum() BuildOption {
+	return func(o *buildOptions) {
+		o.checksum = true
+	}
+}
+
+// WithRequestID tags the frame so the reply can be matched to it.
+func WithRequestID(requestID uint32) BuildOption {
+	return func(o *buildOptions) {
+		o.requestID = requestID
+	}
+}
+
+// WithCompression zlib-compresses payloads of at least minSize bytes. The
+// payload is sent uncompressed if compression would not make it smaller.
+func WithCompression(minSize int) BuildOption {
+	return func(o *buildOptions) {
+		o.compress = true
+		o.compressionMinSize = minSize
+	}
+}
+
+// WithFlags sets extension flags (FLAG_5 to FLAG_8) in the header. Flags
+// that describe the frame itself, such as MSG_CHECKSUM_FLAG, follow the
+// outcome, payload and other options and are ignored here.
+func WithFlags(flags MessageFlags) BuildOption {
+	return func(o *buildOptions) {
+		o.flags |= flags
+	}
+}
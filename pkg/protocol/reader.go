@@ -0,0 +1,42 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+)
+
+// readChunkSize bounds how much is allocated ahead of data actually
+// arriving, so a header declaring a huge payload costs nothing until the
+// bytes are sent.
+const readChunkSize = 64 << 10
+
+// ReadMessage reads exactly one frame from r: first the header, then the
+// payload length it declares. Short reads from r are retried until the
+// frame is complete. Frames larger than maxFrameSize are rejected with
+// ErrMessageTooLarge before any of the payload is read; a non-positive
+// maxFrameSize disables the check.
+func ReadMessage(r io.Reader, maxFrameSize int) (*RawMessage, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	frameSize, err := FrameSize(header)
+	if err != nil {
+		return nil, err
+	}
+	if maxFrameSize > 0 && frameSize > maxFrameSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	frame := bytes.NewBuffer(make([]byte, 0, min(frameSize, HeaderSize+readChunkSize)))
+	frame.Write(header)
+	if _, err := io.CopyN(frame, r, int64(frameSize-HeaderSize)); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return ParseRawMessage(frame.Bytes())
+}
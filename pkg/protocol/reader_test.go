@@ -0,0 +1,160 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+// declaredFrame returns a header declaring a payload of length followed by
+// payload, which may be shorter.
+func declaredFrame(t *testing.T, length uint32, payload []byte) []byte {
+	t.Helper()
+
+	frame, err := BuildRawMessage(true, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(frame[10:14], length)
+	return append(frame, payload...)
+}
+
+func TestReadMessageRejectsOversizedFrame(t *testing.T) {
+	const maxFrameSize = 1024
+
+	tests := []struct {
+		name   string
+		length uint32
+		err    error
+	}{
+		{name: "1GB", length: 1 << 30, err: ErrMessageTooLarge},
+		{name: "one byte over", length: maxFrameSize - HeaderSize + 1, err: ErrMessageTooLarge},
+		{name: "at limit", length: maxFrameSize - HeaderSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := declaredFrame(t, tt.length, bytes.Repeat([]byte{'x'}, maxFrameSize))
+			r := bytes.NewReader(frame)
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := ReadMessage(r, maxFrameSize)
+			runtime.ReadMemStats(&after)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<10 {
+				t.Fatalf("allocated %d bytes for a %d byte limit", allocated, maxFrameSize)
+			}
+			if tt.err != nil && r.Len() != len(frame)-HeaderSize {
+				t.Fatalf("read %d payload bytes of a rejected frame", len(frame)-HeaderSize-r.Len())
+			}
+		})
+	}
+}
+
+func TestReadMessageUnlimitedDoesNotPreallocate(t *testing.T) {
+	// Without a limit a 1GB declaration still only costs what arrives.
+	frame := declaredFrame(t, 1<<30, []byte("short"))
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := ReadMessage(bytes.NewReader(frame), 0)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("allocated %d bytes for a truncated frame", allocated)
+	}
+}
+
+func TestReadMessageFromFragmentedReader(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []BuildOption
+	}{
+		{name: "plain"},
+		{name: "checksum", opts: []BuildOption{WithChecksum()}},
+		{name: "compressed", opts: []BuildOption{WithCompression(0), WithChecksum()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream []byte
+			quotes := []string{"first", "second", string(bytes.Repeat([]byte("third "), 100))}
+			for i, quote := range quotes {
+				frame, err := BuildRawMessage(true, uint32(i+1), StringEncoder(quote), tt.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stream = append(stream, frame...)
+			}
+
+			r := iotest.OneByteReader(bytes.NewReader(stream))
+			for i, want := range quotes {
+				msg, err := ReadMessage(r, 1<<16)
+				if err != nil {
+					t.Fatalf("frame %d: %v", i, err)
+				}
+				got := ""
+				if err := msg.DecodePayload(StringDecoder{Value: &got}); err != nil {
+					t.Fatal(err)
+				}
+				if msg.Opcode != uint32(i+1) || got != want {
+					t.Fatalf("frame %d: got opcode %d %q", i, msg.Opcode, got)
+				}
+			}
+			if _, err := ReadMessage(r, 1<<16); !errors.Is(err, io.EOF) {
+				t.Fatalf("after the last frame: got %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReadMessageTruncatedStream(t *testing.T) {
+	frame, err := BuildRawMessage(true, 1, StringEncoder("cut short"), WithChecksum())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		keep int
+		err  error
+	}{
+		{name: "empty", keep: 0, err: io.EOF},
+		{name: "inside header", keep: HeaderSize / 2, err: io.ErrUnexpectedEOF},
+		{name: "inside payload", keep: HeaderSize + 3, err: io.ErrUnexpectedEOF},
+		{name: "inside checksum", keep: len(frame) - 1, err: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := iotest.OneByteReader(bytes.NewReader(frame[:tt.keep]))
+			if _, err := ReadMessage(r, 1<<16); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadMessageRejectsOtherVersionBeforePayload(t *testing.T) {
+	frame := declaredFrame(t, 1<<20, []byte("payload of a later layout"))
+	frame[0] = CurrentVersion + 1
+	r := bytes.NewReader(frame)
+
+	if _, err := ReadMessage(r, 0); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("got %v, want ErrUnsupportedVersion", err)
+	}
+	if r.Len() != len(frame)-HeaderSize {
+		t.Fatalf("read %d payload bytes of a frame from another version", len(frame)-HeaderSize-r.Len())
+	}
+}
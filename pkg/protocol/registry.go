@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrUnknownOpcode = errors.New("unknown opcode")
+)
+
+type Handler func(msg *RawMessage) error
+
+// Registry routes messages to handlers by opcode. Safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[uint32]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[uint32]Handler),
+	}
+}
+
+// Register sets the handler for an opcode, replacing any previous one.
+func (r *Registry) Register(opcode uint32, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[opcode] = handler
+}
+
+func (r *Registry) Dispatch(msg *RawMessage) error {
+	r.mu.RLock()
+	handler, ok := r.handlers[msg.Opcode]
+	r.mu.RUnlock()
+
+	if !ok {
+		return ErrUnknownOpcode
+	}
+
+	return handler(msg)
+}
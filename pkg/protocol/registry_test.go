@@ -0,0 +1,58 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistryDispatch(t *testing.T) {
+	errHandler := errors.New("handler failed")
+
+	tests := []struct {
+		name   string
+		opcode uint32
+		fired  string
+		err    error
+	}{
+		{name: "first opcode", opcode: 1, fired: "wisdom"},
+		{name: "second opcode", opcode: 2, fired: "proof", err: errHandler},
+		{name: "unregistered opcode", opcode: 3, err: ErrUnknownOpcode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fired := ""
+			registry := NewRegistry()
+			registry.Register(1, func(msg *RawMessage) error {
+				fired = "wisdom"
+				return nil
+			})
+			registry.Register(2, func(msg *RawMessage) error {
+				fired = "proof"
+				return errHandler
+			})
+
+			err := registry.Dispatch(&RawMessage{Opcode: tt.opcode})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if fired != tt.fired {
+				t.Fatalf("handler %q fired, want %q", fired, tt.fired)
+			}
+		})
+	}
+}
+
+func TestRegistryRegisterReplaces(t *testing.T) {
+	registry := NewRegistry()
+	calls := []string{}
+	registry.Register(1, func(*RawMessage) error { calls = append(calls, "old"); return nil })
+	registry.Register(1, func(*RawMessage) error { calls = append(calls, "new"); return nil })
+
+	if err := registry.Dispatch(&RawMessage{Opcode: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != "new" {
+		t.Fatalf("calls = %v, want [new]", calls)
+	}
+}
@@ -0,0 +1,42 @@
+package requests
+
+import (
+	"encoding/binary"
+	"errors"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrInvalidBatchWisdomRequest = errors.New("invalid batch wisdom request")
+)
+
+// BatchWisdomRequest is the payload of OPCODE_REQUEST_WISDOM_BATCH.
+type BatchWisdomRequest struct {
+	Count int
+	// Category of the quotes asked for; empty for any.
+	Category string
+}
+
+// Batch wisdom request payload: count (2 bytes) | category (the rest, may
+// be empty).
+func (r BatchWisdomRequest) Encode() ([]byte, error) {
+	if r.Count < 1 || r.Count > protocol.MaxBatchSize {
+		return nil, protocol.ErrInvalidBatchSize
+	}
+	if len(r.Category) > protocol.MaxCategoryLength {
+		return nil, protocol.ErrCategoryTooLong
+	}
+
+	buff := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(r.Category)), uint16(r.Count))
+	return append(buff, r.Category...), nil
+}
+
+func (r *BatchWisdomRequest) Decode(buff []byte) error {
+	if len(buff) < 2 || len(buff)-2 > protocol.MaxCategoryLength {
+		return ErrInvalidBatchWisdomRequest
+	}
+
+	r.Count = int(binary.BigEndian.Uint16(buff[:2]))
+	r.Category = string(buff[2:])
+	return nil
+}
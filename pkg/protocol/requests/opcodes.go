@@ -3,4 +3,8 @@ package requests
 const (
 	OPCODE_REQUEST_WISDOM          uint32 = 1
 	OPCODE_REQUEST_CHALLENGE_PROOF uint32 = 2
+	OPCODE_SUBMIT_SOLUTION         uint32 = 3
+	OPCODE_HELLO_ACK               uint32 = 4
+	OPCODE_REQUEST_SESSION_WISDOM  uint32 = 5
+	OPCODE_REQUEST_WISDOM_BATCH    uint32 = 6
 )
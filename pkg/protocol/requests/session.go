@@ -0,0 +1,44 @@
+package requests
+
+import (
+	"errors"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrInvalidSessionWisdomRequest = errors.New("invalid session wisdom request")
+)
+
+// SessionWisdomRequest is the payload of OPCODE_REQUEST_SESSION_WISDOM.
+type SessionWisdomRequest struct {
+	Token protocol.SessionToken
+	// Category of the quote asked for; empty for any.
+	Category string
+}
+
+// Session wisdom request payload: session token | category (the rest, may
+// be empty).
+func (r SessionWisdomRequest) Encode() ([]byte, error) {
+	if len(r.Category) > protocol.MaxCategoryLength {
+		return nil, protocol.ErrCategoryTooLong
+	}
+
+	token, err := r.Token.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(token, r.Category...), nil
+}
+
+func (r *SessionWisdomRequest) Decode(buff []byte) error {
+	if len(buff) < protocol.SessionTokenSize {
+		return ErrInvalidSessionWisdomRequest
+	}
+	if err := r.Token.Decode(buff[:protocol.SessionTokenSize]); err != nil {
+		return errors.Join(err, ErrInvalidSessionWisdomRequest)
+	}
+
+	r.Category = string(buff[protocol.SessionTokenSize:])
+	return nil
+}
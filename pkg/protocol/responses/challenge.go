@@ -6,44 +6,30 @@ import (
 	"strconv"
 )
 
+// ChallengeResponse payload: data (16 bytes) | timestamp (8 bytes) |
+// difficulty in leading zero bits (8 bytes).
 type ChallengeResponse struct {
-	Data           [16]byte
-	Timestamp      uint64
-	Difficulty     uint64
-	ExpectedPrefix []byte
+	Data       [16]byte
+	Timestamp  uint64
+	Difficulty uint64
 }
 
 func (cr *ChallengeResponse) Encode() ([]byte, error) {
-	buff := make([]byte, 16+8+8+len(cr.ExpectedPrefix))
+	buff := make([]byte, 16+8+8)
 	copy(buff[:16], cr.Data[:])
 	binary.BigEndian.PutUint64(buff[16:24], uint64(cr.Timestamp))
 	binary.BigEndian.PutUint64(buff[24:32], uint64(cr.Difficulty))
-	copy(buff[32:], cr.ExpectedPrefix)
 	return buff, nil
 }
 
 func (cr *ChallengeResponse) Decode(buff []byte) error {
-	if len(buff) <= 16+8+8 {
-		return errors.New("invalid challenge response: too short [SIZE: " + strconv.Itoa(len(buff)) + "]")
+	if len(buff) != 16+8+8 {
+		return errors.New("invalid challenge response: wrong size [SIZE: " + strconv.Itoa(len(buff)) + "]")
 	}
 
-	var dataBuff [16]byte
-	copy(dataBuff[:], buff[:16])
-
-	timestamp := binary.BigEndian.Uint64(buff[16:24])
-	difficulty := binary.BigEndian.Uint64(buff[24:32])
-
-	if len(buff)-32 != int(difficulty) {
-		return errors.New("expected prefix length not the same as difficulty")
-	}
-
-	expectedPrefixBuff := make([]byte, difficulty)
-	copy(expectedPrefixBuff, buff[32:])
-
-	cr.Data = dataBuff
-	cr.Timestamp = timestamp
-	cr.Difficulty = difficulty
-	cr.ExpectedPrefix = expectedPrefixBuff
+	copy(cr.Data[:], buff[:16])
+	cr.Timestamp = binary.BigEndian.Uint64(buff[16:24])
+	cr.Difficulty = binary.BigEndian.Uint64(buff[24:32])
 
 	return nil
 }
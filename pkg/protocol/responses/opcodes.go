@@ -1,6 +1,15 @@
 package responses
 
 const (
-	RES_CODE_CHALLENGE uint32 = 1
-	RES_CODE_WISDOM    uint32 = 2
+	RES_CODE_CHALLENGE       uint32 = 1
+	RES_CODE_WISDOM          uint32 = 2
+	RES_CODE_POW_CHALLENGE   uint32 = 3
+	RES_CODE_HELLO           uint32 = 4
+	RES_CODE_SESSION         uint32 = 5
+	RES_CODE_BATCH_CHALLENGE uint32 = 6
+	RES_CODE_WISDOM_BATCH    uint32 = 7
+	// RES_CODE_CHALLENGE_TOKEN replaces RES_CODE_POW_CHALLENGE on servers
+	// issuing stateless challenges. Its payload is a protocol.ChallengeToken,
+	// returned as is in the solution's Token.
+	RES_CODE_CHALLENGE_TOKEN uint32 = 8
 )
@@ -1,5 +1,12 @@
 package responses
 
+import "wordofwisdom/pkg/protocol"
+
+var (
+	_ protocol.MessageEncoder = (*WisdomResponse)(nil)
+	_ protocol.MessageDecoder = (*WisdomResponse)(nil)
+)
+
 type WisdomResponse struct {
 	Quote string `json:"quote"`
 }
@@ -0,0 +1,70 @@
+package responses
+
+import (
+	"encoding/binary"
+	"errors"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrInvalidWisdomBatchPayload = errors.New("invalid wisdom batch payload")
+)
+
+var (
+	_ protocol.MessageEncoder = (*WisdomBatchResponse)(nil)
+	_ protocol.MessageDecoder = (*WisdomBatchResponse)(nil)
+)
+
+type WisdomBatchResponse struct {
+	Quotes []string `json:"quotes"`
+}
+
+// Wisdom batch payload: count (2 bytes) | per quote: length (4 bytes) | quote.
+func (w *WisdomBatchResponse) Encode() ([]byte, error) {
+	if len(w.Quotes) > protocol.MaxBatchSize {
+		return nil, protocol.ErrInvalidBatchSize
+	}
+
+	size := 2
+	for _, quote := range w.Quotes {
+		size += 4 + len(quote)
+	}
+
+	buff := binary.BigEndian.AppendUint16(make([]byte, 0, size), uint16(len(w.Quotes)))
+	for _, quote := range w.Quotes {
+		buff = binary.BigEndian.AppendUint32(buff, uint32(len(quote)))
+		buff = append(buff, quote...)
+	}
+	return buff, nil
+}
+
+func (w *WisdomBatchResponse) Decode(buff []byte) error {
+	if len(buff) < 2 {
+		return ErrInvalidWisdomBatchPayload
+	}
+
+	count := int(binary.BigEndian.Uint16(buff[:2]))
+	if count > protocol.MaxBatchSize {
+		return protocol.ErrInvalidBatchSize
+	}
+
+	quotes := make([]string, 0, count)
+	buff = buff[2:]
+	for range count {
+		if len(buff) < 4 {
+			return ErrInvalidWisdomBatchPayload
+		}
+		length := binary.BigEndian.Uint32(buff[:4])
+		if uint64(len(buff)-4) < uint64(length) {
+			return ErrInvalidWisdomBatchPayload
+		}
+		quotes = append(quotes, string(buff[4:4+length]))
+		buff = buff[4+length:]
+	}
+	if len(buff) != 0 {
+		return ErrInvalidWisdomBatchPayload
+	}
+
+	w.Quotes = quotes
+	return nil
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionExpired        = errors.New("session expired")
+	ErrInvalidSessionToken   = errors.New("invalid session token")
+	ErrInvalidSessionPayload = errors.New("invalid session token payload")
+)
+
+const (
+	SessionIDSize = 16
+
+	// SessionTokenSize is the encoded size of a SessionToken.
+	SessionTokenSize = sessionFieldsSize + TagSize
+
+	sessionFieldsSize = SessionIDSize + 8
+)
+
+// SessionToken lets a client that already solved a challenge request more
+// quotes without solving again until Expiry. It is signed by the server, so
+// the server keeps no session state.
+type SessionToken struct {
+	ID     [SessionIDSize]byte
+	Expiry time.Time
+	Tag    [TagSize]byte
+}
+
+// NewSessionToken issues a token valid for ttl signed under secret.
+func NewSessionToken(secret []byte, ttl time.Duration) (SessionToken, error) {
+	t := SessionToken{Expiry: time.Now().Add(ttl)}
+	if _, err := rand.Read(t.ID[:]); err != nil {
+		return SessionToken{}, err
+	}
+
+	t.Tag = t.computeTag(secret)
+	return t, nil
+}
+
+// Verify checks the token signature in constant time, then its expiry.
+func (t SessionToken) Verify(secret []byte, now time.Time) error {
+	expected := t.computeTag(secret)
+	if subtle.ConstantTimeCompare(expected[:], t.Tag[:]) != 1 {
+		return ErrInvalidSessionToken
+	}
+	if !now.Before(t.Expiry) {
+		return ErrSessionExpired
+	}
+
+	return nil
+}
+
+func (t SessionToken) computeTag(secret []byte) [TagSize]byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(t.fields())
+
+	var tag [TagSize]byte
+	copy(tag[:], mac.Sum(nil))
+	return tag
+}
+
+func (t SessionToken) fields() []byte {
+	buff := make([]byte, 0, sessionFieldsSize)
+	buff = append(buff, t.ID[:]...)
+	return binary.BigEndian.AppendUint64(buff, uint64(t.Expiry.UnixNano()))
+}
+
+// Session token payload: id (16 bytes) | expiry unix nanos (8 bytes) |
+// tag (32 bytes).
+func (t SessionToken) EncodedLen() int {
+	return SessionTokenSize
+}
+
+func (t SessionToken) Encode() ([]byte, error) {
+	return append(t.fields(), t.Tag[:]...), nil
+}
+
+func (t *SessionToken) Decode(buff []byte) error {
+	if len(buff) != SessionTokenSize {
+		return ErrInvalidSessionPayload
+	}
+
+	copy(t.ID[:], buff[:SessionIDSize])
+	t.Expiry = time.Unix(0, int64(binary.BigEndian.Uint64(buff[SessionIDSize:sessionFieldsSize])))
+	copy(t.Tag[:], buff[sessionFieldsSize:])
+	return nil
+}
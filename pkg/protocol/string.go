@@ -0,0 +1,70 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+var (
+	ErrStringTooLong        = errors.New("string too long")
+	ErrInvalidStringPayload = errors.New("invalid string payload")
+	ErrInvalidUTF8          = errors.New("string is not valid utf-8")
+)
+
+// MaxStringLength is the longest string, in bytes, a StringEncoder payload
+// can carry.
+const MaxStringLength = math.MaxUint16
+
+var (
+	_ SizedEncoder   = StringEncoder("")
+	_ MessageDecoder = StringDecoder{}
+)
+
+// StringEncoder encodes a UTF-8 string as a payload: length (2 bytes) | string.
+type StringEncoder string
+
+func (s StringEncoder) EncodedLen() int {
+	return 2 + len(s)
+}
+
+func (s StringEncoder) Encode() ([]byte, error) {
+	if len(s) > MaxStringLength {
+		return nil, ErrStringTooLong
+	}
+	if !utf8.ValidString(string(s)) {
+		return nil, ErrInvalidUTF8
+	}
+
+	buff := binary.BigEndian.AppendUint16(make([]byte, 0, s.EncodedLen()), uint16(len(s)))
+	return append(buff, s...), nil
+}
+
+// DecodeString reads a StringEncoder payload. The length prefix must match
+// the rest of the payload exactly.
+func DecodeString(buff []byte) (string, error) {
+	if len(buff) < 2 || int(binary.BigEndian.Uint16(buff[:2])) != len(buff)-2 {
+		return "", ErrInvalidStringPayload
+	}
+	if !utf8.Valid(buff[2:]) {
+		return "", ErrInvalidUTF8
+	}
+
+	return string(buff[2:]), nil
+}
+
+// StringDecoder decodes a StringEncoder payload into Value.
+type StringDecoder struct {
+	Value *string
+}
+
+func (d StringDecoder) Decode(buff []byte) error {
+	s, err := DecodeString(buff)
+	if err != nil {
+		return err
+	}
+
+	*d.Value = s
+	return nil
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "empty", s: ""},
+		{name: "ascii", s: "Wisdom begins in wonder."},
+		{name: "multibyte", s: "知之为知之 — Ζεύς 🦉"},
+		{name: "max length", s: strings.Repeat("a", MaxStringLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := StringEncoder(tt.s).Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(payload) != StringEncoder(tt.s).EncodedLen() {
+				t.Fatalf("encoded %d bytes, EncodedLen says %d", len(payload), StringEncoder(tt.s).EncodedLen())
+			}
+
+			got := ""
+			if err := (StringDecoder{Value: &got}).Decode(payload); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.s {
+				t.Fatalf("got %q, want %q", got, tt.s)
+			}
+
+			frame, err := BuildRawMessage(true, 1, StringEncoder(tt.s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(msg.Data, payload) {
+				t.Fatalf("frame payload %x, want %x", msg.Data, payload)
+			}
+		})
+	}
+}
+
+func TestStringEncoderRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		err  error
+	}{
+		{name: "too long", s: strings.Repeat("a", MaxStringLength+1), err: ErrStringTooLong},
+		{name: "invalid utf-8", s: "bad \xff byte", err: ErrInvalidUTF8},
+		{name: "lone continuation byte", s: "\x80", err: ErrInvalidUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := StringEncoder(tt.s).Encode(); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDecodeStringRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		err     error
+	}{
+		{name: "empty", payload: nil, err: ErrInvalidStringPayload},
+		{name: "half a length", payload: []byte{0}, err: ErrInvalidStringPayload},
+		{name: "truncated string", payload: []byte{0, 5, 'a', 'b'}, err: ErrInvalidStringPayload},
+		{name: "trailing bytes", payload: []byte{0, 1, 'a', 'b'}, err: ErrInvalidStringPayload},
+		{name: "multibyte rune cut short", payload: []byte{0, 2, 0xe7, 0x9f}, err: ErrInvalidUTF8},
+		{name: "invalid utf-8", payload: []byte{0, 1, 0xff}, err: ErrInvalidUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeString(tt.payload); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+func TestServerServesBatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxBatchSize int
+		count        int
+		code         uint32
+	}{
+		{name: "batch of 4", maxBatchSize: 8, count: 4},
+		{name: "batch of 1", maxBatchSize: 8, count: 1},
+		{name: "batch at the limit", maxBatchSize: 4, count: 4},
+		{name: "batch over the limit", maxBatchSize: 4, count: 5, code: protocol.ERR_CODE_INVALID_BATCH},
+		{name: "batches disabled", count: 4, code: protocol.ERR_CODE_INVALID_BATCH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{MaxBatchSize: tt.maxBatchSize})
+			conn := pipe(t, srv)
+
+			opening := readChallenge(t, conn)
+			sendFrame(t, conn, requests.OPCODE_REQUEST_WISDOM_BATCH, requests.BatchWisdomRequest{Count: tt.count})
+			if tt.code != 0 {
+				expectError(t, conn, tt.code)
+				return
+			}
+
+			msg := readFrame(t, conn)
+			if msg.Opcode != responses.RES_CODE_BATCH_CHALLENGE {
+				t.Fatalf("got opcode %d (success %v), want a batch challenge", msg.Opcode, msg.IsSuccess())
+			}
+			batch := protocol.BatchChallenge{}
+			if err := msg.DecodePayload(&batch); err != nil {
+				t.Fatal(err)
+			}
+			if want := protocol.BatchDifficulty(opening.Difficulty, tt.count); batch.Count != tt.count || batch.Challenge.Difficulty != want {
+				t.Fatalf("batch of %d at difficulty %d, want %d at %d", batch.Count, batch.Challenge.Difficulty, tt.count, want)
+			}
+
+			submitSolution(t, conn, batch.Challenge)
+			msg = readFrame(t, conn)
+			wisdom := responses.WisdomBatchResponse{}
+			if msg.Opcode != responses.RES_CODE_WISDOM_BATCH {
+				t.Fatalf("got opcode %d (success %v), want a wisdom batch", msg.Opcode, msg.IsSuccess())
+			}
+			if err := msg.DecodePayload(&wisdom); err != nil {
+				t.Fatal(err)
+			}
+			if len(wisdom.Quotes) != tt.count {
+				t.Fatalf("got %d quotes, want %d", len(wisdom.Quotes), tt.count)
+			}
+			for i, quote := range wisdom.Quotes {
+				if quote != testQuote {
+					t.Fatalf("quote %d is %q, want %q", i, quote, testQuote)
+				}
+			}
+		})
+	}
+}
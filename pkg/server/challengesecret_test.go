@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+)
+
+var testChallengeSecret = []byte("test challenge secret")
+
+func TestServerSignsChallenges(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "inline", cfg: Config{ChallengeSecret: testChallengeSecret}},
+		{name: "verifier pool", cfg: Config{ChallengeSecret: testChallengeSecret, VerifyWorkers: 1, VerifyQueueSize: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := pipe(t, newTestServer(t, tt.cfg))
+
+			challenge := readChallenge(t, conn)
+			if !challenge.VerifyTag(testChallengeSecret) {
+				t.Fatal("issued challenge does not carry a valid tag")
+			}
+			submitSolution(t, conn, challenge)
+			expectWisdom(t, conn)
+		})
+	}
+}
+
+func TestServerRejectsForgedChallenges(t *testing.T) {
+	srv := newTestServer(t, Config{ChallengeSecret: testChallengeSecret})
+	issued, err := srv.newChallenge(protocol.AlgoSHA256, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		challenge func() protocol.Challenge
+		err       error
+	}{
+		{
+			name:      "issued",
+			challenge: func() protocol.Challenge { return issued },
+		},
+		{
+			name: "signed with another secret",
+			challenge: func() protocol.Challenge {
+				forged := issued
+				forged.Sign([]byte("another secret"))
+				return forged
+			},
+			err: protocol.ErrInvalidChallengeTag,
+		},
+		{
+			name: "lowered difficulty",
+			challenge: func() protocol.Challenge {
+				tampered := issued
+				tampered.Difficulty = 1
+				tampered.Nonce[0] ^= 1
+				return tampered
+			},
+			err: protocol.ErrInvalidChallengeTag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge := tt.challenge()
+			solution, err := protocol.SolveChallenge(challenge, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = srv.verifySolution(context.Background(), challenge, solution, false)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if got := failureReason(challenge, solution, err); got != failureForged {
+					t.Fatalf("logged as %q, want %q", got, failureForged)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+var (
+	testChallengeKey    = protocol.ChallengeKey{ID: 1, Secret: []byte("first challenge key")}
+	rotatedChallengeKey = protocol.ChallengeKey{ID: 2, Secret: []byte("second challenge key")}
+)
+
+func readChallengeToken(t *testing.T, conn net.Conn) protocol.ChallengeToken {
+	t.Helper()
+
+	msg := readFrame(t, conn)
+	if msg.Opcode != responses.RES_CODE_CHALLENGE_TOKEN {
+		t.Fatalf("got opcode %d, want a challenge token", msg.Opcode)
+	}
+	token := protocol.ChallengeToken{}
+	if err := msg.DecodePayload(&token); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// submitTokenSolution solves the challenge in token and sends the solution
+// with the token attached.
+func submitTokenSolution(t *testing.T, conn net.Conn, token protocol.ChallengeToken) {
+	t.Helper()
+
+	solution, err := protocol.SolveChallenge(token.Challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution.Token, err = token.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, solution)
+}
+
+func TestServerVerifiesChallengeTokensStatelessly(t *testing.T) {
+	issuer := newTestServer(t, Config{ChallengeKey: &testChallengeKey})
+
+	tests := []struct {
+		name string
+		// verifier is the config of the server the solution is sent to, on a
+		// connection of its own.
+		verifier Config
+		accepted bool
+	}{
+		{name: "same key", verifier: Config{ChallengeKey: &testChallengeKey}, accepted: true},
+		{name: "same key on a verifier pool", verifier: Config{ChallengeKey: &testChallengeKey, VerifyWorkers: 1, VerifyQueueSize: 1}, accepted: true},
+		{
+			name: "retired key",
+			verifier: Config{
+				ChallengeKey:  &rotatedChallengeKey,
+				ChallengeKeys: protocol.Keyring{testChallengeKey.ID: testChallengeKey.Secret},
+			},
+			accepted: true,
+		},
+		{name: "unknown key", verifier: Config{ChallengeKey: &rotatedChallengeKey}},
+		{name: "stateful server", verifier: Config{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := readChallengeToken(t, pipe(t, issuer))
+
+			conn := pipe(t, newTestServer(t, tt.verifier))
+			// The verifier's own challenge is left unsolved.
+			readFrame(t, conn)
+			submitTokenSolution(t, conn, token)
+
+			if tt.accepted {
+				expectWisdom(t, conn)
+			} else {
+				expectError(t, conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+			}
+		})
+	}
+}
+
+func TestServerRejectsBadChallengeTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(token *protocol.ChallengeToken)
+	}{
+		{name: "lowered difficulty", tamper: func(token *protocol.ChallengeToken) { token.Challenge.Difficulty = 1 }},
+		{name: "other key id", tamper: func(token *protocol.ChallengeToken) { token.KeyID = rotatedChallengeKey.ID }},
+		{name: "re-signed", tamper: func(token *protocol.ChallengeToken) { token.Sign([]byte("guessed secret")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{
+				ChallengeKey:  &testChallengeKey,
+				ChallengeKeys: protocol.Keyring{rotatedChallengeKey.ID: rotatedChallengeKey.Secret},
+			})
+			conn := pipe(t, srv)
+
+			token := readChallengeToken(t, conn)
+			tt.tamper(&token)
+			submitTokenSolution(t, conn, token)
+			expectError(t, conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+		})
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		conn := pipe(t, newTestServer(t, Config{ChallengeKey: &testChallengeKey}))
+
+		token := readChallengeToken(t, conn)
+		submitSolution(t, conn, token.Challenge)
+		expectError(t, conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+	})
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net"
+	"wordofwisdom/pkg/protocol"
+)
+
+// readMessage returns the next client message, answering heartbeat pings
+// with a pong so handlers never see them. Pings do not extend the caller's
+// read deadline.
+func readMessage(conn net.Conn, maxMessageSizeBytes int) (*protocol.RawMessage, error) {
+	for {
+		msg, err := protocol.ReadMessage(conn, maxMessageSizeBytes)
+		if err != nil {
+			return nil, err
+		}
+		if msg.Opcode != protocol.OpcodePing {
+			return msg, nil
+		}
+		if err := writeMessage(conn, true, protocol.OpcodePong, nil); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeMessage(conn net.Conn, success bool, opcode uint32, payload protocol.MessageEncoder) error {
+	frame, err := protocol.BuildRawMessage(success, opcode, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(frame)
+	return err
+}
+
+// writeError sends a failure frame with the code as opcode and an
+// ErrorResponse payload describing err.
+func writeError(conn net.Conn, code uint32, err error) error {
+	return writeMessage(conn, false, code, protocol.ErrorResponse{Code: code, Message: err.Error()})
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+func TestServerClosesOnOversizedFrame(t *testing.T) {
+	srv := newTestServer(t, Config{})
+	conn := pipe(t, srv)
+	readChallenge(t, conn)
+
+	frame, err := protocol.BuildRawMessage(true, requests.OPCODE_SUBMIT_SOLUTION, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Declares 1GB but never sends it.
+	binary.BigEndian.PutUint32(frame[10:14], 1<<30)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.Copy(io.Discard, conn); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("server did not close the connection: %v", err)
+	}
+}
+
+// expectPong pings the server and waits for the pong.
+func expectPong(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	sendFrame(t, conn, protocol.OpcodePing, nil)
+	if msg := readFrame(t, conn); !msg.IsSuccess() || msg.Opcode != protocol.OpcodePong {
+		t.Fatalf("got opcode %d (success %v), want a pong", msg.Opcode, msg.IsSuccess())
+	}
+}
+
+func TestServerAnswersPings(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "plain"},
+		{name: "negotiated", cfg: Config{Algorithms: []protocol.Algorithm{protocol.AlgoSHA256}}},
+		{name: "with idle timeout", cfg: Config{IdleTimeout: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := pipe(t, newTestServer(t, tt.cfg))
+
+			if len(tt.cfg.Algorithms) > 0 {
+				readFrame(t, conn)
+				expectPong(t, conn)
+				sendFrame(t, conn, requests.OPCODE_HELLO_ACK, protocol.HelloAck{Algorithm: protocol.AlgoSHA256})
+			}
+			challenge := readChallenge(t, conn)
+			expectPong(t, conn)
+			submitSolution(t, conn, challenge)
+			expectWisdom(t, conn)
+
+			expectPong(t, conn)
+			expectPong(t, conn)
+			sendFrame(t, conn, requests.OPCODE_REQUEST_WISDOM, nil)
+			submitSolution(t, conn, readChallenge(t, conn))
+			expectWisdom(t, conn)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+type connKey struct{}
+
+// recordingQuotes hands out testQuote and keeps the context of every
+// QuoteContext call, so a test can watch the connection's lifetime.
+type recordingQuotes struct {
+	ctxs chan context.Context
+}
+
+func (q recordingQuotes) Quote() string {
+	return testQuote
+}
+
+func (q recordingQuotes) QuoteContext(ctx context.Context) (string, error) {
+	q.ctxs <- ctx
+	return testQuote, nil
+}
+
+func TestConnContextCancelledWhenConnectionEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(srv *Server, conn net.Conn)
+	}{
+		{
+			name: "client closes mid-handshake",
+			end: func(_ *Server, conn net.Conn) {
+				sendFrame(t, conn, requests.OPCODE_REQUEST_WISDOM, nil)
+				readChallenge(t, conn)
+				conn.Close()
+			},
+		},
+		{name: "client closes while idle", end: func(_ *Server, conn net.Conn) { conn.Close() }},
+		{name: "server closed", end: func(srv *Server, _ net.Conn) { srv.Close() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := recordingQuotes{ctxs: make(chan context.Context, 1)}
+			srv := NewServer(context.Background(), Config{
+				Difficulty:          4,
+				MaxMessageSizeBytes: 1024,
+				ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+					return context.WithValue(ctx, connKey{}, conn.RemoteAddr().String())
+				},
+			}, quotes)
+			defer srv.Close()
+
+			conn := pipe(t, srv)
+			submitSolution(t, conn, readChallenge(t, conn))
+			expectWisdom(t, conn)
+			ctx := <-quotes.ctxs
+
+			if ctx.Value(connKey{}) == nil {
+				t.Fatal("quote context does not derive from ConnContext")
+			}
+			if err := ctx.Err(); err != nil {
+				t.Fatalf("connection context done while the connection is open: %v", err)
+			}
+
+			tt.end(srv, conn)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				t.Fatal("connection context still live after the connection ended")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+// connectionMetrics counts connection events and ignores the rest.
+type connectionMetrics struct {
+	noopMetrics
+	active   atomic.Int64
+	rejected atomic.Int64
+}
+
+func (m *connectionMetrics) ConnectionOpened()   { m.active.Add(1) }
+func (m *connectionMetrics) ConnectionClosed()   { m.active.Add(-1) }
+func (m *connectionMetrics) ConnectionRejected() { m.rejected.Add(1) }
+
+func TestServerLimitsConcurrentConnections(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		queue      time.Duration
+		wantWithin time.Duration
+	}{
+		{name: "refused right away", limit: 2, wantWithin: 500 * time.Millisecond},
+		{name: "refused after the queue timeout", limit: 1, queue: 50 * time.Millisecond, wantWithin: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &connectionMetrics{}
+			srv := newTestServer(t, Config{
+				MaxConcurrentConnections: tt.limit,
+				ConnectionQueueTimeout:   tt.queue,
+				Metrics:                  metrics,
+			})
+			for range tt.limit {
+				readChallenge(t, pipe(t, srv))
+			}
+			if got := metrics.active.Load(); got != int64(tt.limit) {
+				t.Fatalf("active connections = %d, want %d", got, tt.limit)
+			}
+
+			start := time.Now()
+			refused := pipe(t, srv)
+			expectError(t, refused, protocol.ERR_CODE_SERVER_BUSY)
+			if elapsed := time.Since(start); elapsed < tt.queue || elapsed > tt.wantWithin {
+				t.Fatalf("refused after %v, want between %v and %v", elapsed, tt.queue, tt.wantWithin)
+			}
+			expectClosed(t, refused)
+			if got := metrics.rejected.Load(); got != 1 {
+				t.Fatalf("rejected connections = %d, want 1", got)
+			}
+			if got := metrics.active.Load(); got != int64(tt.limit) {
+				t.Fatalf("active connections = %d after a refusal, want %d", got, tt.limit)
+			}
+		})
+	}
+}
+
+func TestServerQueuedConnectionGetsFreedSlot(t *testing.T) {
+	srv := newTestServer(t, Config{
+		MaxConcurrentConnections: 1,
+		ConnectionQueueTimeout:   5 * time.Second,
+	})
+	first := pipe(t, srv)
+	challenge := readChallenge(t, first)
+
+	queued := pipe(t, srv)
+	submitSolution(t, first, challenge)
+	expectWisdom(t, first)
+	first.Close()
+
+	// The queued connection takes the slot once the first one is done.
+	submitSolution(t, queued, readChallenge(t, queued))
+	expectWisdom(t, queued)
+}
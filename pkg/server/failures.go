@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+	"wordofwisdom/internal/pow"
+	"wordofwisdom/pkg/protocol"
+)
+
+// Reasons a solution is rejected, as logged.
+const (
+	failureMalformed  = "malformed"
+	failureExpired    = "expired"
+	failureReplayed   = "replayed"
+	failureMismatched = "mismatched"
+	failureForged     = "forged"
+	failureBadHash    = "bad_hash"
+)
+
+// fingerprintSize is how many bytes of the payload hash are logged.
+const fingerprintSize = 8
+
+// failureReason classifies a failed verification. Only a solution the nonce
+// cache has already accepted counts as replayed; one carrying another
+// challenge's nonce is a mismatch.
+func failureReason(challenge protocol.Challenge, solution protocol.Solution, err error) string {
+	switch {
+	case errors.Is(err, protocol.ErrChallengeExpired):
+		return failureExpired
+	case errors.Is(err, pow.ErrReplayedChallenge):
+		return failureReplayed
+	case errors.Is(err, protocol.ErrInvalidTokenPayload):
+		return failureMalformed
+	case errors.Is(err, protocol.ErrInvalidChallengeTag), errors.Is(err, protocol.ErrUnknownChallengeKey):
+		return failureForged
+	case solution.Nonce != challenge.Nonce:
+		return failureMismatched
+	default:
+		return failureBadHash
+	}
+}
+
+// fingerprint identifies a payload in logs without revealing it.
+func fingerprint(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:fingerprintSize])
+}
+
+// recordFailure logs a rejected solution and, if the rate limiter or the
+// reputation store keep track of failures, reports it there so repeat
+// offenders run out of allowance sooner and get harder challenges.
+func (s *Server) recordFailure(conn net.Conn, challenge protocol.Challenge, msg *protocol.RawMessage, reason string, issued time.Time) {
+	s.metrics.VerificationFailed()
+	s.logger.Warn("Solution rejected",
+		slog.String("remote_ip", addrIP(conn.RemoteAddr())),
+		slog.Int("difficulty", challenge.Difficulty),
+		slog.String("reason", reason),
+		slog.Duration("solve_time", time.Since(issued)),
+		slog.String("fingerprint", fingerprint(msg.Data)),
+	)
+
+	if recorder, ok := s.cfg.RateLimiter.(FailureRecorder); ok {
+		recorder.RecordFailure(conn.RemoteAddr())
+	}
+	if recorder, ok := s.cfg.Reputation.(FailureRecorder); ok {
+		recorder.RecordFailure(conn.RemoteAddr())
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+// syncBuffer collects log output written from connection goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records decodes every JSON log line written so far.
+func (b *syncBuffer) records(t *testing.T) []map[string]any {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(b.buf.Bytes()), []byte("\n")) {
+		record := map[string]any{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// badCounter returns a solution to challenge whose hash misses the target.
+func badCounter(t *testing.T, challenge protocol.Challenge) protocol.Solution {
+	t.Helper()
+
+	solution := protocol.Solution{Nonce: challenge.Nonce}
+	for protocol.VerifySolution(challenge, solution, time.Now()) == nil {
+		solution.Counter++
+	}
+	return solution
+}
+
+func TestServerLogsFailureReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		submit func(t *testing.T, srv *Server) net.Conn
+		reason string
+	}{
+		{
+			name: "bad hash",
+			submit: func(t *testing.T, srv *Server) net.Conn {
+				conn := pipe(t, srv)
+				sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, badCounter(t, readChallenge(t, conn)))
+				return conn
+			},
+			reason: failureBadHash,
+		},
+		{
+			name: "mismatched nonce",
+			submit: func(t *testing.T, srv *Server) net.Conn {
+				conn := pipe(t, srv)
+				challenge := readChallenge(t, conn)
+				challenge.Nonce[0] ^= 0xff
+				submitSolution(t, conn, challenge)
+				return conn
+			},
+			reason: failureMismatched,
+		},
+		{
+			name: "replayed",
+			cfg:  Config{NonceSource: repeatReader{b: 3}},
+			submit: func(t *testing.T, srv *Server) net.Conn {
+				first := pipe(t, srv)
+				solution := submitSolution(t, first, readChallenge(t, first))
+				if msg := readFrame(t, first); msg.Opcode != responses.RES_CODE_WISDOM {
+					t.Fatalf("first submission got opcode %d, want wisdom", msg.Opcode)
+				}
+
+				conn := pipe(t, srv)
+				readChallenge(t, conn)
+				sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, solution)
+				return conn
+			},
+			reason: failureReplayed,
+		},
+		{
+			name: "expired",
+			cfg:  Config{ChallengeTTL: time.Nanosecond},
+			submit: func(t *testing.T, srv *Server) net.Conn {
+				conn := pipe(t, srv)
+				challenge := readChallenge(t, conn)
+				time.Sleep(time.Millisecond)
+				submitSolution(t, conn, challenge)
+				return conn
+			},
+			reason: failureExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := &syncBuffer{}
+			tt.cfg.Logger = slog.New(slog.NewJSONHandler(logs, nil))
+			srv := newTestServer(t, tt.cfg)
+
+			conn := tt.submit(t, srv)
+			expectError(t, conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+
+			var rejected []map[string]any
+			for _, record := range logs.records(t) {
+				if record["msg"] == "Solution rejected" {
+					rejected = append(rejected, record)
+				}
+			}
+			if len(rejected) != 1 {
+				t.Fatalf("got %d rejection logs, want 1", len(rejected))
+			}
+			record := rejected[0]
+			if record["level"] != "WARN" || record["reason"] != tt.reason {
+				t.Fatalf("got level %v reason %v, want WARN %s", record["level"], record["reason"], tt.reason)
+			}
+			for _, key := range []string{"remote_ip", "difficulty", "solve_time", "fingerprint"} {
+				if _, ok := record[key]; !ok {
+					t.Errorf("log is missing %q", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+func TestServerRoundTripOverPipe(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{name: "configured ttl", ttl: 10 * time.Second, want: 10 * time.Second},
+		{name: "zero ttl defaults", ttl: 0, want: DefaultChallengeTTL},
+		{name: "negative ttl defaults", ttl: -time.Second, want: DefaultChallengeTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{ChallengeTTL: tt.ttl})
+			conn := pipe(t, srv)
+
+			before := time.Now()
+			challenge := readChallenge(t, conn)
+			after := time.Now()
+			if challenge.Expiry.Before(before.Add(tt.want)) || challenge.Expiry.After(after.Add(tt.want)) {
+				t.Fatalf("challenge expires at %v, want %v after issue", challenge.Expiry, tt.want)
+			}
+
+			submitSolution(t, conn, challenge)
+			msg := readFrame(t, conn)
+			if msg.Opcode != responses.RES_CODE_WISDOM {
+				t.Fatalf("got opcode %d, want wisdom", msg.Opcode)
+			}
+			wisdom := responses.WisdomResponse{}
+			if err := msg.DecodePayload(&wisdom); err != nil {
+				t.Fatal(err)
+			}
+			if wisdom.Quote != testQuote {
+				t.Fatalf("quote = %q, want %q", wisdom.Quote, testQuote)
+			}
+		})
+	}
+}
+
+func TestServerNegotiatesAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered []protocol.Algorithm
+		ack     protocol.Algorithm
+		errCode uint32
+	}{
+		{name: "client picks sha256", offered: []protocol.Algorithm{protocol.AlgoSHA256, protocol.AlgoArgon2id}, ack: protocol.AlgoSHA256},
+		{name: "client picks argon2id", offered: []protocol.Algorithm{protocol.AlgoSHA256, protocol.AlgoArgon2id}, ack: protocol.AlgoArgon2id},
+		{name: "client picks unoffered", offered: []protocol.Algorithm{protocol.AlgoSHA256}, ack: protocol.AlgoArgon2id, errCode: protocol.ERR_CODE_UNSUPPORTED_ALGORITHM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{Algorithms: tt.offered})
+			conn := pipe(t, srv)
+
+			msg := readFrame(t, conn)
+			hello := protocol.HelloMessage{}
+			if msg.Opcode != responses.RES_CODE_HELLO {
+				t.Fatalf("got opcode %d, want hello", msg.Opcode)
+			}
+			if err := msg.DecodePayload(&hello); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(hello.Algorithms, tt.offered) || hello.MinDifficulty != 4 {
+				t.Fatalf("hello advertised %+v", hello)
+			}
+
+			sendFrame(t, conn, requests.OPCODE_HELLO_ACK, protocol.HelloAck{Algorithm: tt.ack})
+			if tt.errCode != 0 {
+				expectError(t, conn, tt.errCode)
+				return
+			}
+			challenge := readChallenge(t, conn)
+			if challenge.Algorithm != tt.ack {
+				t.Fatalf("challenge uses %v, want %v", challenge.Algorithm, tt.ack)
+			}
+		})
+	}
+}
+
+func TestServerDropsStalledHandshake(t *testing.T) {
+	tests := []struct {
+		name  string
+		stall func(t *testing.T, conn net.Conn)
+	}{
+		{name: "never submits", stall: func(t *testing.T, conn net.Conn) {}},
+		{name: "half a header", stall: func(t *testing.T, conn net.Conn) {
+			if _, err := conn.Write([]byte{protocol.CurrentVersion, 0}); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{HandshakeTimeout: 50 * time.Millisecond})
+			conn := pipe(t, srv)
+
+			readChallenge(t, conn)
+			start := time.Now()
+			tt.stall(t, conn)
+
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, err := conn.Read(make([]byte, 1))
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("read = %v, want EOF from the server closing", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("server held the stalled client for %v", elapsed)
+			}
+		})
+	}
+}
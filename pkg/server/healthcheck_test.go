@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+func TestServerAnswersHealthCheckWithoutSolution(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		// opening is the frame the server sends before the health check.
+		opening uint32
+	}{
+		{name: "instead of a solution", cfg: Config{Difficulty: 30}, opening: responses.RES_CODE_POW_CHALLENGE},
+		{name: "instead of a hello ack", cfg: Config{Algorithms: []protocol.Algorithm{protocol.AlgoSHA256}}, opening: responses.RES_CODE_HELLO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := pipe(t, newTestServer(t, tt.cfg))
+
+			if msg := readFrame(t, conn); !msg.IsSuccess() || msg.Opcode != tt.opening {
+				t.Fatalf("opening opcode = %d, want %d", msg.Opcode, tt.opening)
+			}
+			sendFrame(t, conn, protocol.OpcodeHealthCheck, nil)
+
+			msg := readFrame(t, conn)
+			if !msg.IsSuccess() || msg.Opcode != protocol.OpcodeHealthCheck {
+				t.Fatalf("got opcode %d (success %v), want a health check reply", msg.Opcode, msg.IsSuccess())
+			}
+			expectClosed(t, conn)
+		})
+	}
+}
+
+func TestServerRateLimitsHealthChecks(t *testing.T) {
+	srv := newTestServer(t, Config{RateLimiter: NewTokenBucketLimiter(0.001, 1, time.Minute)})
+
+	conn := pipe(t, srv)
+	readChallenge(t, conn)
+	sendFrame(t, conn, protocol.OpcodeHealthCheck, nil)
+	if msg := readFrame(t, conn); msg.Opcode != protocol.OpcodeHealthCheck {
+		t.Fatalf("got opcode %d, want a health check reply", msg.Opcode)
+	}
+
+	conn = pipe(t, srv)
+	expectError(t, conn, protocol.ERR_CODE_RATE_LIMITED)
+}
@@ -0,0 +1,27 @@
+package server
+
+import "time"
+
+// Metrics receives server events. See pkg/server/metrics for a Prometheus
+// implementation; the server itself does not depend on any metrics library.
+type Metrics interface {
+	ChallengeIssued()
+	SolutionVerified()
+	VerificationFailed()
+	HandshakeDuration(d time.Duration)
+	ConnectionOpened()
+	ConnectionClosed()
+	// ConnectionRejected is called for connections turned away by
+	// MaxConcurrentConnections.
+	ConnectionRejected()
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ChallengeIssued()                {}
+func (noopMetrics) SolutionVerified()               {}
+func (noopMetrics) VerificationFailed()             {}
+func (noopMetrics) HandshakeDuration(time.Duration) {}
+func (noopMetrics) ConnectionOpened()               {}
+func (noopMetrics) ConnectionClosed()               {}
+func (noopMetrics) ConnectionRejected()             {}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "wordofwisdom"
+
+// Prometheus implements server.Metrics with Prometheus collectors.
+type Prometheus struct {
+	challengesIssued   prometheus.Counter
+	solutionsVerified  prometheus.Counter
+	verificationFailed prometheus.Counter
+	handshakeDuration  prometheus.Histogram
+	activeConnections  prometheus.Gauge
+	rejectedConns      prometheus.Counter
+}
+
+// NewPrometheus creates the collectors and registers them with reg.
+func NewPrometheus(reg prometheus.Registerer) (*Prometheus, error) {
+	m := &Prometheus{
+		challengesIssued: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "challenges_issued_total",
+			Help:      "Proof-of-work challenges sent to clients.",
+		}),
+		solutionsVerified: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "solutions_verified_total",
+			Help:      "Solutions that passed verification.",
+		}),
+		verificationFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "verification_failures_total",
+			Help:      "Solutions that were malformed, expired or wrong.",
+		}),
+		handshakeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handshake_duration_seconds",
+			Help:      "Time from issuing a challenge to serving the quote.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+		}),
+		activeConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "active_connections",
+			Help:      "Connections currently being served.",
+		}),
+		rejectedConns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "connections_rejected_total",
+			Help:      "Connections refused because the server was at capacity.",
+		}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.challengesIssued,
+		m.solutionsVerified,
+		m.verificationFailed,
+		m.handshakeDuration,
+		m.activeConnections,
+		m.rejectedConns,
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Prometheus) ChallengeIssued() {
+	m.challengesIssued.Inc()
+}
+
+func (m *Prometheus) SolutionVerified() {
+	m.solutionsVerified.Inc()
+}
+
+func (m *Prometheus) VerificationFailed() {
+	m.verificationFailed.Inc()
+}
+
+func (m *Prometheus) HandshakeDuration(d time.Duration) {
+	m.handshakeDuration.Observe(d.Seconds())
+}
+
+func (m *Prometheus) ConnectionOpened() {
+	m.activeConnections.Inc()
+}
+
+func (m *Prometheus) ConnectionClosed() {
+	m.activeConnections.Dec()
+}
+
+func (m *Prometheus) ConnectionRejected() {
+	m.rejectedConns.Inc()
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/server"
+	"wordofwisdom/pkg/server_sdk"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrape gathers reg and returns the value of every counter, gauge and
+// histogram sample count by metric name.
+func scrape(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				values[family.GetName()] += metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[family.GetName()] += metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return values
+}
+
+func TestPrometheusCountsHandshakes(t *testing.T) {
+	tests := []struct {
+		name       string
+		handshakes int
+	}{
+		{name: "one handshake", handshakes: 1},
+		{name: "three handshakes", handshakes: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			m, err := NewPrometheus(reg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			quotes, err := server.NewSliceQuoteProvider([]string{"measured"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := server.NewServer(context.Background(), server.Config{
+				Difficulty:          4,
+				MaxMessageSizeBytes: 1024,
+				Metrics:             m,
+			}, quotes)
+			defer srv.Close()
+			transport := server_sdk.NewPipeTransport()
+			go srv.Serve(transport)
+
+			client := server_sdk.NewClient(server_sdk.ClientConfig{
+				ServerAddress:       "pipe",
+				MaxMessageSizeBytes: 1024,
+				PopMessageTimeout:   5 * time.Second,
+				Options:             []server_sdk.Option{server_sdk.WithTransport(transport)},
+			})
+			for range tt.handshakes {
+				if _, err := client.GetWisdom(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The server finishes its side of a connection after the client
+			// has its quote.
+			want := map[string]float64{
+				"wordofwisdom_challenges_issued_total":     float64(tt.handshakes),
+				"wordofwisdom_solutions_verified_total":    float64(tt.handshakes),
+				"wordofwisdom_verification_failures_total": 0,
+				"wordofwisdom_handshake_duration_seconds":  float64(tt.handshakes),
+				"wordofwisdom_active_connections":          0,
+				"wordofwisdom_connections_rejected_total":  0,
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				got := scrape(t, reg)
+				mismatch := ""
+				for name, value := range want {
+					if got[name] != value {
+						mismatch = name
+					}
+				}
+				if mismatch == "" {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("%s = %v, want %v", mismatch, got[mismatch], want[mismatch])
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestNewPrometheusRejectsDoubleRegistration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if _, err := NewPrometheus(reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPrometheus(reg); err == nil {
+		t.Fatal("registering the collectors twice succeeded")
+	}
+}
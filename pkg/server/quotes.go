@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+var (
+	ErrNoQuotes        = errors.New("no quotes provided")
+	ErrInvalidWeight   = errors.New("quote weight must be positive")
+	ErrUnknownCategory = errors.New("unknown quote category")
+)
+
+// SliceQuoteProvider picks quotes uniformly at random.
+type SliceQuoteProvider struct {
+	quotes []string
+}
+
+func NewSliceQuoteProvider(quotes []string) (*SliceQuoteProvider, error) {
+	if len(quotes) == 0 {
+		return nil, ErrNoQuotes
+	}
+
+	return &SliceQuoteProvider{quotes: quotes}, nil
+}
+
+func (p *SliceQuoteProvider) Quote() string {
+	return p.quotes[rand.Intn(len(p.quotes))]
+}
+
+type WeightedQuote struct {
+	Quote  string
+	Weight float64
+}
+
+// WeightedQuoteProvider picks each quote with probability proportional to
+// its weight, using a binary search over cumulative weights.
+type WeightedQuoteProvider struct {
+	quotes     []string
+	cumulative []float64
+}
+
+func NewWeightedQuoteProvider(quotes []WeightedQuote) (*WeightedQuoteProvider, error) {
+	if len(quotes) == 0 {
+		return nil, ErrNoQuotes
+	}
+
+	p := &WeightedQuoteProvider{
+		quotes:     make([]string, len(quotes)),
+		cumulative: make([]float64, len(quotes)),
+	}
+
+	total := 0.0
+	for i, q := range quotes {
+		if q.Weight <= 0 {
+			return nil, ErrInvalidWeight
+		}
+		total += q.Weight
+		p.quotes[i] = q.Quote
+		p.cumulative[i] = total
+	}
+
+	return p, nil
+}
+
+func (p *WeightedQuoteProvider) Quote() string {
+	total := p.cumulative[len(p.cumulative)-1]
+	target := rand.Float64() * total
+	idx := sort.Search(len(p.cumulative), func(i int) bool {
+		return p.cumulative[i] > target
+	})
+
+	return p.quotes[min(idx, len(p.quotes)-1)]
+}
+
+// ContextQuoteProvider is implemented by quote providers that do slow work,
+// e.g. a database lookup. The server then calls QuoteContext with the
+// connection's context, which is cancelled once the client is gone.
+type ContextQuoteProvider interface {
+	QuoteProvider
+	QuoteContext(ctx context.Context) (string, error)
+}
+
+// CategoryQuoteProvider serves quotes from named categories.
+type CategoryQuoteProvider interface {
+	Quote(category string) (string, error)
+}
+
+// CategorizedQuoteProvider picks quotes uniformly at random from the asked
+// category.
+type CategorizedQuoteProvider struct {
+	categories map[string][]string
+}
+
+func NewCategorizedQuoteProvider(categories map[string][]string) (*CategorizedQuoteProvider, error) {
+	p := &CategorizedQuoteProvider{categories: make(map[string][]string, len(categories))}
+	for category, quotes := range categories {
+		if len(quotes) == 0 {
+			return nil, ErrNoQuotes
+		}
+		p.categories[category] = quotes
+	}
+	if len(p.categories) == 0 {
+		return nil, ErrNoQuotes
+	}
+
+	return p, nil
+}
+
+func (p *CategorizedQuoteProvider) Quote(category string) (string, error) {
+	quotes, ok := p.categories[category]
+	if !ok {
+		return "", ErrUnknownCategory
+	}
+
+	return quotes[rand.Intn(len(quotes))], nil
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+const draws = 100_000
+
+// frequencies draws from quote and returns how often each quote came up.
+func frequencies(quote func() string) map[string]float64 {
+	counts := map[string]float64{}
+	for range draws {
+		counts[quote()]++
+	}
+	for q := range counts {
+		counts[q] /= draws
+	}
+	return counts
+}
+
+func TestWeightedQuoteProviderDistribution(t *testing.T) {
+	tests := []struct {
+		name   string
+		quotes []WeightedQuote
+	}{
+		{name: "single", quotes: []WeightedQuote{{Quote: "a", Weight: 3}}},
+		{name: "equal", quotes: []WeightedQuote{{Quote: "a", Weight: 1}, {Quote: "b", Weight: 1}}},
+		{name: "skewed", quotes: []WeightedQuote{
+			{Quote: "a", Weight: 1},
+			{Quote: "b", Weight: 2},
+			{Quote: "c", Weight: 7},
+		}},
+		{name: "fractional", quotes: []WeightedQuote{
+			{Quote: "a", Weight: 0.05},
+			{Quote: "b", Weight: 0.15},
+			{Quote: "c", Weight: 0.3},
+			{Quote: "d", Weight: 0.5},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewWeightedQuoteProvider(tt.quotes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			total := 0.0
+			for _, q := range tt.quotes {
+				total += q.Weight
+			}
+			got := frequencies(p.Quote)
+			for _, q := range tt.quotes {
+				want := q.Weight / total
+				if math.Abs(got[q.Quote]-want) > 0.01 {
+					t.Errorf("quote %q drawn %.4f of the time, want %.4f", q.Quote, got[q.Quote], want)
+				}
+			}
+			if len(got) != len(tt.quotes) {
+				t.Errorf("drew %d distinct quotes, want %d", len(got), len(tt.quotes))
+			}
+		})
+	}
+}
+
+func TestSliceQuoteProviderDistribution(t *testing.T) {
+	quotes := []string{"a", "b", "c", "d"}
+	p, err := NewSliceQuoteProvider(quotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := frequencies(p.Quote)
+	for _, q := range quotes {
+		if math.Abs(got[q]-0.25) > 0.01 {
+			t.Errorf("quote %q drawn %.4f of the time, want 0.25", q, got[q])
+		}
+	}
+}
+
+func TestNewQuoteProvidersRejectBadInput(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() error
+		err  error
+	}{
+		{name: "no slice quotes", new: func() error { _, err := NewSliceQuoteProvider(nil); return err }, err: ErrNoQuotes},
+		{name: "no weighted quotes", new: func() error { _, err := NewWeightedQuoteProvider(nil); return err }, err: ErrNoQuotes},
+		{name: "zero weight", new: func() error {
+			_, err := NewWeightedQuoteProvider([]WeightedQuote{{Quote: "a", Weight: 1}, {Quote: "b"}})
+			return err
+		}, err: ErrInvalidWeight},
+		{name: "negative weight", new: func() error {
+			_, err := NewWeightedQuoteProvider([]WeightedQuote{{Quote: "a", Weight: -1}})
+			return err
+		}, err: ErrInvalidWeight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.new(); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestServerServesRequestedCategory(t *testing.T) {
+	categories, err := NewCategorizedQuoteProvider(map[string][]string{
+		"stoic": {"stoic quote"},
+		"tech":  {"tech quote"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		categories CategoryQuoteProvider
+		category   string
+		want       string
+		code       uint32
+	}{
+		{name: "known category", categories: categories, category: "stoic", want: "stoic quote"},
+		{name: "another category", categories: categories, category: "tech", want: "tech quote"},
+		{name: "no category", categories: categories, want: testQuote},
+		{name: "unknown category", categories: categories, category: "humor", code: protocol.ERR_CODE_UNKNOWN_CATEGORY},
+		{name: "no categories configured", category: "stoic", code: protocol.ERR_CODE_UNKNOWN_CATEGORY},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{Categories: tt.categories})
+			conn := pipe(t, srv)
+
+			solution, err := protocol.SolveChallenge(readChallenge(t, conn), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			solution.Category = tt.category
+			sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, solution)
+
+			if tt.code != 0 {
+				expectError(t, conn, tt.code)
+				return
+			}
+			msg := readFrame(t, conn)
+			wisdom := responses.WisdomResponse{}
+			if err := msg.DecodePayload(&wisdom); err != nil {
+				t.Fatalf("opcode %d: %v", msg.Opcode, err)
+			}
+			if wisdom.Quote != tt.want {
+				t.Fatalf("got quote %q, want %q", wisdom.Quote, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+type RateLimiter interface {
+	Allow(addr net.Addr) bool
+}
+
+// FailureRecorder is implemented by rate limiters and reputation stores
+// that also account for rejected solutions. The server reports every failed
+// verification to a RateLimiter or ReputationStore implementing it.
+type FailureRecorder interface {
+	RecordFailure(addr net.Addr)
+}
+
+// TokenBucketLimiter allows each remote IP a burst of requests refilled at
+// a steady rate. Buckets idle for longer than idleTimeout are pruned.
+type TokenBucketLimiter struct {
+	rate        float64
+	burst       float64
+	idleTimeout time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// DefaultBucketIdleTimeout is used when NewTokenBucketLimiter is given no
+// positive idle timeout.
+const DefaultBucketIdleTimeout = 10 * time.Minute
+
+// NewTokenBucketLimiter allows ratePerSecond requests per IP on average and
+// up to burst at once. The idle timeout should be at least the time an
+// empty bucket takes to refill, or pruning hands out allowance early.
+func NewTokenBucketLimiter(ratePerSecond float64, burst int, idleTimeout time.Duration) *TokenBucketLimiter {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultBucketIdleTimeout
+	}
+
+	return &TokenBucketLimiter{
+		rate:        ratePerSecond,
+		burst:       float64(burst),
+		idleTimeout: idleTimeout,
+		buckets:     make(map[string]*tokenBucket),
+		lastPrune:   time.Now(),
+		now:         time.Now,
+	}
+}
+
+func (l *TokenBucketLimiter) Allow(addr net.Addr) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	ip := addrIP(addr)
+	if now.Sub(l.lastPrune) >= l.idleTimeout {
+		l.prune(now, ip)
+	}
+
+	bucket, ok := l.buckets[ip]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[ip] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = min(l.burst, bucket.tokens+elapsed*l.rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// RecordFailure spends a token of the IP's bucket, so each failed solution
+// costs as much allowance as a new connection.
+func (l *TokenBucketLimiter) RecordFailure(addr net.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[addrIP(addr)]
+	if !ok {
+		return
+	}
+	bucket.tokens = max(bucket.tokens-1, 0)
+}
+
+// prune drops idle buckets other than keep, the one about to be used.
+func (l *TokenBucketLimiter) prune(now time.Time, keep string) {
+	for ip, bucket := range l.buckets {
+		if ip != keep && now.Sub(bucket.lastSeen) >= l.idleTimeout {
+			delete(l.buckets, ip)
+		}
+	}
+	l.lastPrune = now
+}
+
+func addrIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+var testAddr = &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+
+// fakeNow returns a clock for the limiter and a function advancing it.
+func fakeNow(l *TokenBucketLimiter) func(time.Duration) {
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	l.lastPrune = now
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTokenBucketLimiterRefusesOverBurst(t *testing.T) {
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+	}{
+		{name: "idle timeout", idleTimeout: time.Minute},
+		{name: "zero idle timeout", idleTimeout: 0},
+		{name: "negative idle timeout", idleTimeout: -time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewTokenBucketLimiter(1, 3, tt.idleTimeout)
+			fakeNow(l)
+
+			for i := range 3 {
+				if !l.Allow(testAddr) {
+					t.Fatalf("request %d refused within burst", i)
+				}
+			}
+			if l.Allow(testAddr) {
+				t.Fatal("request over burst allowed")
+			}
+		})
+	}
+}
+
+func TestTokenBucketLimiterPruneKeepsCurrentBucket(t *testing.T) {
+	// An idle timeout shorter than the refill time: pruning the bucket in
+	// use would hand out a full burst again.
+	l := NewTokenBucketLimiter(1, 5, time.Second)
+	advance := fakeNow(l)
+
+	for range 5 {
+		l.Allow(testAddr)
+	}
+	advance(time.Second)
+
+	if !l.Allow(testAddr) {
+		t.Fatal("refilled token refused")
+	}
+	if l.Allow(testAddr) {
+		t.Fatal("bucket was reset by pruning")
+	}
+}
+
+func TestTokenBucketLimiterPrunesOtherIdleBuckets(t *testing.T) {
+	l := NewTokenBucketLimiter(1, 1, time.Second)
+	advance := fakeNow(l)
+
+	other := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 1}
+	l.Allow(other)
+	advance(time.Second)
+	l.Allow(testAddr)
+
+	if _, ok := l.buckets[addrIP(other)]; ok {
+		t.Fatal("idle bucket not pruned")
+	}
+}
+
+func TestServerRefusesFloodWithErrRateLimited(t *testing.T) {
+	const burst = 3
+	srv := newTestServer(t, Config{RateLimiter: NewTokenBucketLimiter(0.001, burst, time.Minute)})
+
+	for range burst {
+		conn := pipe(t, srv)
+		readChallenge(t, conn)
+		conn.Close()
+	}
+
+	conn := pipe(t, srv)
+	msg := readFrame(t, conn)
+	code, message := msg.ErrorPayload()
+	if !msg.IsError() || code != protocol.ERR_CODE_RATE_LIMITED {
+		t.Fatalf("got opcode %d, want ERR_CODE_RATE_LIMITED", msg.Opcode)
+	}
+	if message != ErrRateLimited.Error() {
+		t.Fatalf("message = %q, want %q", message, ErrRateLimited.Error())
+	}
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+// repeatReader yields the same bytes forever, so every challenge gets the
+// same nonce.
+type repeatReader struct {
+	b byte
+}
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	copy(p, bytes.Repeat([]byte{r.b}, len(p)))
+	return len(p), nil
+}
+
+func TestServerRejectsReplayedSolution(t *testing.T) {
+	srv := newTestServer(t, Config{NonceSource: repeatReader{b: 7}})
+
+	first := pipe(t, srv)
+	challenge := readChallenge(t, first)
+	solution := submitSolution(t, first, challenge)
+	if msg := readFrame(t, first); msg.Opcode != responses.RES_CODE_WISDOM {
+		t.Fatalf("first submission got opcode %d, want wisdom", msg.Opcode)
+	}
+
+	second := pipe(t, srv)
+	if replayed := readChallenge(t, second); replayed.Nonce != challenge.Nonce {
+		t.Fatal("nonce source did not repeat")
+	}
+	sendFrame(t, second, requests.OPCODE_SUBMIT_SOLUTION, solution)
+	expectError(t, second, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net"
+	"sync"
+	"wordofwisdom/pkg/protocol"
+)
+
+// ReputationStore tunes challenge difficulty per client: challenges issued
+// to addr get Config.Difficulty plus Penalty(addr) leading zero bits. A
+// negative penalty eases them for trusted clients. A store that also
+// implements FailureRecorder is told about every rejected solution.
+type ReputationStore interface {
+	Penalty(addr net.Addr) int
+}
+
+// IPReputationStore keeps a penalty per remote IP. Each rejected solution
+// raises it by failurePenalty, up to maxPenalty; SetPenalty flags or trusts
+// an IP by hand. Penalties do not decay.
+type IPReputationStore struct {
+	failurePenalty int
+	maxPenalty     int
+
+	mu        sync.Mutex
+	penalties map[string]int
+}
+
+func NewIPReputationStore(failurePenalty, maxPenalty int) *IPReputationStore {
+	return &IPReputationStore{
+		failurePenalty: failurePenalty,
+		maxPenalty:     maxPenalty,
+		penalties:      make(map[string]int),
+	}
+}
+
+func (r *IPReputationStore) Penalty(addr net.Addr) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.penalties[addrIP(addr)]
+}
+
+// SetPenalty overrides the penalty of ip. Zero forgets it.
+func (r *IPReputationStore) SetPenalty(ip string, penalty int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if penalty == 0 {
+		delete(r.penalties, ip)
+		return
+	}
+	r.penalties[ip] = penalty
+}
+
+func (r *IPReputationStore) RecordFailure(addr net.Addr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ip := addrIP(addr)
+	penalty := r.penalties[ip]
+	if penalty >= r.maxPenalty {
+		return
+	}
+	r.penalties[ip] = min(penalty+r.failurePenalty, r.maxPenalty)
+}
+
+// difficulty is the challenge difficulty for the client on conn, kept
+// between one bit and protocol.MaxDifficulty whatever the penalty.
+func (s *Server) difficulty(conn net.Conn) int {
+	if s.cfg.Reputation == nil {
+		return s.cfg.Difficulty
+	}
+
+	difficulty := s.cfg.Difficulty + s.cfg.Reputation.Penalty(conn.RemoteAddr())
+	return max(1, min(difficulty, protocol.MaxDifficulty))
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+// addrConn reports a chosen remote address, so pipe connections can stand
+// in for different client IPs.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// pipeFrom is pipe with the server seeing the client at ip.
+func pipeFrom(t *testing.T, srv *Server, ip net.IP) net.Conn {
+	t.Helper()
+
+	client, conn := net.Pipe()
+	served := addrConn{Conn: conn, remote: &net.TCPAddr{IP: ip, Port: 1234}}
+	if !srv.trackConnection(served) {
+		t.Fatal("server refused the connection")
+	}
+	go srv.handleConnection(served)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestServerDifficultyFollowsReputation(t *testing.T) {
+	const base = 8
+	flagged := net.IPv4(192, 0, 2, 66)
+	fresh := net.IPv4(192, 0, 2, 1)
+
+	tests := []struct {
+		name    string
+		penalty int
+		want    int
+	}{
+		{name: "flagged", penalty: 6, want: base + 6},
+		{name: "trusted", penalty: -3, want: base - 3},
+		{name: "floor of one bit", penalty: -100, want: 1},
+		{name: "capped at max difficulty", penalty: 1000, want: protocol.MaxDifficulty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewIPReputationStore(1, 10)
+			store.SetPenalty(flagged.String(), tt.penalty)
+			srv := newTestServer(t, Config{Difficulty: base, Reputation: store})
+
+			if got := readChallenge(t, pipeFrom(t, srv, flagged)).Difficulty; got != tt.want {
+				t.Fatalf("flagged client got difficulty %d, want %d", got, tt.want)
+			}
+			if got := readChallenge(t, pipeFrom(t, srv, fresh)).Difficulty; got != base {
+				t.Fatalf("fresh client got difficulty %d, want %d", got, base)
+			}
+		})
+	}
+}
+
+func TestServerRaisesDifficultyAfterFailure(t *testing.T) {
+	const (
+		base           = 4
+		failurePenalty = 2
+		maxPenalty     = 3
+	)
+	client := net.IPv4(192, 0, 2, 7)
+	srv := newTestServer(t, Config{Difficulty: base, Reputation: NewIPReputationStore(failurePenalty, maxPenalty)})
+
+	for _, want := range []int{base, base + failurePenalty, base + maxPenalty, base + maxPenalty} {
+		conn := pipeFrom(t, srv, client)
+		challenge := readChallenge(t, conn)
+		if challenge.Difficulty != want {
+			t.Fatalf("got difficulty %d, want %d", challenge.Difficulty, want)
+		}
+		sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, badCounter(t, challenge))
+		expectError(t, conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF)
+	}
+}
@@ -0,0 +1,839 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"wordofwisdom/internal/pow"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+var (
+	ErrServerClosed         = errors.New("server closed")
+	ErrInvalidOpcode        = errors.New("invalid opcode")
+	ErrInvalidProof         = errors.New("invalid challenge proof")
+	ErrAlreadyServing       = errors.New("server is already serving")
+	ErrUnsupportedAlgorithm = errors.New("unsupported pow algorithm")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrHandshakeTimeout     = errors.New("handshake timeout")
+	ErrSessionsDisabled     = errors.New("sessions are disabled")
+	ErrServerBusy           = errors.New("server busy")
+	ErrBatchesDisabled      = errors.New("batch requests are disabled")
+
+	// errHealthChecked ends a connection whose first message was a health
+	// check instead of an answer to the hello or challenge.
+	errHealthChecked = errors.New("health check answered")
+)
+
+type QuoteProvider interface {
+	Quote() string
+}
+
+// DefaultChallengeTTL is how long challenges stay valid when
+// Config.ChallengeTTL is not set.
+const DefaultChallengeTTL = time.Minute
+
+type Config struct {
+	Difficulty int
+	// ChallengeTTL is how long a client has to solve a challenge; defaults
+	// to DefaultChallengeTTL.
+	ChallengeTTL time.Duration
+	// ChallengeSecret signs every issued challenge with an HMAC tag, and
+	// solutions are checked with protocol.VerifySignedSolution, so a
+	// challenge the server did not issue is rejected. nil leaves challenges
+	// unsigned.
+	ChallengeSecret []byte
+	// ChallengeKey makes challenges stateless: each is sent as a
+	// protocol.ChallengeToken signed under the key, which the client returns
+	// in its solution, and solutions are verified with
+	// protocol.VerifyTokenSolution against ChallengeKeys and ChallengeKey
+	// instead of the challenge the connection issued. Any server sharing the
+	// keys accepts the solution; replays are still only caught per server.
+	// It takes precedence over ChallengeSecret and does not apply to batch
+	// challenges. nil issues plain challenges.
+	ChallengeKey *protocol.ChallengeKey
+	// ChallengeKeys holds retired keys whose tokens are still accepted.
+	ChallengeKeys       protocol.Keyring
+	MaxMessageSizeBytes int
+	// Algorithms enables the hello negotiation step: the server advertises
+	// them and the client picks one before the challenge is issued. When
+	// empty no hello is sent and challenges use AlgoSHA256.
+	Algorithms []protocol.Algorithm
+	// Argon2 parameters for AlgoArgon2id challenges; defaults to
+	// protocol.DefaultArgon2Params.
+	Argon2 *protocol.Argon2Params
+	// RateLimiter is consulted before a challenge is issued; nil disables it.
+	RateLimiter RateLimiter
+	// Logger receives server logs; nothing is logged when nil.
+	Logger *slog.Logger
+	// SessionTTL enables session tokens: after a solved challenge the client
+	// gets a token it can redeem for further quotes on the connection
+	// without solving again, until the token expires. Zero disables them.
+	SessionTTL time.Duration
+	// SessionSecret signs session tokens; a random one is generated when
+	// empty.
+	SessionSecret []byte
+	// Metrics receives handshake and connection events; nil disables them.
+	Metrics Metrics
+	// HandshakeTimeout bounds how long the server waits for the hello ack
+	// and for each solution; the connection is closed when it runs out.
+	// Zero waits forever.
+	HandshakeTimeout time.Duration
+	// IdleTimeout bounds how long the server waits for the next request on
+	// a connection that was already served, then closes it. Defaults to
+	// HandshakeTimeout; when both are zero it waits forever.
+	IdleTimeout time.Duration
+	// MaxConcurrentConnections bounds how many connections are served at
+	// once; zero means no limit. Connections over the limit wait up to
+	// ConnectionQueueTimeout for a slot, then get ERR_CODE_SERVER_BUSY and
+	// are closed. With no queue timeout they are refused right away.
+	MaxConcurrentConnections int
+	ConnectionQueueTimeout   time.Duration
+	// Categories serves quotes for requests naming a category; requests
+	// without one get a quote from the server's QuoteProvider. Without
+	// Categories every named category is unknown.
+	Categories CategoryQuoteProvider
+	// MaxBatchSize enables batch requests of up to that many quotes, capped
+	// at protocol.MaxBatchSize. A batch is authorized by one challenge of
+	// protocol.BatchDifficulty. Zero disables batches.
+	MaxBatchSize int
+	// VerifyWorkers moves solution verification to a VerifierPool of that
+	// many workers, with up to VerifyQueueSize solutions waiting. Solutions
+	// arriving while it is saturated get ERR_CODE_SERVER_BUSY. Zero
+	// verifies on the connection's goroutine.
+	VerifyWorkers   int
+	VerifyQueueSize int
+	// ChallengeRefreshInterval makes the server answer a session request
+	// with a fresh challenge once that long has passed since the connection
+	// last solved one, so a stolen session token cannot be used on it
+	// indefinitely. Zero never refreshes.
+	ChallengeRefreshInterval time.Duration
+	// NonceSource provides challenge nonces; defaults to crypto/rand.Reader.
+	// Only tests should set it, to get reproducible challenges. It is read
+	// from concurrent connections.
+	NonceSource io.Reader
+	// Reputation adds a per-client penalty to Difficulty for the hello and
+	// every challenge; nil gives all clients the same difficulty.
+	Reputation ReputationStore
+	// ConnContext derives the context of each accepted connection from ctx,
+	// e.g. to attach request-scoped values. It must return a context derived
+	// from ctx. nil uses ctx as is.
+	ConnContext func(ctx context.Context, conn net.Conn) context.Context
+}
+
+// Server serves word of wisdom quotes to clients that solve a hashcash
+// challenge first.
+type Server struct {
+	cfg           Config
+	quotes        QuoteProvider
+	logger        *slog.Logger
+	metrics       Metrics
+	sessionSecret []byte
+	// challengeKeys verifies challenge tokens, ChallengeKey included.
+	challengeKeys protocol.Keyring
+	verifier      *VerifierPool
+	stopVerifier  context.CancelFunc
+	// nonces remembers accepted challenges for ChallengeTTL to reject
+	// replayed solutions.
+	nonces *pow.NonceCache
+	// connBase is the parent of every connection context. Like the
+	// verifier it outlives the server context, so that Shutdown does not
+	// abort in-flight handshakes.
+	connBase  context.Context
+	stopConns context.CancelFunc
+	// slots holds one token per served connection when
+	// MaxConcurrentConnections is set.
+	slots chan struct{}
+	// now tells when a connection last solved a challenge, for
+	// ChallengeRefreshInterval.
+	now func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	listener net.Listener
+	// connections maps every active connection to whether it is idle,
+	// i.e. waiting for the client's next request.
+	connections map[net.Conn]bool
+	draining    bool
+	wg          sync.WaitGroup
+}
+
+func NewServer(ctx context.Context, cfg Config, quotes QuoteProvider) *Server {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	if cfg.ChallengeTTL <= 0 {
+		cfg.ChallengeTTL = DefaultChallengeTTL
+	}
+
+	var metrics Metrics = noopMetrics{}
+	if cfg.Metrics != nil {
+		metrics = cfg.Metrics
+	}
+
+	sessionSecret := cfg.SessionSecret
+	if cfg.SessionTTL > 0 && len(sessionSecret) == 0 {
+		sessionSecret = make([]byte, 32)
+		rand.Read(sessionSecret)
+	}
+
+	var challengeKeys protocol.Keyring
+	if cfg.ChallengeKey != nil {
+		challengeKeys = maps.Clone(cfg.ChallengeKeys)
+		if challengeKeys == nil {
+			challengeKeys = protocol.Keyring{}
+		}
+		challengeKeys[cfg.ChallengeKey.ID] = cfg.ChallengeKey.Secret
+	}
+
+	var slots chan struct{}
+	if cfg.MaxConcurrentConnections > 0 {
+		slots = make(chan struct{}, cfg.MaxConcurrentConnections)
+	}
+
+	// The verifier outlives the server context so that Shutdown can let
+	// in-flight handshakes finish.
+	var verifier *VerifierPool
+	stopVerifier := func() {}
+	if cfg.VerifyWorkers > 0 {
+		var verifierCtx context.Context
+		verifierCtx, stopVerifier = context.WithCancel(ctx)
+		verifier = NewVerifierPool(verifierCtx, cfg.VerifyWorkers, cfg.VerifyQueueSize)
+	}
+
+	connBase, stopConns := context.WithCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Server{
+		cfg:           cfg,
+		quotes:        quotes,
+		logger:        logger,
+		metrics:       metrics,
+		sessionSecret: sessionSecret,
+		challengeKeys: challengeKeys,
+		verifier:      verifier,
+		stopVerifier:  stopVerifier,
+		nonces:        pow.NewNonceCache(cfg.ChallengeTTL),
+		connBase:      connBase,
+		stopConns:     stopConns,
+		slots:         slots,
+		now:           time.Now,
+		ctx:           ctx,
+		cancel:        cancel,
+		connections:   make(map[net.Conn]bool),
+	}
+}
+
+// Serve accepts connections on ln until Close is called or the server
+// context is cancelled, in which case it returns ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.listener != nil {
+		s.mu.Unlock()
+		return ErrAlreadyServing
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	stop := context.AfterFunc(s.ctx, func() {
+		ln.Close()
+	})
+	defer stop()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		if !s.trackConnection(conn) {
+			conn.Close()
+			continue
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+// Close stops accepting connections and closes every active one.
+func (s *Server) Close() error {
+	s.cancel()
+	s.stopVerifier()
+	s.stopConns()
+	s.nonces.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.connections {
+		conn.Close()
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting connections, closes idle ones and waits for
+// in-flight handshakes to finish. If ctx is done first the remaining
+// connections are closed and ctx.Err() is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	for conn, idle := range s.connections {
+		if idle {
+			conn.Close()
+		}
+	}
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.stopVerifier()
+		s.stopConns()
+		s.nonces.Close()
+		return nil
+	case <-ctx.Done():
+		s.Close()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// trackConnection registers a new connection unless the server is
+// draining.
+func (s *Server) trackConnection(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return false
+	}
+
+	s.connections[conn] = false
+	s.wg.Add(1)
+	return true
+}
+
+// setIdle marks the connection idle or busy. It returns false if the server
+// is draining, in which case an idle connection should be closed.
+func (s *Server) setIdle(conn net.Conn, idle bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connections[conn] = idle
+	return !s.draining
+}
+
+func (s *Server) untrackConnection(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.connections, conn)
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.untrackConnection(conn)
+		s.wg.Done()
+	}()
+
+	remoteAddr := conn.RemoteAddr().String()
+	if !s.acquireSlot() {
+		writeError(conn, protocol.ERR_CODE_SERVER_BUSY, ErrServerBusy)
+		s.metrics.ConnectionRejected()
+		s.logger.Info("Connection refused",
+			slog.String("remote_addr", remoteAddr),
+			slog.Any("error", ErrServerBusy),
+		)
+		return
+	}
+	defer s.releaseSlot()
+
+	s.metrics.ConnectionOpened()
+	defer s.metrics.ConnectionClosed()
+
+	ctx, cancel := s.connContext(conn)
+	defer cancel()
+
+	if s.cfg.RateLimiter != nil && !s.cfg.RateLimiter.Allow(conn.RemoteAddr()) {
+		writeError(conn, protocol.ERR_CODE_RATE_LIMITED, ErrRateLimited)
+		s.logger.Info("Connection refused",
+			slog.String("remote_addr", remoteAddr),
+			slog.Any("error", ErrRateLimited),
+		)
+		return
+	}
+
+	algorithm, err := s.negotiate(conn)
+	if errors.Is(err, errHealthChecked) {
+		s.logger.Debug("Health check answered", slog.String("remote_addr", remoteAddr))
+		return
+	}
+	if err != nil {
+		s.logger.Info("Negotiation failed",
+			slog.String("remote_addr", remoteAddr),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	if err := s.handshake(ctx, conn, algorithm); errors.Is(err, errHealthChecked) {
+		s.logger.Debug("Health check answered", slog.String("remote_addr", remoteAddr))
+		return
+	} else if err != nil {
+		s.logger.Info("Handshake failed",
+			slog.String("remote_addr", remoteAddr),
+			slog.Any("error", err),
+		)
+		return
+	}
+	s.logger.Debug("Quote served", slog.String("remote_addr", remoteAddr))
+	solvedAt := s.now()
+
+	// Clients may ask for further quotes on the same connection, each behind
+	// a fresh challenge or a session token.
+	for {
+		if !s.setIdle(conn, true) {
+			return
+		}
+		msg, err := s.readNextRequest(conn)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			s.logger.Debug("Connection idle, closing", slog.String("remote_addr", remoteAddr))
+			return
+		}
+		if err != nil {
+			return
+		}
+		s.setIdle(conn, false)
+
+		switch msg.Opcode {
+		case requests.OPCODE_REQUEST_WISDOM:
+			err = s.handshake(ctx, conn, algorithm)
+			solvedAt = s.now()
+		case requests.OPCODE_REQUEST_SESSION_WISDOM:
+			if s.cfg.ChallengeRefreshInterval > 0 && s.now().Sub(solvedAt) >= s.cfg.ChallengeRefreshInterval {
+				err = s.handshake(ctx, conn, algorithm)
+				solvedAt = s.now()
+			} else {
+				err = s.redeemSession(ctx, conn, msg)
+			}
+		case requests.OPCODE_REQUEST_WISDOM_BATCH:
+			err = s.batch(ctx, conn, algorithm, msg)
+			solvedAt = s.now()
+		case protocol.OpcodeHealthCheck:
+			err = writeMessage(conn, true, protocol.OpcodeHealthCheck, nil)
+		default:
+			writeError(conn, protocol.ERR_CODE_INVALID_OPCODE, ErrInvalidOpcode)
+			err = ErrInvalidOpcode
+		}
+
+		if err != nil {
+			s.logger.Info("Request failed",
+				slog.String("remote_addr", remoteAddr),
+				slog.Any("error", err),
+			)
+			return
+		}
+		s.logger.Debug("Request served", slog.String("remote_addr", remoteAddr))
+	}
+}
+
+// connContext derives the context of conn from connBase. It is cancelled
+// when the connection ends or the server is closed, and cancelling it closes
+// the connection, so handlers blocked on it return.
+func (s *Server) connContext(conn net.Conn) (context.Context, context.CancelFunc) {
+	ctx := s.connBase
+	if s.cfg.ConnContext != nil {
+		ctx = s.cfg.ConnContext(ctx, conn)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	return ctx, cancel
+}
+
+// acquireSlot waits for room under MaxConcurrentConnections, up to
+// ConnectionQueueTimeout.
+func (s *Server) acquireSlot() bool {
+	if s.slots == nil {
+		return true
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if s.cfg.ConnectionQueueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(s.cfg.ConnectionQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) releaseSlot() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// redeemSession replies with a quote if the request carries a valid session
+// token. A rejected token is reported to the client, which may fall back to
+// a challenge, and does not end the connection.
+func (s *Server) redeemSession(ctx context.Context, conn net.Conn, msg *protocol.RawMessage) error {
+	if s.cfg.SessionTTL <= 0 {
+		return writeError(conn, protocol.ERR_CODE_INVALID_SESSION, ErrSessionsDisabled)
+	}
+
+	req := requests.SessionWisdomRequest{}
+	if err := msg.DecodePayload(&req); err != nil {
+		return writeError(conn, protocol.ERR_CODE_INVALID_SESSION, protocol.ErrInvalidSessionToken)
+	}
+	if err := req.Token.Verify(s.sessionSecret, time.Now()); err != nil {
+		return writeError(conn, protocol.ERR_CODE_INVALID_SESSION, err)
+	}
+
+	quote, err := s.quote(ctx, req.Category)
+	if err != nil {
+		return writeError(conn, protocol.ERR_CODE_UNKNOWN_CATEGORY, err)
+	}
+
+	return writeMessage(conn, true, responses.RES_CODE_WISDOM, &responses.WisdomResponse{Quote: quote})
+}
+
+// quote picks a quote from the requested category, or from the default
+// provider when no category is given.
+func (s *Server) quote(ctx context.Context, category string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if category == "" {
+		if provider, ok := s.quotes.(ContextQuoteProvider); ok {
+			return provider.QuoteContext(ctx)
+		}
+		return s.quotes.Quote(), nil
+	}
+	if s.cfg.Categories == nil {
+		return "", ErrUnknownCategory
+	}
+
+	return s.cfg.Categories.Quote(category)
+}
+
+// handshake sends a challenge, waits for the solution and replies with a
+// quote if the solution is valid. The client may answer the challenge with
+// a batch request instead, trading it for a batch challenge.
+func (s *Server) handshake(ctx context.Context, conn net.Conn, algorithm protocol.Algorithm) error {
+	started := time.Now()
+	challenge, err := s.newChallenge(algorithm, s.difficulty(conn))
+	if err != nil {
+		return err
+	}
+
+	stateless := s.cfg.ChallengeKey != nil
+	if stateless {
+		token := protocol.ChallengeToken{KeyID: s.cfg.ChallengeKey.ID, Challenge: challenge}
+		token.Sign(s.cfg.ChallengeKey.Secret)
+		err = writeMessage(conn, true, responses.RES_CODE_CHALLENGE_TOKEN, token)
+	} else {
+		err = writeMessage(conn, true, responses.RES_CODE_POW_CHALLENGE, challenge)
+	}
+	if err != nil {
+		return err
+	}
+	s.metrics.ChallengeIssued()
+
+	msg, err := s.readHandshakeMessage(conn)
+	if err != nil {
+		return err
+	}
+	if msg.Opcode == requests.OPCODE_REQUEST_WISDOM_BATCH {
+		return s.batch(ctx, conn, algorithm, msg)
+	}
+
+	solution, err := s.verifyProof(ctx, conn, challenge, msg, started, stateless)
+	if err != nil {
+		return err
+	}
+
+	// An unknown category is the client's mistake, not an attack; it can ask
+	// again on the same connection.
+	quote, err := s.quote(ctx, solution.Category)
+	if err != nil {
+		return writeError(conn, protocol.ERR_CODE_UNKNOWN_CATEGORY, err)
+	}
+
+	if s.cfg.SessionTTL > 0 {
+		token, err := protocol.NewSessionToken(s.sessionSecret, s.cfg.SessionTTL)
+		if err != nil {
+			return err
+		}
+		if err := writeMessage(conn, true, responses.RES_CODE_SESSION, token); err != nil {
+			return err
+		}
+	}
+
+	if err := writeMessage(conn, true, responses.RES_CODE_WISDOM, &responses.WisdomResponse{Quote: quote}); err != nil {
+		return err
+	}
+	s.metrics.HandshakeDuration(time.Since(started))
+
+	return nil
+}
+
+// batch serves a batch request: one challenge of BatchDifficulty, then all
+// the quotes at once. An invalid request is reported and does not end the
+// connection.
+func (s *Server) batch(ctx context.Context, conn net.Conn, algorithm protocol.Algorithm, msg *protocol.RawMessage) error {
+	started := time.Now()
+	maxSize := min(s.cfg.MaxBatchSize, protocol.MaxBatchSize)
+	if maxSize <= 0 {
+		return writeError(conn, protocol.ERR_CODE_INVALID_BATCH, ErrBatchesDisabled)
+	}
+
+	req := requests.BatchWisdomRequest{}
+	if err := msg.DecodePayload(&req); err != nil {
+		return writeError(conn, protocol.ERR_CODE_INVALID_BATCH, err)
+	}
+	if req.Count < 1 || req.Count > maxSize {
+		return writeError(conn, protocol.ERR_CODE_INVALID_BATCH, protocol.ErrInvalidBatchSize)
+	}
+
+	challenge, err := s.newChallenge(algorithm, protocol.BatchDifficulty(s.difficulty(conn), req.Count))
+	if err != nil {
+		return err
+	}
+
+	batch := protocol.BatchChallenge{Challenge: challenge, Count: req.Count}
+	if err := writeMessage(conn, true, responses.RES_CODE_BATCH_CHALLENGE, batch); err != nil {
+		return err
+	}
+	s.metrics.ChallengeIssued()
+
+	if msg, err = s.readHandshakeMessage(conn); err != nil {
+		return err
+	}
+	if _, err := s.verifyProof(ctx, conn, challenge, msg, started, false); err != nil {
+		return err
+	}
+
+	quotes := make([]string, req.Count)
+	for i := range quotes {
+		if quotes[i], err = s.quote(ctx, req.Category); err != nil {
+			return writeError(conn, protocol.ERR_CODE_UNKNOWN_CATEGORY, err)
+		}
+	}
+
+	if err := writeMessage(conn, true, responses.RES_CODE_WISDOM_BATCH, &responses.WisdomBatchResponse{Quotes: quotes}); err != nil {
+		return err
+	}
+	s.metrics.HandshakeDuration(time.Since(started))
+
+	return nil
+}
+
+func (s *Server) newChallenge(algorithm protocol.Algorithm, difficulty int) (protocol.Challenge, error) {
+	source := s.cfg.NonceSource
+	if source == nil {
+		source = rand.Reader
+	}
+
+	challenge, err := protocol.NewChallengeFrom(source, difficulty, s.cfg.ChallengeTTL)
+	if err != nil {
+		return protocol.Challenge{}, err
+	}
+	challenge.Algorithm = algorithm
+	if algorithm == protocol.AlgoArgon2id {
+		challenge.Argon2 = protocol.DefaultArgon2Params
+		if s.cfg.Argon2 != nil {
+			challenge.Argon2 = *s.cfg.Argon2
+		}
+	}
+	if len(s.cfg.ChallengeSecret) > 0 {
+		challenge.Sign(s.cfg.ChallengeSecret)
+	}
+
+	return challenge, nil
+}
+
+// verifyProof checks that msg carries a valid solution to challenge, which
+// was issued at issued, reporting a failure to the client. A stateless
+// solution is checked against the challenge token it carries instead.
+func (s *Server) verifyProof(ctx context.Context, conn net.Conn, challenge protocol.Challenge, msg *protocol.RawMessage, issued time.Time, stateless bool) (protocol.Solution, error) {
+	if msg.Opcode != requests.OPCODE_SUBMIT_SOLUTION {
+		writeError(conn, protocol.ERR_CODE_INVALID_OPCODE, ErrInvalidOpcode)
+		return protocol.Solution{}, ErrInvalidOpcode
+	}
+
+	solution := protocol.Solution{}
+	if err := msg.DecodePayload(&solution); err != nil {
+		s.recordFailure(conn, challenge, msg, failureMalformed, issued)
+		writeError(conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF, ErrInvalidProof)
+		return protocol.Solution{}, err
+	}
+	if stateless {
+		// The token may come from another server; the challenge issued here
+		// does not matter any more.
+		token := protocol.ChallengeToken{}
+		if token.Decode(solution.Token) == nil {
+			challenge = token.Challenge
+		}
+	}
+
+	err := s.verifySolution(ctx, challenge, solution, stateless)
+	if errors.Is(err, ErrVerifierBusy) {
+		writeError(conn, protocol.ERR_CODE_SERVER_BUSY, err)
+		return protocol.Solution{}, err
+	}
+	if err != nil {
+		s.recordFailure(conn, challenge, msg, failureReason(challenge, solution, err), issued)
+		writeError(conn, protocol.ERR_CODE_INVALID_CHALLENGE_PROOF, err)
+		return protocol.Solution{}, errors.Join(err, ErrInvalidProof)
+	}
+	s.metrics.SolutionVerified()
+
+	return solution, nil
+}
+
+// verifySolution checks the solution and accepts each challenge nonce only
+// once, so a solution cannot be submitted again while it is unexpired.
+func (s *Server) verifySolution(ctx context.Context, challenge protocol.Challenge, solution protocol.Solution, stateless bool) error {
+	now := time.Now()
+	verify := func() error {
+		switch {
+		case stateless:
+			return protocol.VerifyTokenSolution(s.challengeKeys, solution, now)
+		case len(s.cfg.ChallengeSecret) > 0:
+			return protocol.VerifySignedSolution(challenge, solution, s.cfg.ChallengeSecret, now)
+		default:
+			return protocol.VerifySolution(challenge, solution, now)
+		}
+	}
+
+	var err error
+	if s.verifier == nil {
+		err = verify()
+	} else {
+		err = s.verifier.run(ctx, verify)
+	}
+	if err != nil {
+		return err
+	}
+	if s.nonces.Seen(challenge.Nonce) {
+		return pow.ErrReplayedChallenge
+	}
+
+	return nil
+}
+
+// negotiate advertises the configured algorithms and returns the client's
+// choice. Without configured algorithms it is skipped.
+func (s *Server) negotiate(conn net.Conn) (protocol.Algorithm, error) {
+	if len(s.cfg.Algorithms) == 0 {
+		return protocol.AlgoSHA256, nil
+	}
+
+	hello := protocol.HelloMessage{
+		Algorithms:    s.cfg.Algorithms,
+		MinDifficulty: s.difficulty(conn),
+	}
+	if err := writeMessage(conn, true, responses.RES_CODE_HELLO, hello); err != nil {
+		return 0, err
+	}
+
+	msg, err := s.readHandshakeMessage(conn)
+	if err != nil {
+		return 0, err
+	}
+	if msg.Opcode != requests.OPCODE_HELLO_ACK {
+		writeError(conn, protocol.ERR_CODE_INVALID_OPCODE, ErrInvalidOpcode)
+		return 0, ErrInvalidOpcode
+	}
+
+	ack := protocol.HelloAck{}
+	if err := msg.DecodePayload(&ack); err != nil {
+		return 0, err
+	}
+	if !slices.Contains(s.cfg.Algorithms, ack.Algorithm) {
+		writeError(conn, protocol.ERR_CODE_UNSUPPORTED_ALGORITHM, ErrUnsupportedAlgorithm)
+		return 0, ErrUnsupportedAlgorithm
+	}
+
+	return ack.Algorithm, nil
+}
+
+// readNextRequest waits up to IdleTimeout for the next request of a served
+// client, so one that goes silent does not hold its slot forever.
+func (s *Server) readNextRequest(conn net.Conn) (*protocol.RawMessage, error) {
+	timeout := s.cfg.IdleTimeout
+	if timeout <= 0 {
+		timeout = s.cfg.HandshakeTimeout
+	}
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	return readMessage(conn, s.cfg.MaxMessageSizeBytes)
+}
+
+// readHandshakeMessage reads a handshake reply within HandshakeTimeout so a
+// client that stalls mid-handshake cannot hold the connection. A health
+// check is answered instead and ends the handshake with errHealthChecked.
+func (s *Server) readHandshakeMessage(conn net.Conn) (*protocol.RawMessage, error) {
+	if s.cfg.HandshakeTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.cfg.HandshakeTimeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	msg, err := readMessage(conn, s.cfg.MaxMessageSizeBytes)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil, errors.Join(err, ErrHandshakeTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.Opcode == protocol.OpcodeHealthCheck {
+		if err := writeMessage(conn, true, protocol.OpcodeHealthCheck, nil); err != nil {
+			return nil, err
+		}
+		return nil, errHealthChecked
+	}
+
+	return msg, nil
+}
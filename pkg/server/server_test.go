@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+const testQuote = "test quote"
+
+// newTestServer returns a server with cheap challenges; fields set in cfg
+// override the defaults.
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = 4
+	}
+	if cfg.MaxMessageSizeBytes == 0 {
+		cfg.MaxMessageSizeBytes = 1024
+	}
+
+	quotes, err := NewSliceQuoteProvider([]string{testQuote})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer(context.Background(), cfg, quotes)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// pipe serves one end of a net.Pipe and returns the other as the client.
+func pipe(t *testing.T, srv *Server) net.Conn {
+	t.Helper()
+
+	client, conn := net.Pipe()
+	if !srv.trackConnection(conn) {
+		t.Fatal("server refused the connection")
+	}
+	go srv.handleConnection(conn)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func readFrame(t *testing.T, conn net.Conn) *protocol.RawMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg, err := protocol.ReadMessage(conn, 1<<16)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func sendFrame(t *testing.T, conn net.Conn, opcode uint32, payload protocol.MessageEncoder) {
+	t.Helper()
+
+	frame, err := protocol.BuildRawMessage(true, opcode, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func readChallenge(t *testing.T, conn net.Conn) protocol.Challenge {
+	t.Helper()
+
+	msg := readFrame(t, conn)
+	challenge := protocol.Challenge{}
+	if err := msg.DecodePayload(&challenge); err != nil {
+		t.Fatalf("opcode %d: %v", msg.Opcode, err)
+	}
+	return challenge
+}
+
+func submitSolution(t *testing.T, conn net.Conn, challenge protocol.Challenge) protocol.Solution {
+	t.Helper()
+
+	solution, err := protocol.SolveChallenge(challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendFrame(t, conn, requests.OPCODE_SUBMIT_SOLUTION, solution)
+	return solution
+}
+
+// expectError reads a failure frame and checks its code.
+func expectError(t *testing.T, conn net.Conn, code uint32) {
+	t.Helper()
+
+	msg := readFrame(t, conn)
+	if msg.IsSuccess() || msg.Opcode != code {
+		t.Fatalf("got opcode %d (success %v), want error code %d", msg.Opcode, msg.IsSuccess(), code)
+	}
+}
+
+func TestServerChallengesFromNonceSource(t *testing.T) {
+	fixed := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name string
+		// want are the nonces of consecutive challenges.
+		want [][]byte
+	}{
+		{name: "one challenge", want: [][]byte{fixed[:protocol.NonceSize]}},
+		{name: "consecutive challenges", want: [][]byte{fixed[:protocol.NonceSize], fixed[protocol.NonceSize:]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{NonceSource: bytes.NewReader(fixed)})
+			for i, want := range tt.want {
+				challenge := readChallenge(t, pipe(t, srv))
+				if !bytes.Equal(challenge.Nonce[:], want) {
+					t.Fatalf("challenge %d has nonce %x, want %x", i, challenge.Nonce, want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerClosesWhenNonceSourceRunsDry(t *testing.T) {
+	srv := newTestServer(t, Config{NonceSource: bytes.NewReader(make([]byte, protocol.NonceSize-1))})
+	conn := pipe(t, srv)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("read = %v, want EOF instead of a challenge", err)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+func expectWisdom(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	msg := readFrame(t, conn)
+	if msg.Opcode != responses.RES_CODE_WISDOM {
+		t.Fatalf("got opcode %d (success %v), want wisdom", msg.Opcode, msg.IsSuccess())
+	}
+}
+
+func TestServerThreeFetchesOverOneConnection(t *testing.T) {
+	srv := newTestServer(t, Config{SessionTTL: time.Minute})
+	conn := pipe(t, srv)
+
+	submitSolution(t, conn, readChallenge(t, conn))
+	msg := readFrame(t, conn)
+	token := protocol.SessionToken{}
+	if msg.Opcode != responses.RES_CODE_SESSION {
+		t.Fatalf("got opcode %d, want session", msg.Opcode)
+	}
+	if err := msg.DecodePayload(&token); err != nil {
+		t.Fatal(err)
+	}
+	expectWisdom(t, conn)
+
+	tests := []struct {
+		name string
+		send func()
+	}{
+		{name: "session token", send: func() {
+			sendFrame(t, conn, requests.OPCODE_REQUEST_SESSION_WISDOM, requests.SessionWisdomRequest{Token: token})
+		}},
+		{name: "fresh challenge", send: func() {
+			sendFrame(t, conn, requests.OPCODE_REQUEST_WISDOM, nil)
+			submitSolution(t, conn, readChallenge(t, conn))
+			if msg := readFrame(t, conn); msg.Opcode != responses.RES_CODE_SESSION {
+				t.Fatalf("got opcode %d, want session", msg.Opcode)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.send()
+			expectWisdom(t, conn)
+		})
+	}
+}
+
+func TestServerClosesSilentServedClient(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "idle timeout", cfg: Config{IdleTimeout: 50 * time.Millisecond}},
+		{name: "handshake timeout fallback", cfg: Config{HandshakeTimeout: 50 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.cfg)
+			conn := pipe(t, srv)
+
+			submitSolution(t, conn, readChallenge(t, conn))
+			expectWisdom(t, conn)
+
+			start := time.Now()
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, err := conn.Read(make([]byte, 1))
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("read = %v, want EOF from the server closing", err)
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) || time.Since(start) > time.Second {
+				t.Fatalf("server kept the idle connection for %v", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestServerRechallengesAfterRefreshInterval(t *testing.T) {
+	srv := newTestServer(t, Config{SessionTTL: time.Hour, ChallengeRefreshInterval: time.Minute})
+	var now atomic.Int64
+	srv.now = func() time.Time { return time.Unix(0, now.Load()) }
+	conn := pipe(t, srv)
+
+	submitSolution(t, conn, readChallenge(t, conn))
+	msg := readFrame(t, conn)
+	token := protocol.SessionToken{}
+	if msg.Opcode != responses.RES_CODE_SESSION || msg.DecodePayload(&token) != nil {
+		t.Fatalf("got opcode %d, want session", msg.Opcode)
+	}
+	expectWisdom(t, conn)
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		// rechallenged is whether the session request must be answered
+		// with a fresh challenge.
+		rechallenged bool
+	}{
+		{name: "right after solving"},
+		{name: "just before the interval", advance: time.Minute - time.Nanosecond},
+		{name: "at the interval", advance: time.Nanosecond, rechallenged: true},
+		{name: "after re-solving", advance: time.Minute / 2},
+		{name: "an interval after re-solving", advance: time.Minute / 2, rechallenged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The server notes when the connection solved only after
+			// replying, so wait until it is back waiting for a request.
+			for !allIdle(srv) {
+				time.Sleep(time.Millisecond)
+			}
+			now.Add(int64(tt.advance))
+			sendFrame(t, conn, requests.OPCODE_REQUEST_SESSION_WISDOM, requests.SessionWisdomRequest{Token: token})
+
+			if tt.rechallenged {
+				submitSolution(t, conn, readChallenge(t, conn))
+				if msg := readFrame(t, conn); msg.Opcode != responses.RES_CODE_SESSION || msg.DecodePayload(&token) != nil {
+					t.Fatalf("got opcode %d, want a new session", msg.Opcode)
+				}
+			}
+			expectWisdom(t, conn)
+		})
+	}
+}
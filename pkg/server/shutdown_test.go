@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// expectClosed fails unless the server closes conn within a second.
+func expectClosed(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("read = %v, want EOF from the server closing", err)
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// client runs against the connection while Shutdown is blocked.
+		client func(t *testing.T, conn net.Conn)
+		want   error
+	}{
+		{
+			name:    "waits for an in-flight handshake",
+			timeout: 5 * time.Second,
+			client: func(t *testing.T, conn net.Conn) {
+				submitSolution(t, conn, readChallenge(t, conn))
+				expectWisdom(t, conn)
+				expectClosed(t, conn)
+			},
+		},
+		{
+			name:    "closes a stalled handshake when ctx expires",
+			timeout: 50 * time.Millisecond,
+			client: func(t *testing.T, conn net.Conn) {
+				readChallenge(t, conn)
+				expectClosed(t, conn)
+			},
+			want: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Config{})
+			conn := pipe(t, srv)
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- srv.Shutdown(ctx) }()
+
+			select {
+			case err := <-done:
+				t.Fatalf("Shutdown returned %v with a connection still open", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			tt.client(t, conn)
+			select {
+			case err := <-done:
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Shutdown = %v, want %v", err, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Shutdown did not return after the connection closed")
+			}
+		})
+	}
+}
+
+func TestServerShutdownClosesIdleConnections(t *testing.T) {
+	srv := newTestServer(t, Config{})
+	conn := pipe(t, srv)
+	submitSolution(t, conn, readChallenge(t, conn))
+	expectWisdom(t, conn)
+
+	// The server marks the connection idle once it waits for the next
+	// request.
+	deadline := time.Now().Add(time.Second)
+	for !allIdle(srv) {
+		if time.Now().After(deadline) {
+			t.Fatal("connection never went idle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v, want nil", err)
+	}
+	expectClosed(t, conn)
+
+	if srv.trackConnection(&net.TCPConn{}) {
+		t.Fatal("server accepted a connection after Shutdown")
+	}
+}
+
+func allIdle(srv *Server) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, idle := range srv.connections {
+		if !idle {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrVerifierBusy = errors.New("verification queue full")
+)
+
+// VerifierPool verifies solutions on a fixed number of workers, so the CPU
+// spent on verification, Argon2id especially, stays bounded however many
+// connections submit at once. Submissions beyond the workers and the queue
+// are rejected with ErrVerifierBusy instead of piling up.
+type VerifierPool struct {
+	ctx  context.Context
+	jobs chan verifyJob
+}
+
+type verifyJob struct {
+	verify func() error
+	result chan error
+}
+
+// NewVerifierPool starts workers that run until ctx is done. queueSize
+// solutions may wait for a free worker.
+func NewVerifierPool(ctx context.Context, workers int, queueSize int) *VerifierPool {
+	p := &VerifierPool{
+		ctx:  ctx,
+		jobs: make(chan verifyJob, queueSize),
+	}
+	for range max(workers, 1) {
+		go p.work()
+	}
+
+	return p
+}
+
+// Verify runs protocol.VerifySolution on a worker and waits for the result.
+func (p *VerifierPool) Verify(ctx context.Context, challenge protocol.Challenge, solution protocol.Solution, now time.Time) error {
+	return p.run(ctx, func() error {
+		return protocol.VerifySolution(challenge, solution, now)
+	})
+}
+
+// run calls verify on a worker and waits for the result.
+func (p *VerifierPool) run(ctx context.Context, verify func() error) error {
+	job := verifyJob{
+		verify: verify,
+		result: make(chan error, 1),
+	}
+
+	select {
+	case p.jobs <- job:
+	default:
+		return ErrVerifierBusy
+	}
+
+	select {
+	case err := <-job.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+func (p *VerifierPool) work() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			job.result <- job.verify()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func solvedArgon2Challenge(b *testing.B) (protocol.Challenge, protocol.Solution) {
+	b.Helper()
+
+	challenge, err := protocol.NewChallenge(4, time.Hour)
+	if err != nil {
+		b.Fatal(err)
+	}
+	challenge.Algorithm = protocol.AlgoArgon2id
+	challenge.Argon2 = protocol.DefaultArgon2Params
+
+	solution, err := protocol.SolveChallenge(challenge, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return challenge, solution
+}
+
+// BenchmarkVerifyArgon2 compares verifying solutions one after the other,
+// as a single goroutine handling every connection would, with submitting
+// them from concurrent connections to a VerifierPool. The pool queue fits
+// every submitter, so nothing is rejected.
+func BenchmarkVerifyArgon2(b *testing.B) {
+	challenge, solution := solvedArgon2Challenge(b)
+	now := time.Now()
+
+	b.Run("inline", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if err := protocol.VerifySolution(challenge, solution, now); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	workers := runtime.GOMAXPROCS(0)
+	b.Run(fmt.Sprintf("pool=%d", workers), func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pool := NewVerifierPool(ctx, workers, runtime.GOMAXPROCS(0))
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := pool.Verify(ctx, challenge, solution, now); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package server_sdk
+
+import (
+	"sync"
+	"wordofwisdom/pkg/protocol"
+)
+
+func newBufferPool(s *ServerSDK) *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			buff := make([]byte, 0, s.maxMessageSizeBytes)
+			return &buff
+		},
+	}
+}
+
+func (s *ServerSDK) acquireBuffer(size int) []byte {
+	if s.bufferPool == nil {
+		return make([]byte, size)
+	}
+
+	buff := *s.bufferPool.Get().(*[]byte)
+	if cap(buff) < size {
+		return make([]byte, size)
+	}
+
+	return buff[:size]
+}
+
+// ReleaseMessage returns a message frame (RawMessage.Frame) to the buffer
+// pool. The message must not be used afterwards. It is a no-op unless the
+// SDK was created with WithBufferPool.
+func (s *ServerSDK) ReleaseMessage(frame []byte) {
+	if s.bufferPool == nil || frame == nil {
+		return
+	}
+
+	buff := frame[:0]
+	s.bufferPool.Put(&buff)
+}
+
+// parseDelivered parses a frame taken from messagesCh. A frame that does not
+// parse never reaches the caller, so its buffer goes back to the pool here.
+func (s *ServerSDK) parseDelivered(frame []byte) (*protocol.RawMessage, error) {
+	msg, err := protocol.ParseRawMessage(frame)
+	if err != nil {
+		s.ReleaseMessage(frame)
+		return nil, err
+	}
+
+	return msg, nil
+}
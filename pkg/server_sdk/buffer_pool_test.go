@@ -0,0 +1,69 @@
+package server_sdk
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+// BenchmarkReceiveMessage pops and releases quote-sized frames; run with
+// -benchmem to compare allocations with and without the buffer pool.
+func BenchmarkReceiveMessage(b *testing.B) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "unpooled"},
+		{name: "pooled", opts: []Option{WithBufferPool()}},
+	}
+
+	frame, err := protocol.BuildRawMessage(true, responses.RES_CODE_WISDOM, protocol.StringEncoder(strings.Repeat("q", 512)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer ln.Close()
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				for range b.N {
+					if _, err := conn.Write(frame); err != nil {
+						return
+					}
+				}
+				conn.Read(make([]byte, 1))
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sdk := NewServerSDK(ctx, ln.Addr().String(), 1024, 5*time.Second, tt.opts...)
+			if err := sdk.OpenConnection(); err != nil {
+				b.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				msg, err := sdk.PopMessage()
+				if err != nil {
+					b.Fatal(err)
+				}
+				sdk.ReleaseMessage(msg.Frame)
+			}
+		})
+	}
+}
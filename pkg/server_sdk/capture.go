@@ -0,0 +1,44 @@
+package server_sdk
+
+import (
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Capture record: direction (1 byte) | unix nanoseconds (8 bytes) |
+// frame length (4 bytes) | frame. The replay package reads them back.
+const CaptureHeaderSize = 1 + 8 + 4
+
+// capture serializes records from the sending and receiving goroutines.
+// After the first write error it stops, so a broken sink does not fail
+// the connection.
+type capture struct {
+	mu     sync.Mutex
+	w      io.Writer
+	failed bool
+}
+
+func (s *ServerSDK) captureFrame(dir Direction, frame []byte) {
+	c := s.capture
+	if c == nil {
+		return
+	}
+
+	record := make([]byte, CaptureHeaderSize, CaptureHeaderSize+len(frame))
+	record[0] = byte(dir)
+	binary.BigEndian.PutUint64(record[1:9], uint64(s.now().UnixNano()))
+	binary.BigEndian.PutUint32(record[9:13], uint32(len(frame)))
+	record = append(record, frame...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed {
+		return
+	}
+	if _, err := c.w.Write(record); err != nil {
+		c.failed = true
+		s.logger.Warn("Frame capture stopped", slog.Any("error", err))
+	}
+}
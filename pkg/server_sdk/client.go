@@ -0,0 +1,330 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+var (
+	ErrUnexpectedResponse = errors.New("unexpected server response")
+	ErrServerRejected     = errors.New("server rejected request")
+)
+
+type ClientConfig struct {
+	ServerAddress       string
+	MaxMessageSizeBytes int
+	PopMessageTimeout   time.Duration
+	// SolveWorkers defaults to the number of CPUs.
+	SolveWorkers int
+	// MaxSolveAttempts bounds the counters tried per challenge so a server
+	// demanding an absurd difficulty cannot pin the client's CPU; zero means
+	// no limit.
+	MaxSolveAttempts uint64
+	// Algorithms the client is willing to solve, in order of preference.
+	// Defaults to AlgoSHA256 only.
+	Algorithms []protocol.Algorithm
+	// Options are passed to every ServerSDK the client creates.
+	Options []Option
+	// Category of quotes to ask for; empty lets the server pick any.
+	Category string
+}
+
+// Client wraps the whole word of wisdom exchange: connect, solve the
+// server's challenge and return the quote.
+type Client struct {
+	cfg        ClientConfig
+	solveTimes *latencyReservoir
+}
+
+func NewClient(cfg ClientConfig) *Client {
+	if cfg.SolveWorkers <= 0 {
+		cfg.SolveWorkers = runtime.NumCPU()
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []protocol.Algorithm{protocol.AlgoSHA256}
+	}
+
+	return &Client{cfg: cfg, solveTimes: &latencyReservoir{}}
+}
+
+// GetWisdom opens a connection, completes the PoW handshake and returns
+// the quote sent by the server.
+func (c *Client) GetWisdom(ctx context.Context) (string, error) {
+	sdk := NewServerSDK(ctx, c.cfg.ServerAddress, c.cfg.MaxMessageSizeBytes, c.cfg.PopMessageTimeout, c.cfg.Options...)
+	if err := sdk.OpenConnection(); err != nil {
+		return "", err
+	}
+	defer sdk.CloseConnection()
+
+	quote, _, err := c.fetchWisdom(ctx, sdk, true)
+	return quote, err
+}
+
+// Ping checks that the server is alive with a health check, which needs no
+// PoW. The hello or challenge the server opens with is ignored.
+func (c *Client) Ping(ctx context.Context) error {
+	sdk := NewServerSDK(ctx, c.cfg.ServerAddress, c.cfg.MaxMessageSizeBytes, c.cfg.PopMessageTimeout, c.cfg.Options...)
+	if err := sdk.OpenConnection(); err != nil {
+		return err
+	}
+	defer sdk.CloseConnection()
+
+	if err := sdk.SendMessageContext(ctx, true, protocol.OpcodeHealthCheck, nil); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.popSuccess(ctx, sdk)
+		if err != nil {
+			return err
+		}
+		switch msg.Opcode {
+		case protocol.OpcodeHealthCheck:
+			return nil
+		case responses.RES_CODE_HELLO, responses.RES_CODE_POW_CHALLENGE, responses.RES_CODE_CHALLENGE_TOKEN:
+		default:
+			return ErrUnexpectedResponse
+		}
+	}
+}
+
+// GetWisdomBatch opens a connection and trades the opening challenge for a
+// batch of count quotes behind a single, harder challenge of
+// protocol.BatchDifficulty.
+func (c *Client) GetWisdomBatch(ctx context.Context, count int) ([]string, error) {
+	sdk := NewServerSDK(ctx, c.cfg.ServerAddress, c.cfg.MaxMessageSizeBytes, c.cfg.PopMessageTimeout, c.cfg.Options...)
+	if err := sdk.OpenConnection(); err != nil {
+		return nil, err
+	}
+	defer sdk.CloseConnection()
+
+	ctx, span := sdk.startSpan(ctx, SpanHandshake, slog.Int("batch_size", count))
+	quotes, err := c.fetchBatch(ctx, sdk, count)
+	span.end(err)
+	return quotes, err
+}
+
+func (c *Client) fetchBatch(ctx context.Context, sdk *ServerSDK, count int) ([]string, error) {
+	msg, err := c.popSuccess(ctx, sdk)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Opcode == responses.RES_CODE_HELLO {
+		if msg, err = c.negotiate(ctx, sdk, msg); err != nil {
+			return nil, err
+		}
+	}
+	if !isChallenge(msg.Opcode) {
+		return nil, ErrUnexpectedResponse
+	}
+
+	req := requests.BatchWisdomRequest{Count: count, Category: c.cfg.Category}
+	if err := sdk.SendMessageContext(ctx, true, requests.OPCODE_REQUEST_WISDOM_BATCH, req); err != nil {
+		return nil, err
+	}
+
+	if msg, err = c.popSuccess(ctx, sdk); err != nil {
+		return nil, err
+	}
+	if msg.Opcode != responses.RES_CODE_BATCH_CHALLENGE {
+		return nil, ErrUnexpectedResponse
+	}
+
+	batch := protocol.BatchChallenge{}
+	if err := msg.DecodePayload(&batch); err != nil {
+		return nil, err
+	}
+	if batch.Count != count {
+		return nil, ErrUnexpectedResponse
+	}
+	if err := batch.Challenge.ValidateAt(sdk.now()); err != nil {
+		return nil, err
+	}
+
+	solution, err := c.solve(ctx, sdk, batch.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sdk.SendMessageContext(ctx, true, requests.OPCODE_SUBMIT_SOLUTION, solution); err != nil {
+		return nil, err
+	}
+
+	if msg, err = c.popSuccess(ctx, sdk); err != nil {
+		return nil, err
+	}
+	if msg.Opcode != responses.RES_CODE_WISDOM_BATCH {
+		return nil, ErrUnexpectedResponse
+	}
+
+	wisdom := responses.WisdomBatchResponse{}
+	if err := msg.DecodePayload(&wisdom); err != nil {
+		return nil, err
+	}
+	if len(wisdom.Quotes) != count {
+		return nil, ErrUnexpectedResponse
+	}
+
+	return wisdom.Quotes, nil
+}
+
+// fetchWisdom runs one challenge exchange on an open connection. On a fresh
+// connection the server sends the challenge (after an optional hello) by
+// itself; later quotes have to be asked for. The session token is returned
+// if the server issued one.
+func (c *Client) fetchWisdom(ctx context.Context, sdk *ServerSDK, fresh bool) (string, *protocol.SessionToken, error) {
+	ctx, span := sdk.startSpan(ctx, SpanHandshake)
+	quote, session, err := c.handshake(ctx, sdk, fresh)
+	span.end(err)
+	return quote, session, err
+}
+
+func (c *Client) handshake(ctx context.Context, sdk *ServerSDK, fresh bool) (string, *protocol.SessionToken, error) {
+	if !fresh {
+		if err := sdk.SendMessageContext(ctx, true, requests.OPCODE_REQUEST_WISDOM, nil); err != nil {
+			return "", nil, err
+		}
+	}
+
+	msg, err := c.popSuccess(ctx, sdk)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if fresh && msg.Opcode == responses.RES_CODE_HELLO {
+		if msg, err = c.negotiate(ctx, sdk, msg); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return c.answerChallenge(ctx, sdk, msg)
+}
+
+// answerChallenge solves the challenge in msg and reads the quote, and the
+// session token if any, that follow.
+func (c *Client) answerChallenge(ctx context.Context, sdk *ServerSDK, msg *protocol.RawMessage) (string, *protocol.SessionToken, error) {
+	challenge, token, err := decodeChallenge(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := challenge.ValidateAt(sdk.now()); err != nil {
+		return "", nil, err
+	}
+
+	solution, err := c.solve(ctx, sdk, challenge)
+	if err != nil {
+		return "", nil, err
+	}
+	solution.Category = c.cfg.Category
+	solution.Token = token
+
+	if err := sdk.SendMessageContext(ctx, true, requests.OPCODE_SUBMIT_SOLUTION, solution); err != nil {
+		return "", nil, err
+	}
+
+	msg, err = c.popSuccess(ctx, sdk)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var session *protocol.SessionToken
+	if msg.Opcode == responses.RES_CODE_SESSION {
+		session = &protocol.SessionToken{}
+		if err := msg.DecodePayload(session); err != nil {
+			return "", nil, err
+		}
+		if msg, err = c.popSuccess(ctx, sdk); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if msg.Opcode != responses.RES_CODE_WISDOM {
+		return "", nil, ErrUnexpectedResponse
+	}
+
+	wisdom := responses.WisdomResponse{}
+	if err := msg.DecodePayload(&wisdom); err != nil {
+		return "", nil, err
+	}
+
+	return wisdom.Quote, session, nil
+}
+
+// isChallenge reports whether opcode carries a challenge, plain or as a
+// stateless token.
+func isChallenge(opcode uint32) bool {
+	return opcode == responses.RES_CODE_POW_CHALLENGE || opcode == responses.RES_CODE_CHALLENGE_TOKEN
+}
+
+// decodeChallenge reads the challenge in msg. A challenge token is also
+// returned encoded, for the solution to carry back.
+func decodeChallenge(msg *protocol.RawMessage) (protocol.Challenge, []byte, error) {
+	switch msg.Opcode {
+	case responses.RES_CODE_POW_CHALLENGE:
+		challenge := protocol.Challenge{}
+		err := msg.DecodePayload(&challenge)
+		return challenge, nil, err
+	case responses.RES_CODE_CHALLENGE_TOKEN:
+		token := protocol.ChallengeToken{}
+		if err := msg.DecodePayload(&token); err != nil {
+			return protocol.Challenge{}, nil, err
+		}
+		encoded, err := token.Encode()
+		return token.Challenge, encoded, err
+	default:
+		return protocol.Challenge{}, nil, ErrUnexpectedResponse
+	}
+}
+
+func (c *Client) solve(ctx context.Context, sdk *ServerSDK, challenge protocol.Challenge) (protocol.Solution, error) {
+	ctx, span := sdk.startSpan(ctx, SpanSolve,
+		slog.Int("difficulty", challenge.Difficulty),
+		slog.String("algorithm", challenge.Algorithm.String()),
+	)
+	started := sdk.now()
+	solution, err := SolveParallel(ctx, challenge, c.cfg.SolveWorkers, c.cfg.MaxSolveAttempts)
+	elapsed := sdk.since(started)
+	if err == nil {
+		c.solveTimes.record(elapsed)
+	}
+	span.SetAttributes(slog.Duration("solve_duration", elapsed))
+	span.end(err)
+	return solution, err
+}
+
+// negotiate answers the server hello and returns the message that follows.
+func (c *Client) negotiate(ctx context.Context, sdk *ServerSDK, msg *protocol.RawMessage) (*protocol.RawMessage, error) {
+	hello := protocol.HelloMessage{}
+	if err := msg.DecodePayload(&hello); err != nil {
+		return nil, err
+	}
+
+	algorithm, err := protocol.NegotiateAlgorithm(hello, c.cfg.Algorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sdk.SendMessageContext(ctx, true, requests.OPCODE_HELLO_ACK, protocol.HelloAck{Algorithm: algorithm}); err != nil {
+		return nil, err
+	}
+
+	return c.popSuccess(ctx, sdk)
+}
+
+func (c *Client) popSuccess(ctx context.Context, sdk *ServerSDK) (*protocol.RawMessage, error) {
+	msg, err := sdk.PopMessageContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := DecodeServerError(msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
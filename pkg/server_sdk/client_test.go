@@ -0,0 +1,311 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/responses"
+	"wordofwisdom/pkg/server"
+)
+
+const testQuote = "test quote"
+
+// startServer serves a word of wisdom server over a PipeTransport and
+// returns a client config that reaches it. cfg defaults to cheap
+// challenges.
+func startServer(t *testing.T, cfg server.Config, quotes ...string) ClientConfig {
+	t.Helper()
+
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = 4
+	}
+	if cfg.MaxMessageSizeBytes == 0 {
+		cfg.MaxMessageSizeBytes = testMaxMessageSize
+	}
+	if len(quotes) == 0 {
+		quotes = []string{testQuote}
+	}
+	provider, err := server.NewSliceQuoteProvider(quotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := server.NewServer(context.Background(), cfg, provider)
+	transport := NewPipeTransport()
+	go srv.Serve(transport)
+	t.Cleanup(func() { srv.Close() })
+
+	return ClientConfig{
+		ServerAddress:       "pipe",
+		MaxMessageSizeBytes: testMaxMessageSize,
+		PopMessageTimeout:   5 * time.Second,
+		Options:             []Option{WithTransport(transport)},
+	}
+}
+
+func TestClientNegotiatesAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		offered   []protocol.Algorithm
+		supported []protocol.Algorithm
+		err       error
+	}{
+		{name: "sha256 client, both offered", offered: []protocol.Algorithm{protocol.AlgoArgon2id, protocol.AlgoSHA256}, supported: []protocol.Algorithm{protocol.AlgoSHA256}},
+		{name: "default client, both offered", offered: []protocol.Algorithm{protocol.AlgoArgon2id, protocol.AlgoSHA256}},
+		{name: "argon2id client, sha256 offered", offered: []protocol.Algorithm{protocol.AlgoSHA256}, supported: []protocol.Algorithm{protocol.AlgoArgon2id}, err: protocol.ErrNoCommonAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := startServer(t, server.Config{Algorithms: tt.offered})
+			cfg.Algorithms = tt.supported
+
+			var solved []protocol.Algorithm
+			cfg.Options = append(cfg.Options, WithFrameTap(func(dir Direction, frame []byte) {
+				msg, err := protocol.ParseRawMessage(frame)
+				challenge := protocol.Challenge{}
+				if err == nil && dir == DirectionReceived && msg.DecodePayload(&challenge) == nil {
+					solved = append(solved, challenge.Algorithm)
+				}
+			}))
+
+			quote, err := NewClient(cfg).GetWisdom(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if quote != testQuote {
+				t.Fatalf("got quote %q", quote)
+			}
+			if len(solved) != 1 || solved[0] != protocol.AlgoSHA256 {
+				t.Fatalf("solved %v, want one sha256 challenge", solved)
+			}
+		})
+	}
+}
+
+func TestClientGetWisdomRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  server.Config
+		err  error
+	}{
+		{name: "plain"},
+		{name: "with session token", cfg: server.Config{SessionTTL: time.Minute}},
+		{name: "argon2id negotiated", cfg: server.Config{
+			Algorithms: []protocol.Algorithm{protocol.AlgoArgon2id},
+			Argon2:     &protocol.Argon2Params{Time: 1, MemoryKiB: 64, Threads: 1},
+		}},
+		{name: "rate limited", cfg: server.Config{RateLimiter: server.NewTokenBucketLimiter(0.001, 0, time.Minute)}, err: ErrCodeRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Difficulty = 4
+			cfg.MaxMessageSizeBytes = testMaxMessageSize
+			provider, err := server.NewSliceQuoteProvider([]string{testQuote})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := server.NewServer(context.Background(), cfg, provider)
+			defer srv.Close()
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Serve(ln)
+
+			client := NewClient(ClientConfig{
+				ServerAddress:       ln.Addr().String(),
+				MaxMessageSizeBytes: testMaxMessageSize,
+				PopMessageTimeout:   5 * time.Second,
+				Algorithms:          []protocol.Algorithm{protocol.AlgoSHA256, protocol.AlgoArgon2id},
+			})
+			quote, err := client.GetWisdom(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && quote != testQuote {
+				t.Fatalf("got quote %q, want %q", quote, testQuote)
+			}
+			if tt.err != nil && !errors.Is(err, ErrServerRejected) {
+				t.Fatalf("%v does not match ErrServerRejected", err)
+			}
+		})
+	}
+}
+
+func TestClientRejectsMalformedChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *protocol.Challenge)
+		err    error
+	}{
+		{name: "zero difficulty", modify: func(c *protocol.Challenge) { c.Difficulty = 0 }, err: protocol.ErrZeroDifficulty},
+		{name: "empty nonce", modify: func(c *protocol.Challenge) { c.Nonce = [protocol.NonceSize]byte{} }, err: protocol.ErrEmptyNonce},
+		{name: "already expired", modify: func(c *protocol.Challenge) { c.Expiry = time.Now().Add(-time.Second) }, err: protocol.ErrChallengeExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := protocol.NewChallenge(4, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&challenge)
+
+			// Opens with the bad challenge and reports whatever comes back.
+			answered := make(chan bool, 1)
+			address := listen(t, func(conn net.Conn) {
+				writeFrame(t, conn, true, responses.RES_CODE_POW_CHALLENGE, challenge)
+				_, err := protocol.ReadMessage(conn, testMaxMessageSize)
+				answered <- err == nil
+			})
+
+			client := NewClient(ClientConfig{
+				ServerAddress:       address,
+				MaxMessageSizeBytes: testMaxMessageSize,
+				PopMessageTimeout:   5 * time.Second,
+			})
+			_, err = client.GetWisdom(context.Background())
+			if !errors.Is(err, tt.err) || !errors.Is(err, protocol.ErrInvalidChallenge) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if <-answered {
+				t.Fatal("client answered a challenge it should have rejected")
+			}
+		})
+	}
+}
+
+func TestSessionSolvesRefreshChallenge(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	cfg := startServer(t, server.Config{SessionTTL: time.Minute, ChallengeRefreshInterval: interval})
+	var challenges atomic.Int64
+	cfg.Options = append(cfg.Options, WithFrameTap(func(dir Direction, frame []byte) {
+		if opcode, _ := protocol.PeekOpcode(frame); dir == DirectionReceived && opcode == responses.RES_CODE_POW_CHALLENGE {
+			challenges.Add(1)
+		}
+	}))
+
+	session, err := NewClient(cfg).Session(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	tests := []struct {
+		name  string
+		sleep time.Duration
+		// challenges is the number solved so far, after this fetch.
+		challenges int64
+	}{
+		{name: "first quote", challenges: 1},
+		{name: "redeemed token", challenges: 1},
+		{name: "past the refresh interval", sleep: interval + interval/2, challenges: 2},
+		{name: "fresh token", challenges: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(tt.sleep)
+			quote, err := session.GetWisdom(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote != testQuote {
+				t.Fatalf("got quote %q, want %q", quote, testQuote)
+			}
+			if got := challenges.Load(); got != tt.challenges {
+				t.Fatalf("%d challenges solved, want %d", got, tt.challenges)
+			}
+		})
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  server.Config
+	}{
+		// Difficulty 30 is out of reach, so a pass means no solution was sent.
+		{name: "unsolvable challenge", cfg: server.Config{Difficulty: 30}},
+		{name: "after hello", cfg: server.Config{Algorithms: []protocol.Algorithm{protocol.AlgoSHA256}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(startServer(t, tt.cfg))
+
+			if err := client.Ping(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestHeartbeatAgainstServer(t *testing.T) {
+	cfg := startServer(t, server.Config{})
+	sdk := NewServerSDK(context.Background(), cfg.ServerAddress, cfg.MaxMessageSizeBytes, cfg.PopMessageTimeout,
+		append(cfg.Options, WithHeartbeat(5*time.Millisecond, time.Second))...)
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseConnection()
+	// Heartbeats run while the challenge is pending.
+	msg, err := sdk.PopMessage()
+	if err != nil || msg.Opcode != responses.RES_CODE_POW_CHALLENGE {
+		t.Fatalf("got %v, %v; want the challenge", msg, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sdk.Stats().RTT == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no pong from the server")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-sdk.Done():
+		t.Fatalf("server dropped a heartbeating client: %v", sdk.Err())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+}
+
+func TestClientSolvesChallengeTokens(t *testing.T) {
+	key := protocol.ChallengeKey{ID: 7, Secret: []byte("test challenge key")}
+	cfg := startServer(t, server.Config{ChallengeKey: &key, MaxBatchSize: 2})
+	client := NewClient(cfg)
+
+	tests := []struct {
+		name  string
+		fetch func(ctx context.Context) error
+	}{
+		{name: "wisdom", fetch: func(ctx context.Context) error {
+			_, err := client.GetWisdom(ctx)
+			return err
+		}},
+		{name: "batch", fetch: func(ctx context.Context) error {
+			_, err := client.GetWisdomBatch(ctx, 2)
+			return err
+		}},
+		{name: "ping", fetch: client.Ping},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fetch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
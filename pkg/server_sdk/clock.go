@@ -0,0 +1,57 @@
+package server_sdk
+
+import "time"
+
+// Clock is the time source for every SDK timeout: PopMessage, heartbeats,
+// the idle watch, reconnect backoff, challenge expiry, write buffer flushes,
+// the Shutdown drain and Pool idle eviction. Socket deadlines still use the
+// wall clock, since the kernel enforces them.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f in its own goroutine after d. The returned Timer's
+	// channel is unused.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the part of *time.Timer the SDK uses.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (s *ServerSDK) now() time.Time {
+	return s.clock.Now()
+}
+
+func (s *ServerSDK) since(t time.Time) time.Duration {
+	return s.clock.Now().Sub(t)
+}
@@ -0,0 +1,279 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/server"
+)
+
+// waitTimers waits in real time until the code under test has armed n fake
+// timers, so the next Advance is not lost on a timer that does not exist yet.
+func waitTimers(t *testing.T, clock *FakeClock, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Timers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers armed, want %d", clock.Timers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// silentServer accepts connections, reads everything and never writes.
+func silentServer(t *testing.T) string {
+	return listen(t, func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	})
+}
+
+func TestFakeClockDrivesWatchdogs(t *testing.T) {
+	type step struct {
+		// timers is how many timers must be armed before advancing.
+		timers  int
+		advance time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		opt   Option
+		steps []step
+		err   error
+	}{
+		{
+			name: "idle timeout",
+			opt:  WithIdleTimeout(time.Hour),
+			steps: []step{
+				{timers: 1, advance: time.Hour},
+			},
+			err: ErrIdleTimeout,
+		},
+		{
+			name: "heartbeat timeout",
+			opt:  WithHeartbeat(time.Hour, time.Minute),
+			steps: []step{
+				// The interval fires a ping, then the pong wait joins the ticker.
+				{timers: 1, advance: time.Hour},
+				{timers: 2, advance: time.Minute},
+			},
+			err: ErrHeartbeatTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(0, 0))
+			sdk := openSDK(t, silentServer(t), WithClock(clock), tt.opt)
+
+			for i, step := range tt.steps {
+				waitTimers(t, clock, step.timers)
+				if i < len(tt.steps)-1 {
+					clock.Advance(step.advance)
+					continue
+				}
+
+				clock.Advance(step.advance - time.Nanosecond)
+				select {
+				case <-sdk.Done():
+					t.Fatalf("closed a nanosecond early: %v", sdk.Err())
+				case <-time.After(20 * time.Millisecond):
+				}
+				clock.Advance(time.Nanosecond)
+			}
+
+			select {
+			case <-sdk.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("connection still open after advancing past the timeout")
+			}
+			if err := sdk.Err(); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestFakeClockDrivesPopMessageTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	sdk := NewServerSDK(context.Background(), silentServer(t), testMaxMessageSize, time.Hour, WithClock(clock))
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseConnection()
+
+	popped := make(chan error, 1)
+	go func() {
+		_, err := sdk.PopMessage()
+		popped <- err
+	}()
+
+	waitTimers(t, clock, 1)
+	clock.Advance(time.Hour - time.Nanosecond)
+	select {
+	case err := <-popped:
+		t.Fatalf("PopMessage returned %v before the timeout", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Nanosecond)
+	select {
+	case err := <-popped:
+		if !errors.Is(err, ErrPopMessageTimeout) {
+			t.Fatalf("got %v, want ErrPopMessageTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PopMessage still waiting after the clock passed its timeout")
+	}
+}
+
+func TestFakeClockDrivesReconnectBackoff(t *testing.T) {
+	// The first connection is hung up at once; the reconnect is left open.
+	var dials atomic.Int32
+	accepted := make(chan struct{}, 2)
+	address := listen(t, func(conn net.Conn) {
+		accepted <- struct{}{}
+		if dials.Add(1) == 1 {
+			return
+		}
+		io.Copy(io.Discard, conn)
+	})
+
+	clock := NewFakeClock(time.Unix(0, 0))
+	sdk := openSDK(t, address, WithClock(clock), WithReconnect(ReconnectPolicy{
+		InitialDelay: time.Hour,
+		Multiplier:   2,
+		MaxAttempts:  1,
+	}))
+	<-accepted
+
+	waitTimers(t, clock, 1)
+	select {
+	case <-accepted:
+		t.Fatal("redialled before the backoff elapsed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case event := <-sdk.ReconnectEvents():
+		if event.Attempt != 1 || event.Err != nil {
+			t.Fatalf("got %+v, want a successful first attempt", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reconnect after advancing past the backoff")
+	}
+}
+
+func TestFakeClockDrivesShutdownDrain(t *testing.T) {
+	const queued = 3
+
+	address := listen(t, func(conn net.Conn) {
+		for i := range queued {
+			writeFrame(t, conn, true, uint32(i+1), nil)
+		}
+		io.Copy(io.Discard, conn)
+	})
+	clock := NewFakeClock(time.Unix(0, 0))
+	sdk := openSDK(t, address, WithClock(clock), WithReceiveBuffer(queued))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sdk.undelivered() < queued {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d messages queued", sdk.undelivered(), queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan ShutdownResult, 1)
+	go func() {
+		result, _ := sdk.Shutdown(context.Background())
+		done <- result
+	}()
+
+	// Shutdown only notices the drained queue when its poll timer fires.
+	waitTimers(t, clock, 1)
+	for i := range queued {
+		if _, err := sdk.PopMessage(); err != nil {
+			t.Fatalf("pop %d: %v", i, err)
+		}
+	}
+	select {
+	case result := <-done:
+		t.Fatalf("Shutdown returned %+v before its poll timer fired", result)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(shutdownPollInterval)
+	select {
+	case result := <-done:
+		if want := (ShutdownResult{Drained: queued}); result != want {
+			t.Fatalf("got %+v, want %+v", result, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown still waiting after the poll timer fired")
+	}
+}
+
+func TestFakeClockDrivesWriteBufferFlush(t *testing.T) {
+	received := make(chan struct{}, 1)
+	address := listen(t, func(conn net.Conn) {
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			received <- struct{}{}
+		}
+		io.Copy(io.Discard, conn)
+	})
+	clock := NewFakeClock(time.Unix(0, 0))
+	sdk := openSDK(t, address, WithClock(clock), WithWriteBuffer(1024))
+
+	if err := sdk.SendMessage(true, requests.OPCODE_REQUEST_WISDOM, nil); err != nil {
+		t.Fatal(err)
+	}
+	waitTimers(t, clock, 1)
+	select {
+	case <-received:
+		t.Fatal("frame flushed before the flush delay")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(writeFlushDelay)
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("frame still buffered after the flush delay")
+	}
+}
+
+func TestFakeClockDrivesPoolEviction(t *testing.T) {
+	const idleTimeout = time.Minute
+
+	cfg := startServer(t, server.Config{})
+	dials := countDials(&cfg)
+	clock := NewFakeClock(time.Unix(0, 0))
+	pool := NewPool(NewClient(cfg), PoolConfig{IdleTimeout: idleTimeout, Clock: clock})
+	defer pool.Close()
+
+	steps := []struct {
+		advance time.Duration
+		dials   int32
+	}{
+		{advance: 0, dials: 1},
+		{advance: idleTimeout, dials: 1},
+		{advance: idleTimeout + time.Nanosecond, dials: 2},
+	}
+
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		if _, err := pool.GetWisdom(context.Background()); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if got := dials.Load(); got != step.dials {
+			t.Fatalf("call %d: %d dials, want %d", i, got, step.dials)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package server_sdk
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+var (
+	ErrNotConnected = errors.New("not connected")
+	ErrReadDeadline = errors.New("read deadline exceeded")
+)
+
+// SetReadDeadline sets a deadline for receiving the next frame from the
+// server, following net.Conn semantics; the zero time clears it. The receive
+// loop's own poll deadline never cuts a read short of t. Once t passes
+// without a complete frame, a waiting PopMessage returns a non-fatal
+// ErrReadDeadline and the deadline is cleared. In sync mode t applies to
+// ReadMessage as is.
+func (s *ServerSDK) SetReadDeadline(t time.Time) error {
+	conn := s.getConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	if s.syncMode {
+		return conn.SetReadDeadline(t)
+	}
+	s.readDeadline.Store(deadlineNanos(t))
+	return conn.SetReadDeadline(s.pollDeadline(time.Now()))
+}
+
+// SetWriteDeadline sets a deadline for every following write; the zero time
+// clears it. A context deadline passed to SendMessageContext takes
+// precedence if it is earlier.
+func (s *ServerSDK) SetWriteDeadline(t time.Time) error {
+	conn := s.getConn()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	s.writeDeadline.Store(deadlineNanos(t))
+	return conn.SetWriteDeadline(t)
+}
+
+func deadlineNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nanosDeadline(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// pollDeadline is the read deadline for the next read in the receive loop:
+// the poll interval, or the user read deadline if that comes first.
+func (s *ServerSDK) pollDeadline(now time.Time) time.Time {
+	deadline := now.Add(readPollInterval)
+	if user := nanosDeadline(s.readDeadline.Load()); !user.IsZero() && user.Before(deadline) {
+		return user
+	}
+	return deadline
+}
+
+// readDeadlinePassed reports whether a user read deadline has been reached,
+// clearing it so the timeout is surfaced only once.
+func (s *ServerSDK) readDeadlinePassed(now time.Time) bool {
+	nanos := s.readDeadline.Load()
+	if nanos == 0 || now.UnixNano() < nanos {
+		return false
+	}
+	return s.readDeadline.CompareAndSwap(nanos, 0)
+}
+
+// writeDeadlineFor combines the user write deadline with the deadline of ctx,
+// the earlier one applying.
+func (s *ServerSDK) writeDeadlineFor(ctxDeadline time.Time, ok bool) time.Time {
+	deadline := nanosDeadline(s.writeDeadline.Load())
+	if ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+func readDeadlineError() *SDKError {
+	return &SDKError{Kind: ErrorKindTimeout, Err: errors.Join(ErrReadDeadline, os.ErrDeadlineExceeded)}
+}
@@ -0,0 +1,106 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestDeadlinesNeedConnection(t *testing.T) {
+	sdk := NewServerSDK(context.Background(), "127.0.0.1:1", testMaxMessageSize, time.Second)
+	if err := sdk.SetReadDeadline(time.Now()); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("SetReadDeadline = %v, want ErrNotConnected", err)
+	}
+	if err := sdk.SetWriteDeadline(time.Now()); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("SetWriteDeadline = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestWritePastDeadlineFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		// ctx bounds the send; zero sends without a context deadline.
+		ctx time.Duration
+	}{
+		{name: "deadline already passed", deadline: -time.Second},
+		{name: "deadline passes mid write", deadline: 50 * time.Millisecond},
+		{name: "earlier context deadline wins", deadline: time.Hour, ctx: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Never reads, so a pipe write blocks until its deadline.
+			release := make(chan struct{})
+			defer close(release)
+			sdk := pipeSDK(t, func(net.Conn) { <-release })
+			if err := sdk.SetWriteDeadline(time.Now().Add(tt.deadline)); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			if tt.ctx > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctx)
+				defer cancel()
+			}
+			start := time.Now()
+			err := sdk.SendMessageContext(ctx, true, 1, protocol.StringEncoder("late"))
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Fatalf("send = %v, want a deadline error", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("send returned after %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestReadDeadlineOutlastsPollInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+	}{
+		{name: "before the poll interval", deadline: 50 * time.Millisecond},
+		{name: "after the poll interval", deadline: readPollInterval + 200*time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Stays silent until told to send a frame.
+			send := make(chan struct{})
+			sdk := NewServerSDK(context.Background(), listen(t, func(conn net.Conn) {
+				<-send
+				writeFrame(t, conn, true, 1, protocol.StringEncoder("after"))
+				io.Copy(io.Discard, conn)
+			}), testMaxMessageSize, time.Minute)
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			start := time.Now()
+			if err := sdk.SetReadDeadline(start.Add(tt.deadline)); err != nil {
+				t.Fatal(err)
+			}
+			_, err := sdk.PopMessage()
+			if !errors.Is(err, ErrReadDeadline) {
+				t.Fatalf("pop = %v, want ErrReadDeadline", err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.deadline {
+				t.Fatalf("read deadline hit after %v, want %v", elapsed, tt.deadline)
+			}
+
+			// The deadline is not fatal and is cleared once surfaced.
+			close(send)
+			if _, err := sdk.PopMessage(); err != nil {
+				t.Fatalf("pop after the deadline = %v", err)
+			}
+		})
+	}
+}
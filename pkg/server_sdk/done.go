@@ -0,0 +1,36 @@
+package server_sdk
+
+import "context"
+
+// Done returns a channel that is closed once the SDK is finished, whether
+// its context was cancelled or the connection closed for good.
+func (s *ServerSDK) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// Err returns nil until Done is closed and then the cause: the context's
+// error, or the close cause such as ErrConnectionClosed, joined with io.EOF
+// when the server hung up.
+func (s *ServerSDK) Err() error {
+	select {
+	case <-s.doneCh:
+		return s.doneErr
+	default:
+		return nil
+	}
+}
+
+// watchDone finishes the SDK when its context is done. The watch is
+// dropped once the connection closes first.
+func (s *ServerSDK) watchDone() {
+	s.stopDoneWatch = context.AfterFunc(s.ctx, func() {
+		s.finish(context.Cause(s.ctx))
+	})
+}
+
+func (s *ServerSDK) finish(cause error) {
+	s.doneOnce.Do(func() {
+		s.doneErr = cause
+		close(s.doneCh)
+	})
+}
@@ -0,0 +1,76 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDoneReportsCause(t *testing.T) {
+	tests := []struct {
+		name string
+		// hangUp makes the server close the connection right away.
+		hangUp bool
+		finish func(sdk *ServerSDK, cancel context.CancelFunc)
+		errs   []error
+	}{
+		{
+			name:   "ctx cancelled",
+			finish: func(_ *ServerSDK, cancel context.CancelFunc) { cancel() },
+			errs:   []error{context.Canceled},
+		},
+		{
+			name:   "server hung up",
+			hangUp: true,
+			errs:   []error{ErrConnectionClosed, io.EOF},
+		},
+		{
+			name:   "connection closed",
+			finish: func(sdk *ServerSDK, _ context.CancelFunc) { sdk.CloseConnection() },
+			errs:   []error{ErrConnectionClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := listen(t, func(conn net.Conn) {
+				if !tt.hangUp {
+					io.Copy(io.Discard, conn)
+				}
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sdk := NewServerSDK(ctx, address, testMaxMessageSize, time.Minute)
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			if tt.finish != nil {
+				select {
+				case <-sdk.Done():
+					t.Fatalf("done before finishing: %v", sdk.Err())
+				default:
+				}
+				if err := sdk.Err(); err != nil {
+					t.Fatalf("Err() = %v before Done", err)
+				}
+				tt.finish(sdk, cancel)
+			}
+
+			select {
+			case <-sdk.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("Done never closed")
+			}
+			for _, want := range tt.errs {
+				if err := sdk.Err(); !errors.Is(err, want) {
+					t.Fatalf("Err() = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package server_sdk
+
+import (
+	"errors"
+	"net"
+	"wordofwisdom/pkg/protocol"
+)
+
+type ErrorKind int
+
+const (
+	// ErrorKindTimeout is a read or write that timed out; the connection is
+	// still usable.
+	ErrorKindTimeout ErrorKind = iota
+	// ErrorKindConnection is a closed, reset or otherwise broken connection.
+	ErrorKindConnection
+	// ErrorKindProtocol is a frame the SDK could not accept.
+	ErrorKindProtocol
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindTimeout:
+		return "timeout"
+	case ErrorKindConnection:
+		return "connection"
+	case ErrorKindProtocol:
+		return "protocol"
+	default:
+		return "unknown"
+	}
+}
+
+// SDKError is what the receive loop reports. Fatal errors end the
+// connection; the others may be retried.
+type SDKError struct {
+	Kind  ErrorKind
+	Fatal bool
+	Err   error
+}
+
+func (e *SDKError) Error() string {
+	return e.Kind.String() + ": " + e.Err.Error()
+}
+
+func (e *SDKError) Unwrap() error {
+	return e.Err
+}
+
+// IsFatal reports whether err carries a fatal SDKError.
+func IsFatal(err error) bool {
+	var sdkErr *SDKError
+	return errors.As(err, &sdkErr) && sdkErr.Fatal
+}
+
+func closedError() error {
+	return &SDKError{Kind: ErrorKindConnection, Fatal: true, Err: ErrConnectionClosed}
+}
+
+// classifyReadError decides whether reading may succeed if retried. Only
+// timeouts are retried; closed sockets, resets and anything unknown end the
+// connection.
+func classifyReadError(err error) *SDKError {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, protocol.ErrMessageTooLarge):
+		return &SDKError{Kind: ErrorKindProtocol, Fatal: true, Err: err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &SDKError{Kind: ErrorKindTimeout, Err: err}
+	default:
+		return &SDKError{Kind: ErrorKindConnection, Fatal: true, Err: err}
+	}
+}
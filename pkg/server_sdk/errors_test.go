@@ -0,0 +1,121 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+// failingConn is a connection whose every Read fails with err.
+type failingConn struct {
+	err    error
+	reads  atomic.Int32
+	closed atomic.Bool
+}
+
+func (c *failingConn) Read([]byte) (int, error) {
+	c.reads.Add(1)
+	if c.closed.Load() {
+		return 0, net.ErrClosed
+	}
+	return 0, c.err
+}
+
+func (c *failingConn) Write(b []byte) (int, error)      { return len(b), nil }
+func (c *failingConn) Close() error                     { c.closed.Store(true); return nil }
+func (c *failingConn) LocalAddr() net.Addr              { return pipeAddr{} }
+func (c *failingConn) RemoteAddr() net.Addr             { return pipeAddr{} }
+func (c *failingConn) SetDeadline(time.Time) error      { return nil }
+func (c *failingConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *failingConn) SetWriteDeadline(time.Time) error { return nil }
+
+type connTransport struct {
+	conn net.Conn
+}
+
+func (t connTransport) Dial(context.Context) (net.Conn, error) {
+	return t.conn, nil
+}
+
+// timeoutError is a transient error, as a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestPersistentReadErrorDoesNotSpin(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		closes   bool
+		maxReads int32
+	}{
+		{name: "closed socket", err: net.ErrClosed, closes: true, maxReads: 1},
+		{name: "bad file descriptor", err: os.ErrClosed, closes: true, maxReads: 1},
+		{name: "unknown error", err: errors.New("device gone"), closes: true, maxReads: 1},
+		// The retry backoff doubles from 10ms, so a few hundred
+		// milliseconds of a persistent timeout only take a handful of reads.
+		{name: "persistent timeout", err: timeoutError{}, maxReads: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &failingConn{err: tt.err}
+			sdk := NewServerSDK(context.Background(), "pipe", testMaxMessageSize, 300*time.Millisecond, WithTransport(connTransport{conn: conn}))
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			_, err := sdk.PopMessage()
+			if tt.closes {
+				if !errors.Is(err, ErrConnectionClosed) || !IsFatal(err) {
+					t.Fatalf("got %v, want a fatal ErrConnectionClosed", err)
+				}
+				if closeErr := sdk.WaitForClose(); !errors.Is(closeErr, tt.err) {
+					t.Fatalf("close cause %v, want %v", closeErr, tt.err)
+				}
+			} else if !errors.Is(err, ErrPopMessageTimeout) || !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want ErrPopMessageTimeout carrying the read error", err)
+			}
+			if reads := conn.reads.Load(); reads > tt.maxReads {
+				t.Fatalf("receive loop read %d times, want at most %d", reads, tt.maxReads)
+			}
+		})
+	}
+}
+
+func TestClassifyReadError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		kind  ErrorKind
+		fatal bool
+	}{
+		{name: "timeout", err: timeoutError{}, kind: ErrorKindTimeout},
+		{name: "deadline exceeded", err: os.ErrDeadlineExceeded, kind: ErrorKindTimeout},
+		{name: "eof", err: io.EOF, kind: ErrorKindConnection, fatal: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, kind: ErrorKindConnection, fatal: true},
+		{name: "closed", err: net.ErrClosed, kind: ErrorKindConnection, fatal: true},
+		{name: "oversized frame", err: protocol.ErrMessageTooLarge, kind: ErrorKindProtocol, fatal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyReadError(tt.err)
+			if got.Kind != tt.kind || got.Fatal != tt.fatal {
+				t.Fatalf("got kind %v fatal %v, want %v %v", got.Kind, got.Fatal, tt.kind, tt.fatal)
+			}
+			if IsFatal(got) != tt.fatal || !errors.Is(got, tt.err) {
+				t.Fatalf("IsFatal %v, unwraps to %v: %v", IsFatal(got), tt.err, errors.Is(got, tt.err))
+			}
+		})
+	}
+}
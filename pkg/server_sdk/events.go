@@ -0,0 +1,61 @@
+package server_sdk
+
+import "time"
+
+type EventType int
+
+const (
+	EventConnected EventType = iota
+	// EventReconnecting is emitted when the connection drops and the
+	// reconnect policy starts redialing.
+	EventReconnecting
+	EventReconnected
+	// EventHandshakeComplete follows a successful WithHandshake callback.
+	EventHandshakeComplete
+	// EventHandshakeFailed carries the callback error.
+	EventHandshakeFailed
+	// EventClosed is the last event; Err is the close cause.
+	EventClosed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "connected"
+	case EventReconnecting:
+		return "reconnecting"
+	case EventReconnected:
+		return "reconnected"
+	case EventHandshakeComplete:
+		return "handshake_complete"
+	case EventHandshakeFailed:
+		return "handshake_failed"
+	case EventClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+type Event struct {
+	Type EventType
+	Time time.Time
+	Err  error
+	// Detail is a human readable note, e.g. the remote address.
+	Detail string
+}
+
+const eventBuffer = 16
+
+// Events reports the connection lifecycle. Events are dropped if nobody is
+// reading, so it never slows the connection down.
+func (s *ServerSDK) Events() <-chan Event {
+	return s.eventsCh
+}
+
+func (s *ServerSDK) emitEvent(eventType EventType, err error, detail string) {
+	select {
+	case s.eventsCh <- Event{Type: eventType, Time: s.now(), Err: err, Detail: detail}:
+	default:
+	}
+}
@@ -0,0 +1,102 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventsFollowLifecycle(t *testing.T) {
+	errHandshake := errors.New("handshake rejected")
+
+	tests := []struct {
+		name      string
+		handshake error
+		want      []EventType
+	}{
+		{
+			name: "handshake completes",
+			want: []EventType{EventConnected, EventReconnecting, EventReconnected, EventHandshakeComplete, EventClosed},
+		},
+		{
+			name:      "handshake fails",
+			handshake: errHandshake,
+			want:      []EventType{EventConnected, EventReconnecting, EventReconnected, EventHandshakeFailed, EventClosed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Hangs up on the first connection to force a reconnect.
+			var served atomic.Bool
+			address := listen(t, func(conn net.Conn) {
+				if served.Swap(true) {
+					io.Copy(io.Discard, conn)
+				}
+			})
+
+			sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, time.Second,
+				WithReconnect(ReconnectPolicy{InitialDelay: time.Millisecond, Multiplier: 1, MaxAttempts: 5}),
+				WithHandshake(func(*ServerSDK) error { return tt.handshake }))
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []Event
+			for len(got) < len(tt.want) {
+				select {
+				case event := <-sdk.Events():
+					got = append(got, event)
+					if event.Type == EventHandshakeComplete || event.Type == EventHandshakeFailed {
+						sdk.CloseConnection()
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got events %v, want %v", got, tt.want)
+				}
+			}
+
+			for i, event := range got {
+				if event.Type != tt.want[i] {
+					t.Fatalf("event %d is %v, want %v (all: %v)", i, event.Type, tt.want[i], got)
+				}
+				if event.Time.IsZero() {
+					t.Fatalf("event %v has no time", event.Type)
+				}
+			}
+			if failed := got[3]; tt.handshake != nil && !errors.Is(failed.Err, tt.handshake) {
+				t.Fatalf("%v carries %v, want %v", failed.Type, failed.Err, tt.handshake)
+			}
+			if got[0].Detail != address || got[2].Detail != address {
+				t.Fatalf("connect details %q and %q, want %s", got[0].Detail, got[2].Detail, address)
+			}
+		})
+	}
+}
+
+func TestEventsDroppedWithoutConsumer(t *testing.T) {
+	address := listen(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+	sdk := openSDK(t, address)
+
+	// Nobody reads Events: emitting past the buffer must not block.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 2 * eventBuffer {
+			sdk.emitEvent(EventConnected, nil, "")
+		}
+		sdk.CloseConnection()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emitting events blocked without a consumer")
+	}
+	if got := len(sdk.Events()); got != eventBuffer {
+		t.Fatalf("%d events buffered, want the first %d", got, eventBuffer)
+	}
+}
@@ -0,0 +1,78 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// refusedAddress returns a loopback address nothing listens on.
+func refusedAddress(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	ln.Close()
+	return address
+}
+
+func TestOpenConnectionFailsOver(t *testing.T) {
+	serve := func(conn net.Conn) { io.Copy(io.Discard, conn) }
+
+	tests := []struct {
+		name      string
+		addresses func(t *testing.T) []string
+		opts      []Option
+		// want is the index of the address that should be connected to.
+		want int
+	}{
+		{name: "first refuses, second accepts", addresses: func(t *testing.T) []string {
+			return []string{refusedAddress(t), listen(t, serve)}
+		}, want: 1},
+		{name: "first accepts", addresses: func(t *testing.T) []string {
+			return []string{listen(t, serve), listen(t, serve)}
+		}, want: 0},
+		{name: "only the last accepts", addresses: func(t *testing.T) []string {
+			return []string{refusedAddress(t), refusedAddress(t), listen(t, serve)}
+		}, want: 2},
+		{name: "shuffled, one accepts", addresses: func(t *testing.T) []string {
+			return []string{refusedAddress(t), listen(t, serve), refusedAddress(t)}
+		}, opts: []Option{WithShuffledAddresses()}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addresses := tt.addresses(t)
+			sdk := openSDK(t, addresses[0], append(tt.opts, WithFailoverAddresses(addresses[1:]...))...)
+			if got := sdk.RemoteAddr().String(); got != addresses[tt.want] {
+				t.Fatalf("connected to %s, want %s", got, addresses[tt.want])
+			}
+		})
+	}
+}
+
+func TestOpenConnectionAllAddressesRefuse(t *testing.T) {
+	addresses := []string{refusedAddress(t), refusedAddress(t)}
+	sdk := NewServerSDK(context.Background(), addresses[0], testMaxMessageSize, time.Second,
+		WithFailoverAddresses(addresses[1]))
+
+	err := sdk.OpenConnection()
+	if !errors.Is(err, ErrConnectionFailed) {
+		t.Fatalf("OpenConnection = %v, want ErrConnectionFailed", err)
+	}
+	for _, address := range addresses {
+		if !strings.Contains(err.Error(), address) {
+			t.Fatalf("error %q does not mention %s", err, address)
+		}
+	}
+	if sdk.RemoteAddr() != nil {
+		t.Fatalf("remote address %v after every dial failed", sdk.RemoteAddr())
+	}
+}
@@ -0,0 +1,138 @@
+package server_sdk
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Clock = (*FakeClock)(nil)
+
+// FakeClock is a Clock that only moves when Advance is called, so timeouts
+// can be driven without sleeping.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.schedule(d)
+	return t
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1), fn: f}
+	t.schedule(d)
+	return t
+}
+
+// Advance moves the clock forward by d and fires every timer due by then.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.active = false
+		t.fire()
+	}
+	c.timers = pending
+}
+
+// Timers reports how many timers are waiting to fire. It lets a caller
+// wait until the code under test has armed its timer before advancing.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+	active   bool
+	// fn is called instead of sending on ch for AfterFunc timers.
+	fn func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.unschedule()
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.unschedule()
+	t.schedule(d)
+	return wasActive
+}
+
+// schedule and unschedule expect the clock mutex to be held.
+func (t *fakeTimer) schedule(d time.Duration) {
+	t.deadline = t.clock.now.Add(d)
+	if d <= 0 {
+		t.fire()
+		return
+	}
+	t.active = true
+	t.clock.timers = append(t.clock.timers, t)
+}
+
+func (t *fakeTimer) unschedule() bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (t *fakeTimer) fire() {
+	if t.fn != nil {
+		go t.fn()
+		return
+	}
+	select {
+	case t.ch <- t.clock.now:
+	default:
+	}
+}
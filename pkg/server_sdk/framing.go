@@ -0,0 +1,71 @@
+package server_sdk
+
+import (
+	"io"
+	"wordofwisdom/pkg/protocol"
+)
+
+// framedReader assembles complete protocol frames from a byte stream,
+// regardless of how the underlying reads are split or coalesced. Frames
+// larger than maxFrameSize are rejected before anything is allocated.
+// A read error mid-frame keeps what was read so far, so the next call
+// resumes the same frame after e.g. a read deadline.
+type framedReader struct {
+	r    io.Reader
+	buff []byte
+	// n is how much of the current frame is buffered.
+	n int
+}
+
+func newFramedReader(r io.Reader, maxFrameSize int) *framedReader {
+	bufferSize := max(maxFrameSize, protocol.HeaderSize)
+
+	return &framedReader{
+		r:    r,
+		buff: make([]byte, bufferSize),
+	}
+}
+
+// ReadFrame returns the next full frame. The returned slice is only valid
+// until the next call.
+func (fr *framedReader) ReadFrame() ([]byte, error) {
+	if err := fr.fill(protocol.HeaderSize); err != nil {
+		return nil, err
+	}
+
+	frameSize, err := protocol.FrameSize(fr.buff[:protocol.HeaderSize])
+	if err != nil {
+		return nil, err
+	}
+
+	if frameSize > len(fr.buff) {
+		return nil, protocol.ErrMessageTooLarge
+	}
+
+	if err := fr.fill(frameSize); err != nil {
+		return nil, err
+	}
+
+	fr.n = 0
+	return fr.buff[:frameSize], nil
+}
+
+// fill reads until size bytes of the current frame are buffered. Like
+// io.ReadFull it reports io.ErrUnexpectedEOF for a frame cut short.
+func (fr *framedReader) fill(size int) error {
+	for fr.n < size {
+		read, err := fr.r.Read(fr.buff[fr.n:size])
+		fr.n += read
+		if fr.n >= size {
+			return nil
+		}
+		if err == io.EOF && fr.n > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package server_sdk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestFramedReaderRejectsOtherVersion(t *testing.T) {
+	frame, err := protocol.BuildRawMessage(true, 1, protocol.StringEncoder("later layout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame[0] = protocol.CurrentVersion + 1
+
+	tests := []struct {
+		name         string
+		maxFrameSize int
+	}{
+		{name: "frame fits", maxFrameSize: len(frame)},
+		// The declared size must not mask the version.
+		{name: "frame over the limit", maxFrameSize: protocol.HeaderSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := newFramedReader(bytes.NewReader(frame), tt.maxFrameSize)
+			if _, err := fr.ReadFrame(); !errors.Is(err, protocol.ErrUnsupportedVersion) {
+				t.Fatalf("got %v, want ErrUnsupportedVersion", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package server_sdk
+
+import (
+	"errors"
+	"log/slog"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+)
+
+// runHeartbeat pings the server every heartbeatInterval and closes the
+// connection if a pong does not arrive within heartbeatTimeout.
+func (s *ServerSDK) runHeartbeat() {
+	ticker := s.clock.NewTimer(s.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.connCloseCh:
+			return
+		case <-ticker.C():
+		}
+		ticker.Reset(s.heartbeatInterval)
+
+		sentAt := s.now()
+		if err := s.sendMessage(s.ctx, true, protocol.OpcodePing, nil); err != nil {
+			continue
+		}
+
+		timeout := s.clock.NewTimer(s.heartbeatTimeout)
+		select {
+		case <-s.ctx.Done():
+			timeout.Stop()
+			return
+		case <-s.connCloseCh:
+			timeout.Stop()
+			return
+		case <-s.pongCh:
+			timeout.Stop()
+			s.stats.recordRTT(s.since(sentAt))
+		case <-timeout.C():
+			conn := s.getConn()
+			s.logger.Warn("No heartbeat response from server, closing connection",
+				slog.String("remote_addr", conn.RemoteAddr().String()),
+				slog.Duration("timeout", s.heartbeatTimeout),
+			)
+			s.shutdown(ErrHeartbeatTimeout)
+			conn.Close()
+			return
+		}
+	}
+}
+
+// handleControlFrame consumes heartbeat frames so they never reach PopMessage.
+func (s *ServerSDK) handleControlFrame(frame []byte) bool {
+	opcode, err := protocol.PeekOpcode(frame)
+	if err != nil || opcode != protocol.OpcodePong {
+		return false
+	}
+
+	select {
+	case s.pongCh <- struct{}{}:
+	default:
+	}
+
+	return true
+}
@@ -0,0 +1,85 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+// pongServer answers the first pongs pings, then keeps reading without
+// answering, like a peer that went away. A negative pongs answers forever.
+func pongServer(t *testing.T, pongs int) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		for answered := 0; ; {
+			msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+			if err != nil {
+				return
+			}
+			if msg.Opcode != protocol.OpcodePing || (pongs >= 0 && answered >= pongs) {
+				continue
+			}
+			writeFrame(t, conn, true, protocol.OpcodePong, nil)
+			answered++
+		}
+	}
+}
+
+func TestHeartbeatClosesUnresponsiveConnection(t *testing.T) {
+	tests := []struct {
+		name  string
+		pongs int
+		err   error
+	}{
+		{name: "server never answers", pongs: 0, err: ErrHeartbeatTimeout},
+		{name: "server stops answering", pongs: 3, err: ErrHeartbeatTimeout},
+		{name: "server keeps answering", pongs: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := openSDK(t, listen(t, pongServer(t, tt.pongs)), WithHeartbeat(10*time.Millisecond, 100*time.Millisecond))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			err := sdk.WaitForCloseContext(ctx)
+			if tt.err == nil {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("connection closed despite pongs: %v", err)
+				}
+				if sdk.Stats().RTT <= 0 {
+					t.Fatal("no heartbeat round trip recorded")
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestHeartbeatOffByDefault(t *testing.T) {
+	pings := make(chan struct{}, 1)
+	sdk := openSDK(t, listen(t, func(conn net.Conn) {
+		for {
+			msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+			if err != nil {
+				return
+			}
+			if msg.Opcode == protocol.OpcodePing {
+				pings <- struct{}{}
+			}
+		}
+	}))
+
+	select {
+	case <-pings:
+		t.Fatal("ping sent without WithHeartbeat")
+	case <-sdk.Done():
+		t.Fatalf("connection closed: %v", sdk.Err())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
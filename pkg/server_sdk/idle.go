@@ -0,0 +1,50 @@
+package server_sdk
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrIdleTimeout = errors.New("idle timeout")
+)
+
+// touch records traffic for the idle timeout. Heartbeats do not count.
+func (s *ServerSDK) touch() {
+	s.lastActivity.Store(s.now().UnixNano())
+}
+
+// runIdleWatch closes the connection once nothing but heartbeats has been
+// sent or received for idleTimeout.
+func (s *ServerSDK) runIdleWatch() {
+	s.touch()
+
+	timer := s.clock.NewTimer(s.idleTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.connCloseCh:
+			return
+		case <-timer.C():
+		}
+
+		idle := s.since(time.Unix(0, s.lastActivity.Load()))
+		if idle < s.idleTimeout {
+			timer.Reset(s.idleTimeout - idle)
+			continue
+		}
+
+		conn := s.getConn()
+		s.logger.Info("Connection idle, closing",
+			slog.String("remote_addr", conn.RemoteAddr().String()),
+			slog.Duration("timeout", s.idleTimeout),
+		)
+		s.shutdown(ErrIdleTimeout)
+		conn.Close()
+		return
+	}
+}
@@ -0,0 +1,64 @@
+package server_sdk
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestIdleTimeoutClosesConnection(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// active is how long the client keeps sending before going quiet.
+		active time.Duration
+	}{
+		{name: "idle from the start"},
+		{name: "idle after traffic", active: 3 * timeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Echoes every frame back.
+			sdk := openSDK(t, listen(t, func(conn net.Conn) {
+				for {
+					msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+					if err != nil {
+						return
+					}
+					conn.Write(msg.Frame)
+				}
+			}), WithIdleTimeout(timeout))
+
+			start := time.Now()
+			for time.Since(start) < tt.active {
+				if err := sdk.SendMessage(true, 7, protocol.StringEncoder("keepalive")); err != nil {
+					t.Fatalf("connection closed while active: %v", err)
+				}
+				if _, err := sdk.PopMessage(); err != nil {
+					t.Fatalf("connection closed while active: %v", err)
+				}
+				time.Sleep(timeout / 4)
+			}
+
+			quiet := time.Now()
+			select {
+			case <-sdk.Done():
+			case <-time.After(10 * timeout):
+				t.Fatal("idle connection was never closed")
+			}
+			if idle := time.Since(quiet); idle < timeout/2 {
+				t.Fatalf("closed after %v of inactivity, want about %v", idle, timeout)
+			}
+			if !errors.Is(sdk.Err(), ErrIdleTimeout) {
+				t.Fatalf("Err() = %v, want ErrIdleTimeout", sdk.Err())
+			}
+			if err := sdk.SendMessage(true, 7, protocol.StringEncoder("late")); err == nil {
+				t.Fatal("send succeeded on the idled connection")
+			}
+		})
+	}
+}
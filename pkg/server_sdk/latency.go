@@ -0,0 +1,73 @@
+package server_sdk
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// solveSampleSize is how many solve times the reservoir keeps. Percentiles
+// are exact up to that many solves and estimated after.
+const solveSampleSize = 1024
+
+// LatencySummary describes the distribution of solve times. Count and Max
+// cover every solve; P50 and P95 come from a uniform sample of them.
+type LatencySummary struct {
+	Count uint64
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+// latencyReservoir keeps a uniform sample of durations using reservoir
+// sampling (Algorithm R).
+type latencyReservoir struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	count   uint64
+	max     time.Duration
+}
+
+func (r *latencyReservoir) record(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count++
+	r.max = max(r.max, d)
+	if len(r.samples) < solveSampleSize {
+		r.samples = append(r.samples, d)
+		return
+	}
+	if i := rand.Uint64N(r.count); i < solveSampleSize {
+		r.samples[i] = d
+	}
+}
+
+func (r *latencyReservoir) summary() LatencySummary {
+	r.mu.Lock()
+	sorted := slices.Clone(r.samples)
+	summary := LatencySummary{Count: r.count, Max: r.max}
+	r.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return summary
+	}
+	slices.Sort(sorted)
+	summary.P50 = percentile(sorted, 50)
+	summary.P95 = percentile(sorted, 95)
+	return summary
+}
+
+// percentile picks the nearest-rank value from sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (len(sorted)*p + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// SolveLatencies summarizes how long this client took to solve challenges,
+// counting successful solves only. Solve times well above what the server
+// allows for point at a difficulty too high for this machine.
+func (c *Client) SolveLatencies() LatencySummary {
+	return c.solveTimes.summary()
+}
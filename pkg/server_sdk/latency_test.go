@@ -0,0 +1,91 @@
+package server_sdk
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/server"
+)
+
+// millis returns n..m milliseconds in a fixed shuffled order.
+func millis(n, m int) []time.Duration {
+	durations := make([]time.Duration, 0, m-n+1)
+	for i := n; i <= m; i++ {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	rng.Shuffle(len(durations), func(i, j int) { durations[i], durations[j] = durations[j], durations[i] })
+	return durations
+}
+
+func TestLatencyReservoirSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		fed  []time.Duration
+		want LatencySummary
+	}{
+		{name: "no solves"},
+		{name: "one solve", fed: []time.Duration{7 * time.Millisecond}, want: LatencySummary{
+			Count: 1, P50: 7 * time.Millisecond, P95: 7 * time.Millisecond, Max: 7 * time.Millisecond,
+		}},
+		{name: "1 to 100ms", fed: millis(1, 100), want: LatencySummary{
+			Count: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, Max: 100 * time.Millisecond,
+		}},
+		{name: "1 to 20ms", fed: millis(1, 20), want: LatencySummary{
+			Count: 20, P50: 10 * time.Millisecond, P95: 19 * time.Millisecond, Max: 20 * time.Millisecond,
+		}},
+		{name: "exactly the sample size", fed: millis(1, solveSampleSize), want: LatencySummary{
+			Count: solveSampleSize, P50: 512 * time.Millisecond, P95: 973 * time.Millisecond, Max: solveSampleSize * time.Millisecond,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r latencyReservoir
+			for _, d := range tt.fed {
+				r.record(d)
+			}
+			if got := r.summary(); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyReservoirEstimatesPastSampleSize(t *testing.T) {
+	const solves = 4 * solveSampleSize
+	var r latencyReservoir
+	for _, d := range millis(1, solves) {
+		r.record(d)
+	}
+
+	got := r.summary()
+	if got.Count != solves || got.Max != solves*time.Millisecond {
+		t.Fatalf("count %d, max %v; want %d and %v", got.Count, got.Max, solves, solves*time.Millisecond)
+	}
+	// A uniform sample of 1024 puts the estimates within a few percent.
+	within := func(got time.Duration, want int) bool {
+		return got > time.Duration(want*90/100)*time.Millisecond && got < time.Duration(want*110/100)*time.Millisecond
+	}
+	if !within(got.P50, solves/2) || !within(got.P95, solves*95/100) {
+		t.Fatalf("p50 %v, p95 %v; want about %dms and %dms", got.P50, got.P95, solves/2, solves*95/100)
+	}
+}
+
+func TestClientRecordsSolveLatencies(t *testing.T) {
+	client := NewClient(startServer(t, server.Config{}))
+	for range 3 {
+		if _, err := client.GetWisdom(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := client.SolveLatencies()
+	if got.Count != 3 {
+		t.Fatalf("recorded %d solves, want 3", got.Count)
+	}
+	if got.Max <= 0 || got.P50 > got.P95 || got.P95 > got.Max {
+		t.Fatalf("inconsistent summary %+v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package server_sdk
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler drops every record; it is the default so the SDK stays
+// silent unless a logger is injected with WithLogger.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+func newNoopLogger() *slog.Logger {
+	return slog.New(discardHandler{})
+}
@@ -0,0 +1,213 @@
+package server_sdk
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+type Option func(*ServerSDK)
+
+// WithReconnect makes the SDK redial the server with backoff when the
+// connection is closed by the remote side.
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(s *ServerSDK) {
+		policy = policy.normalized()
+		s.reconnectPolicy = &policy
+	}
+}
+
+// WithHandshake registers a callback run after every successful reconnect,
+// e.g. to re-authenticate the new connection.
+func WithHandshake(handshake func(s *ServerSDK) error) Option {
+	return func(s *ServerSDK) {
+		s.handshake = handshake
+	}
+}
+
+// WithTLS dials the server over TLS. If the config has no ServerName it is
+// derived from the server address. Certificate chains are verified unless
+// the config sets InsecureSkipVerify.
+func WithTLS(config *tls.Config) Option {
+	return func(s *ServerSDK) {
+		s.tlsConfig = config
+	}
+}
+
+// WithDialTimeout bounds how long OpenConnection waits for the connection to
+// be established.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(s *ServerSDK) {
+		s.dialTimeout = timeout
+	}
+}
+
+// WithLogger routes SDK logs to the given logger. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *ServerSDK) {
+		s.logger = logger
+	}
+}
+
+// WithBufferPool reuses received frame buffers. Callers must hand every
+// popped message back with ReleaseMessage once they are done with it.
+func WithBufferPool() Option {
+	return func(s *ServerSDK) {
+		s.bufferPool = newBufferPool(s)
+	}
+}
+
+// WithHeartbeat pings the server every interval and closes the connection if
+// no pong arrives within timeout. Heartbeats are disabled by default.
+func WithHeartbeat(interval time.Duration, timeout time.Duration) Option {
+	return func(s *ServerSDK) {
+		s.heartbeatInterval = interval
+		s.heartbeatTimeout = timeout
+	}
+}
+
+// WithIdleTimeout closes the connection after d without messages sent or
+// received; heartbeats do not keep it open. Unlike the pop timeout it does
+// not depend on anyone waiting for a message.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *ServerSDK) {
+		s.idleTimeout = d
+	}
+}
+
+// WithExpectChallenge makes OpenConnection wait for the challenge the server
+// opens the exchange with and pass it to handler, typically to solve and
+// submit it. An error from the handler, or any other first message, fails
+// OpenConnection and closes the connection.
+func WithExpectChallenge(handler func(protocol.Challenge) error) Option {
+	return func(s *ServerSDK) {
+		s.challengeHandler = handler
+	}
+}
+
+// WithReceiveBuffer buffers up to n received messages that have not been
+// popped yet. By default the channel is unbuffered.
+func WithReceiveBuffer(n int) Option {
+	return func(s *ServerSDK) {
+		s.receiveBuffer = n
+	}
+}
+
+// WithOverflowPolicy decides what happens when the receive buffer is full.
+// The default is Block.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(s *ServerSDK) {
+		s.overflowPolicy = policy
+	}
+}
+
+// WithNetwork selects the network passed to the dialer, e.g. "unix" to
+// connect to a colocated server over a Unix domain socket, in which case the
+// server address is the socket path. Defaults to "tcp".
+func WithNetwork(network string) Option {
+	return func(s *ServerSDK) {
+		s.network = network
+	}
+}
+
+// WithFrameTap calls tap with a copy of every raw frame sent to or received
+// from the server, e.g. to hexdump wire traffic while debugging. The tap runs
+// on the sending or receiving goroutine and should not block.
+func WithFrameTap(tap FrameTap) Option {
+	return func(s *ServerSDK) {
+		s.frameTap = tap
+	}
+}
+
+// WithTransport replaces dialing the server address with t, e.g. a
+// PipeTransport in tests. Network, TLS and address settings are then unused.
+func WithTransport(t Transport) Option {
+	return func(s *ServerSDK) {
+		s.transport = t
+	}
+}
+
+// WithoutRawFrameValidation makes SendMessageRaw write frames without
+// checking that they parse, for relays that forward frames they do not
+// understand.
+func WithoutRawFrameValidation() Option {
+	return func(s *ServerSDK) {
+		s.skipRawValidation = true
+	}
+}
+
+// WithSyncMode stops OpenConnection from starting any background goroutine.
+// Messages are then read on the caller's goroutine with ReadMessage;
+// PopMessage, Request, heartbeats, the idle timeout and reconnects are
+// unavailable.
+func WithSyncMode() Option {
+	return func(s *ServerSDK) {
+		s.syncMode = true
+	}
+}
+
+// WithTracer records spans for OpenConnection, handshakes, solving and
+// Request on tracer, parented to the context passed to each call.
+func WithTracer(tracer Tracer) Option {
+	return func(s *ServerSDK) {
+		s.tracer = tracer
+	}
+}
+
+// WithFailoverAddresses adds server addresses to try, in order, when the
+// previous ones refuse the connection; reconnects go through the same list.
+// RemoteAddr tells which one is in use.
+func WithFailoverAddresses(addresses ...string) Option {
+	return func(s *ServerSDK) {
+		s.serverAddresses = append(s.serverAddresses, addresses...)
+	}
+}
+
+// WithShuffledAddresses tries the server addresses in a random order on
+// every dial, spreading clients over the servers.
+func WithShuffledAddresses() Option {
+	return func(s *ServerSDK) {
+		s.shuffleAddresses = true
+	}
+}
+
+// WithWriteBuffer coalesces sent frames in a buffer of size bytes, written
+// out when it fills, shortly after the first frame was buffered, or on
+// Flush. This saves syscalls for bursts of small messages at the cost of
+// latency, so it is off by default.
+func WithWriteBuffer(size int) Option {
+	return func(s *ServerSDK) {
+		s.writeBufferSize = size
+	}
+}
+
+// WithClock replaces the time source for the SDK's timeouts, heartbeats and
+// reconnect backoff. A nil clock keeps the real one.
+func WithClock(clock Clock) Option {
+	return func(s *ServerSDK) {
+		if clock != nil {
+			s.clock = clock
+		}
+	}
+}
+
+// WithMaxInFlight caps the number of Request calls waiting for a reply at
+// once. Requests over the cap fail with ErrTooManyInFlight, so a stalled
+// server cannot make the SDK pile up pending replies.
+func WithMaxInFlight(n int) Option {
+	return func(s *ServerSDK) {
+		s.maxInFlight = n
+	}
+}
+
+// WithCapture records every frame sent to or received from the server to w,
+// with its direction and time, for later use with the replay package. Writes
+// happen on the sending or receiving goroutine, so w should be fast, e.g.
+// a buffered file; capture stops after the first write error.
+func WithCapture(w io.Writer) Option {
+	return func(s *ServerSDK) {
+		s.capture = &capture{w: w}
+	}
+}
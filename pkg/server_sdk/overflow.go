@@ -0,0 +1,66 @@
+package server_sdk
+
+type OverflowPolicy int
+
+const (
+	// Block stops reading from the socket until the consumer catches up.
+	Block OverflowPolicy = iota
+	// DropNewest discards the incoming message when the buffer is full.
+	DropNewest
+	// DropOldest discards the oldest buffered message to make room.
+	DropOldest
+)
+
+// deliverMessage hands a message to PopMessage according to the overflow
+// policy. Drop policies only apply to a buffered channel; without
+// WithReceiveBuffer delivery always blocks, but never past the SDK context
+// or the connection closing, so the receive loop can exit with nobody
+// popping. It returns false in that case, after releasing the message.
+func (s *ServerSDK) deliverMessage(message []byte) bool {
+	s.inflight.Add(1)
+	defer s.inflight.Add(-1)
+
+	policy := s.overflowPolicy
+	if cap(s.messagesCh) == 0 {
+		policy = Block
+	}
+
+	switch policy {
+	case DropNewest:
+		select {
+		case s.messagesCh <- message:
+		default:
+			s.dropMessage(message)
+		}
+		return true
+	case DropOldest:
+		for {
+			select {
+			case s.messagesCh <- message:
+				return true
+			default:
+			}
+
+			select {
+			case oldest := <-s.messagesCh:
+				s.dropMessage(oldest)
+			default:
+			}
+		}
+	}
+
+	select {
+	case s.messagesCh <- message:
+		return true
+	case <-s.connCloseCh:
+	case <-s.ctx.Done():
+	}
+
+	s.ReleaseMessage(message)
+	return false
+}
+
+func (s *ServerSDK) dropMessage(message []byte) {
+	s.stats.messagesDropped.Add(1)
+	s.ReleaseMessage(message)
+}
@@ -0,0 +1,142 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOverflowPolicyWithSlowConsumer(t *testing.T) {
+	const (
+		flood  = 10
+		buffer = 3
+	)
+
+	tests := []struct {
+		name    string
+		policy  OverflowPolicy
+		settled func(sdk *ServerSDK) bool
+		want    []uint32
+		dropped uint64
+	}{
+		{
+			name:   "block",
+			policy: Block,
+			// The buffer is full and the receive loop waits with the next one.
+			settled: func(sdk *ServerSDK) bool { return sdk.undelivered() == buffer+1 },
+			want:    []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+		{
+			name:    "drop newest",
+			policy:  DropNewest,
+			settled: func(sdk *ServerSDK) bool { return sdk.Stats().MessagesDropped == flood-buffer },
+			want:    []uint32{1, 2, 3},
+			dropped: flood - buffer,
+		},
+		{
+			name:    "drop oldest",
+			policy:  DropOldest,
+			settled: func(sdk *ServerSDK) bool { return sdk.Stats().MessagesDropped == flood-buffer },
+			want:    []uint32{8, 9, 10},
+			dropped: flood - buffer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := listen(t, func(conn net.Conn) {
+				for i := range flood {
+					writeFrame(t, conn, true, uint32(i+1), nil)
+				}
+				conn.Read(make([]byte, 1))
+			})
+			sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, 100*time.Millisecond,
+				WithReceiveBuffer(buffer), WithOverflowPolicy(tt.policy))
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !tt.settled(sdk) {
+				if time.Now().After(deadline) {
+					t.Fatalf("flood never settled: %d undelivered, %+v", sdk.undelivered(), sdk.Stats())
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			var got []uint32
+			for {
+				msg, err := sdk.PopMessage()
+				if errors.Is(err, ErrPopMessageTimeout) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, msg.Opcode)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("popped %v, want %v", got, tt.want)
+			}
+			if dropped := sdk.Stats().MessagesDropped; dropped != tt.dropped {
+				t.Fatalf("dropped %d, want %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestBlockedDeliveryExitsWithoutConsumer(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(sdk *ServerSDK, cancel context.CancelFunc)
+	}{
+		{name: "ctx cancelled", stop: func(_ *ServerSDK, cancel context.CancelFunc) { cancel() }},
+		{name: "connection closed", stop: func(sdk *ServerSDK, _ context.CancelFunc) { sdk.CloseConnection() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := listen(t, func(conn net.Conn) {
+				writeFrame(t, conn, true, 1, nil)
+				conn.Read(make([]byte, 1))
+			})
+
+			// Sync mode starts no receive loop, so the test can run its own
+			// and see it return.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sdk := NewServerSDK(ctx, address, testMaxMessageSize, time.Minute, WithSyncMode())
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+			exited := make(chan struct{})
+			go func() {
+				sdk.startReceivingMessages()
+				close(exited)
+			}()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for sdk.inflight.Load() == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("message never reached delivery")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			tt.stop(sdk, cancel)
+			select {
+			case <-exited:
+			case <-time.After(time.Second):
+				t.Fatal("receive loop still blocked delivering a message nobody pops")
+			}
+			if n := sdk.undelivered(); n != 0 {
+				t.Fatalf("%d messages still undelivered after the loop exited", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPoolClosed = errors.New("pool closed")
+)
+
+// DefaultPoolMaxIdle is how many idle connections a Pool keeps when neither
+// MaxIdle nor MaxActive is set.
+const DefaultPoolMaxIdle = 2
+
+type PoolConfig struct {
+	// MaxIdle connections kept open between calls. Zero defaults to
+	// MaxActive, or DefaultPoolMaxIdle without a MaxActive; a negative
+	// value keeps none, closing every connection after its call.
+	MaxIdle int
+	// MaxActive bounds concurrent calls, and with them open connections;
+	// zero means no limit.
+	MaxActive int
+	// IdleTimeout closes connections unused for longer; zero keeps them.
+	IdleTimeout time.Duration
+	// Clock measures IdleTimeout; nil uses the wall clock.
+	Clock Clock
+}
+
+// Pool reuses connections across GetWisdom calls so each quote only costs a
+// challenge, not a new connection and negotiation.
+type Pool struct {
+	client *Client
+	cfg    PoolConfig
+
+	mu     sync.Mutex
+	idle   []*pooledConn
+	closed bool
+	// slots holds one token per call in progress when MaxActive is set.
+	slots chan struct{}
+}
+
+type pooledConn struct {
+	sdk      *ServerSDK
+	cancel   context.CancelFunc
+	lastUsed time.Time
+}
+
+func NewPool(client *Client, cfg PoolConfig) *Pool {
+	if cfg.MaxIdle == 0 {
+		cfg.MaxIdle = cfg.MaxActive
+		if cfg.MaxIdle == 0 {
+			cfg.MaxIdle = DefaultPoolMaxIdle
+		}
+	}
+
+	p := &Pool{
+		client: client,
+		cfg:    cfg,
+	}
+	if cfg.Clock == nil {
+		p.cfg.Clock = realClock{}
+	}
+	if cfg.MaxActive > 0 {
+		p.slots = make(chan struct{}, cfg.MaxActive)
+	}
+
+	return p
+}
+
+// GetWisdom fetches a quote over an idle connection, opening a new one if
+// none is available. Connections that fail are discarded.
+func (p *Pool) GetWisdom(ctx context.Context) (string, error) {
+	if err := p.acquireSlot(ctx); err != nil {
+		return "", err
+	}
+	defer p.releaseSlot()
+
+	conn := p.popIdle()
+	fresh := conn == nil
+	if fresh {
+		var err error
+		if conn, err = p.open(); err != nil {
+			return "", err
+		}
+	}
+
+	quote, _, err := p.client.fetchWisdom(ctx, conn.sdk, fresh)
+	if err != nil {
+		p.discard(conn)
+		return "", err
+	}
+
+	p.putIdle(conn)
+	return quote, nil
+}
+
+// Close closes all idle connections. Connections in use are closed when
+// they are returned.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, conn := range idle {
+		p.discard(conn)
+	}
+}
+
+func (p *Pool) open() (*pooledConn, error) {
+	// Pooled connections outlive the call that opened them.
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := p.client.cfg
+	sdk := NewServerSDK(ctx, cfg.ServerAddress, cfg.MaxMessageSizeBytes, cfg.PopMessageTimeout, cfg.Options...)
+	if err := sdk.OpenConnection(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &pooledConn{sdk: sdk, cancel: cancel}, nil
+}
+
+func (p *Pool) popIdle() *pooledConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.cfg.Clock.Now()
+	for len(p.idle) > 0 {
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+
+		if p.expired(conn, now) || conn.sdk.closed.Load() {
+			go p.discard(conn)
+			continue
+		}
+		return conn
+	}
+
+	return nil
+}
+
+func (p *Pool) putIdle(conn *pooledConn) {
+	conn.lastUsed = p.cfg.Clock.Now()
+
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.cfg.MaxIdle {
+		p.mu.Unlock()
+		p.discard(conn)
+		return
+	}
+
+	p.idle = append(p.idle, conn)
+	p.evictExpiredLocked(conn.lastUsed)
+	p.mu.Unlock()
+}
+
+func (p *Pool) evictExpiredLocked(now time.Time) {
+	kept := p.idle[:0]
+	for _, conn := range p.idle {
+		if p.expired(conn, now) {
+			go p.discard(conn)
+			continue
+		}
+		kept = append(kept, conn)
+	}
+	p.idle = kept
+}
+
+func (p *Pool) expired(conn *pooledConn, now time.Time) bool {
+	return p.cfg.IdleTimeout > 0 && now.Sub(conn.lastUsed) > p.cfg.IdleTimeout
+}
+
+func (p *Pool) discard(conn *pooledConn) {
+	conn.sdk.CloseConnection()
+	conn.cancel()
+}
+
+func (p *Pool) acquireSlot(ctx context.Context) error {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return ErrPoolClosed
+	}
+
+	if p.slots == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p.slots <- struct{}{}:
+		return nil
+	}
+}
+
+func (p *Pool) releaseSlot() {
+	if p.slots == nil {
+		return
+	}
+
+	select {
+	case <-p.slots:
+	default:
+	}
+}
@@ -0,0 +1,136 @@
+package server_sdk
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/server"
+)
+
+// countingTransport counts the connections dialled through it.
+type countingTransport struct {
+	Transport
+	dials *atomic.Int32
+}
+
+func (t countingTransport) Dial(ctx context.Context) (net.Conn, error) {
+	t.dials.Add(1)
+	return t.Transport.Dial(ctx)
+}
+
+// countDials makes cfg dial through a countingTransport.
+func countDials(cfg *ClientConfig) *atomic.Int32 {
+	dials := &atomic.Int32{}
+	for i, opt := range cfg.Options {
+		probe := &ServerSDK{}
+		opt(probe)
+		if probe.transport != nil {
+			cfg.Options[i] = WithTransport(countingTransport{Transport: probe.transport, dials: dials})
+		}
+	}
+	return dials
+}
+
+func (p *Pool) idleConns() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+func TestPoolKeepsIdleConnections(t *testing.T) {
+	const calls = 4
+
+	tests := []struct {
+		name  string
+		cfg   PoolConfig
+		idle  int
+		dials int32 // by the sequential calls
+	}{
+		{name: "zero value", cfg: PoolConfig{}, idle: DefaultPoolMaxIdle},
+		{name: "defaults to max active", cfg: PoolConfig{MaxActive: 3}, idle: 3},
+		{name: "max idle one", cfg: PoolConfig{MaxIdle: 1}, idle: 1},
+		{name: "no idle connections", cfg: PoolConfig{MaxIdle: -1}, dials: calls},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := startServer(t, server.Config{})
+			dials := countDials(&cfg)
+			pool := NewPool(NewClient(cfg), tt.cfg)
+			defer pool.Close()
+
+			// Concurrent calls open a connection each, as none is idle yet
+			// or MaxActive holds them back until one is returned.
+			var wg sync.WaitGroup
+			for range calls {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := pool.GetWisdom(context.Background()); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := pool.idleConns(); got != tt.idle {
+				t.Fatalf("%d idle connections after concurrent calls, want %d", got, tt.idle)
+			}
+
+			// Sequential calls reuse an idle connection when there is one.
+			before := dials.Load()
+			for range calls {
+				if _, err := pool.GetWisdom(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := dials.Load() - before; got != tt.dials {
+				t.Fatalf("sequential calls dialled %d times, want %d", got, tt.dials)
+			}
+		})
+	}
+}
+
+// BenchmarkPoolGetWisdom compares a quote over a pooled connection with a new
+// connection per call, over loopback TCP.
+func BenchmarkPoolGetWisdom(b *testing.B) {
+	quotes, err := server.NewSliceQuoteProvider([]string{testQuote})
+	if err != nil {
+		b.Fatal(err)
+	}
+	srv := server.NewServer(context.Background(), server.Config{Difficulty: 4, MaxMessageSizeBytes: testMaxMessageSize}, quotes)
+	defer srv.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	go srv.Serve(ln)
+
+	client := NewClient(ClientConfig{
+		ServerAddress:       ln.Addr().String(),
+		MaxMessageSizeBytes: testMaxMessageSize,
+		PopMessageTimeout:   5 * time.Second,
+	})
+	pool := NewPool(client, PoolConfig{})
+	defer pool.Close()
+
+	callers := []struct {
+		name      string
+		getWisdom func(ctx context.Context) (string, error)
+	}{
+		{name: "per-call", getWisdom: client.GetWisdom},
+		{name: "pooled", getWisdom: pool.GetWisdom},
+	}
+
+	for _, c := range callers {
+		b.Run(c.name, func(b *testing.B) {
+			for range b.N {
+				if _, err := c.getWisdom(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package server_sdk
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+)
+
+var (
+	ErrReconnectFailed = errors.New("reconnect failed")
+)
+
+// minReconnectDelay keeps a zero-valued policy from redialling a down
+// server in a tight loop.
+const minReconnectDelay = time.Millisecond
+
+// ReconnectPolicy backs off exponentially between attempts. WithReconnect
+// defaults a zero Multiplier to 2 and raises InitialDelay to at least
+// minReconnectDelay.
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// MaxAttempts of zero retries until the SDK context is cancelled.
+	MaxAttempts int
+}
+
+type ReconnectEvent struct {
+	Attempt int
+	Err     error
+}
+
+func (p ReconnectPolicy) normalized() ReconnectPolicy {
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	p.InitialDelay = max(p.InitialDelay, minReconnectDelay)
+	return p
+}
+
+// delay is the wait before attempt, never below minReconnectDelay even if
+// a Multiplier under one shrinks it.
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for range attempt - 1 {
+		delay *= p.Multiplier
+		if p.MaxDelay > 0 && time.Duration(delay) >= p.MaxDelay {
+			return max(p.MaxDelay, minReconnectDelay)
+		}
+	}
+
+	return max(time.Duration(delay), minReconnectDelay)
+}
+
+// ReconnectEvents reports every reconnect attempt. Events are dropped if
+// nobody is reading.
+func (s *ServerSDK) ReconnectEvents() <-chan ReconnectEvent {
+	return s.reconnectCh
+}
+
+func (s *ServerSDK) reconnect() (net.Conn, error) {
+	s.reconnecting.Store(true)
+	defer s.reconnecting.Store(false)
+
+	s.emitEvent(EventReconnecting, nil, s.addressList())
+
+	policy := s.reconnectPolicy
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		case <-s.clock.After(policy.delay(attempt)):
+		}
+
+		conn, err := s.dial()
+		s.emitReconnectEvent(ReconnectEvent{Attempt: attempt, Err: err})
+		if err != nil {
+			s.logger.Warn("Reconnect attempt failed",
+				slog.Int("attempt", attempt),
+				slog.String("remote_addr", s.addressList()),
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		s.setConn(conn)
+		s.stats.reconnects.Add(1)
+		s.logger.Info("Reconnected to server",
+			slog.Int("attempt", attempt),
+			slog.String("remote_addr", conn.RemoteAddr().String()),
+		)
+		s.emitEvent(EventReconnected, nil, conn.RemoteAddr().String())
+		if s.handshake != nil {
+			go func() {
+				if err := s.handshake(s); err != nil {
+					s.logger.Error("Handshake after reconnect failed",
+						slog.String("remote_addr", conn.RemoteAddr().String()),
+						slog.Any("error", err),
+					)
+					s.emitEvent(EventHandshakeFailed, err, conn.RemoteAddr().String())
+					return
+				}
+				s.emitEvent(EventHandshakeComplete, nil, conn.RemoteAddr().String())
+			}()
+		}
+
+		return conn, nil
+	}
+
+	return nil, ErrReconnectFailed
+}
+
+func (s *ServerSDK) emitReconnectEvent(event ReconnectEvent) {
+	select {
+	case s.reconnectCh <- event:
+	default:
+	}
+}
@@ -0,0 +1,56 @@
+package server_sdk
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ReconnectPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "zero-valued policy",
+			policy: ReconnectPolicy{},
+			want:   []time.Duration{minReconnectDelay, 2 * minReconnectDelay, 4 * minReconnectDelay},
+		},
+		{
+			name:   "zero multiplier",
+			policy: ReconnectPolicy{InitialDelay: 10 * time.Millisecond},
+			want:   []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: ReconnectPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond, Multiplier: 2},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond},
+		},
+		{
+			name:   "constant",
+			policy: ReconnectPolicy{InitialDelay: 5 * time.Millisecond, Multiplier: 1},
+			want:   []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond},
+		},
+		{
+			name:   "shrinking multiplier",
+			policy: ReconnectPolicy{InitialDelay: 4 * minReconnectDelay, Multiplier: 0.5},
+			want:   []time.Duration{4 * minReconnectDelay, 2 * minReconnectDelay, minReconnectDelay, minReconnectDelay},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := NewServerSDK(context.Background(), "127.0.0.1:1", testMaxMessageSize, time.Second, WithReconnect(tt.policy))
+
+			var got []time.Duration
+			for attempt := 1; attempt <= len(tt.want); attempt++ {
+				got = append(got, sdk.reconnectPolicy.delay(attempt))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("delays %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
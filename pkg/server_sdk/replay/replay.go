@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/server_sdk"
+)
+
+var (
+	ErrTruncatedRecord = errors.New("truncated capture record")
+	ErrRecordTooLarge  = errors.New("capture record too large")
+)
+
+// MaxFrameSize bounds the frame length a Reader accepts, so a corrupt
+// length does not allocate gigabytes.
+const MaxFrameSize = 16 << 20
+
+type Record struct {
+	Direction server_sdk.Direction
+	Time      time.Time
+	Frame     []byte
+}
+
+// Parse runs the recorded frame through protocol.ParseRawMessage.
+func (r Record) Parse() (*protocol.RawMessage, error) {
+	return protocol.ParseRawMessage(r.Frame)
+}
+
+// Reader reads the records written by server_sdk.WithCapture.
+type Reader struct {
+	r io.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next returns the next record, or io.EOF after the last complete one.
+func (r *Reader) Next() (Record, error) {
+	var header [server_sdk.CaptureHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, ErrTruncatedRecord
+		}
+		return Record{}, err
+	}
+
+	length := binary.BigEndian.Uint32(header[9:13])
+	if length > MaxFrameSize {
+		return Record{}, ErrRecordTooLarge
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r.r, frame); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, ErrTruncatedRecord
+		}
+		return Record{}, err
+	}
+
+	return Record{
+		Direction: server_sdk.Direction(header[0]),
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(header[1:9]))),
+		Frame:     frame,
+	}, nil
+}
+
+// Replay feeds a capture back through protocol.ParseRawMessage, to
+// reproduce parse bugs from real traffic. Every record in r is handed to fn
+// along with its parse result. Replay stops at the end of the capture, on a
+// read error, or when fn returns an error, which is passed through.
+func Replay(r io.Reader, fn func(record Record, msg *protocol.RawMessage, err error) error) error {
+	reader := NewReader(r)
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		msg, parseErr := record.Parse()
+		if err := fn(record, msg, parseErr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/server"
+	"wordofwisdom/pkg/server_sdk"
+)
+
+// lockedBuffer is a capture sink safe to read while the SDK may still write.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+type tappedFrame struct {
+	dir   server_sdk.Direction
+	frame []byte
+}
+
+func TestReplayMatchesLiveSession(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  server.Config
+	}{
+		{name: "plain"},
+		{name: "negotiated", cfg: server.Config{Algorithms: []protocol.Algorithm{protocol.AlgoSHA256}}},
+		{name: "with session token", cfg: server.Config{SessionTTL: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Difficulty = 4
+			cfg.MaxMessageSizeBytes = 1024
+			quotes, err := server.NewSliceQuoteProvider([]string{"captured quote"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := server.NewServer(context.Background(), cfg, quotes)
+			transport := server_sdk.NewPipeTransport()
+			go srv.Serve(transport)
+			defer srv.Close()
+
+			var (
+				mu      sync.Mutex
+				live    []tappedFrame
+				capture lockedBuffer
+			)
+			client := server_sdk.NewClient(server_sdk.ClientConfig{
+				ServerAddress:       "pipe",
+				MaxMessageSizeBytes: 1024,
+				PopMessageTimeout:   5 * time.Second,
+				Options: []server_sdk.Option{
+					server_sdk.WithTransport(transport),
+					server_sdk.WithCapture(&capture),
+					server_sdk.WithFrameTap(func(dir server_sdk.Direction, frame []byte) {
+						mu.Lock()
+						defer mu.Unlock()
+						live = append(live, tappedFrame{dir: dir, frame: frame})
+					}),
+				},
+			})
+			if _, err := client.GetWisdom(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var replayed []tappedFrame
+			err = Replay(bytes.NewReader(capture.Bytes()), func(record Record, msg *protocol.RawMessage, err error) error {
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(msg.Frame, record.Frame) {
+					t.Errorf("record %d parsed to a different frame", len(replayed))
+				}
+				replayed = append(replayed, tappedFrame{dir: record.Direction, frame: record.Frame})
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(replayed) != len(live) || len(live) == 0 {
+				t.Fatalf("replayed %d frames, saw %d live", len(replayed), len(live))
+			}
+			for i, want := range live {
+				got := replayed[i]
+				if got.dir != want.dir || !bytes.Equal(got.frame, want.frame) {
+					t.Fatalf("record %d: %s %x, want %s %x", i, got.dir, got.frame, want.dir, want.frame)
+				}
+				wantMsg, err := protocol.ParseRawMessage(want.frame)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gotMsg, _ := protocol.ParseRawMessage(got.frame)
+				if gotMsg.Opcode != wantMsg.Opcode || !bytes.Equal(gotMsg.Data, wantMsg.Data) {
+					t.Fatalf("record %d: opcode %d, want %d", i, gotMsg.Opcode, wantMsg.Opcode)
+				}
+			}
+		})
+	}
+}
+
+// record encodes one capture record the way WithCapture writes it.
+func record(dir server_sdk.Direction, unixNano int64, declared uint32, frame []byte) []byte {
+	header := make([]byte, server_sdk.CaptureHeaderSize)
+	header[0] = byte(dir)
+	binary.BigEndian.PutUint64(header[1:9], uint64(unixNano))
+	binary.BigEndian.PutUint32(header[9:13], declared)
+	return append(header, frame...)
+}
+
+func TestReaderNext(t *testing.T) {
+	frame, err := protocol.BuildRawMessage(true, 7, protocol.StringEncoder("replayed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole := record(server_sdk.DirectionReceived, 42, uint32(len(frame)), frame)
+
+	tests := []struct {
+		name    string
+		capture []byte
+		err     error
+	}{
+		{name: "whole record", capture: whole},
+		{name: "empty capture", err: io.EOF},
+		{name: "header cut short", capture: whole[:server_sdk.CaptureHeaderSize-1], err: ErrTruncatedRecord},
+		{name: "frame cut short", capture: whole[:len(whole)-1], err: ErrTruncatedRecord},
+		{name: "header only", capture: whole[:server_sdk.CaptureHeaderSize], err: ErrTruncatedRecord},
+		{name: "declared too large", capture: record(server_sdk.DirectionSent, 0, MaxFrameSize+1, nil), err: ErrRecordTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewReader(bytes.NewReader(tt.capture)).Next()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if got.Direction != server_sdk.DirectionReceived || !got.Time.Equal(time.Unix(0, 42)) || !bytes.Equal(got.Frame, frame) {
+				t.Fatalf("got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrTooManyInFlight = errors.New("too many requests in flight")
+)
+
+// Request sends a tagged message and waits for the reply carrying the same
+// request id. Replies to concurrent requests are routed to their callers and
+// never show up in PopMessage. With WithMaxInFlight set, a Request over the
+// limit fails with ErrTooManyInFlight without sending anything.
+func (s *ServerSDK) Request(ctx context.Context, opcode uint32, payload protocol.MessageEncoder) (*protocol.RawMessage, error) {
+	ctx, span := s.startSpan(ctx, SpanRequest, slog.Uint64("opcode", uint64(opcode)))
+	msg, err := s.request(ctx, opcode, payload)
+	span.end(err)
+	return msg, err
+}
+
+func (s *ServerSDK) request(ctx context.Context, opcode uint32, payload protocol.MessageEncoder) (*protocol.RawMessage, error) {
+	if s.syncMode {
+		return nil, ErrSyncMode
+	}
+	if s.closed.Load() {
+		return nil, ErrConnectionClosed
+	}
+
+	requestID, replyCh, err := s.registerRequest()
+	if err != nil {
+		return nil, err
+	}
+	defer s.unregisterRequest(requestID)
+
+	if err := s.sendMessage(ctx, true, opcode, payload, protocol.WithRequestID(requestID)); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case <-s.connCloseCh:
+		return nil, ErrConnectionClosed
+	case frame := <-replyCh:
+		return protocol.ParseRawMessage(frame)
+	}
+}
+
+func (s *ServerSDK) registerRequest() (uint32, chan []byte, error) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if s.maxInFlight > 0 && len(s.pending) >= s.maxInFlight {
+		return 0, nil, ErrTooManyInFlight
+	}
+
+	requestID := s.nextRequestID.Add(1)
+	// Zero is reserved for untagged messages.
+	for requestID == 0 || s.pending[requestID] != nil {
+		requestID = s.nextRequestID.Add(1)
+	}
+
+	replyCh := make(chan []byte, 1)
+	s.pending[requestID] = replyCh
+
+	return requestID, replyCh, nil
+}
+
+func (s *ServerSDK) unregisterRequest(requestID uint32) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	delete(s.pending, requestID)
+}
+
+// deliverReply routes a frame to the Request waiting for it, if any.
+func (s *ServerSDK) deliverReply(frame []byte) bool {
+	requestID, err := protocol.PeekRequestID(frame)
+	if err != nil || requestID == 0 {
+		return false
+	}
+
+	s.pendingMu.Lock()
+	replyCh, ok := s.pending[requestID]
+	if ok {
+		delete(s.pending, requestID)
+	}
+	s.pendingMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	replyCh <- frame
+	return true
+}
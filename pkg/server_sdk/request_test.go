@@ -0,0 +1,131 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+// stallingEcho holds every frame it reads until release is closed, then
+// echoes them and everything after, request id included.
+func stallingEcho(t *testing.T, release <-chan struct{}) string {
+	return listen(t, func(conn net.Conn) {
+		held := make(chan []byte, 64)
+		go func() {
+			<-release
+			for frame := range held {
+				conn.Write(frame)
+			}
+		}()
+		defer close(held)
+		for {
+			msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+			if err != nil {
+				return
+			}
+			held <- msg.Frame
+		}
+	})
+}
+
+func (s *ServerSDK) pendingRequests() int {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return len(s.pending)
+}
+
+// waitPending waits until n Request calls are registered and waiting.
+func waitPending(t *testing.T, sdk *ServerSDK, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sdk.pendingRequests() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests pending, want %d", sdk.pendingRequests(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRequestMaxInFlight(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxInFlight int
+		requests    int
+		refused     int
+	}{
+		{name: "over the limit", maxInFlight: 3, requests: 5, refused: 2},
+		{name: "at the limit", maxInFlight: 3, requests: 3},
+		{name: "no limit", requests: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			sdk := openSDK(t, stallingEcho(t, release), WithMaxInFlight(tt.maxInFlight))
+
+			waiting := tt.requests - tt.refused
+			var wg sync.WaitGroup
+			errs := make(chan error, waiting)
+			for range waiting {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := sdk.Request(context.Background(), 9, nil)
+					errs <- err
+				}()
+			}
+			waitPending(t, sdk, waiting)
+
+			for range tt.refused {
+				if _, err := sdk.Request(context.Background(), 9, nil); !errors.Is(err, ErrTooManyInFlight) {
+					t.Fatalf("got %v, want ErrTooManyInFlight", err)
+				}
+			}
+
+			close(release)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("request under the limit failed: %v", err)
+				}
+			}
+			if n := sdk.pendingRequests(); n != 0 {
+				t.Fatalf("%d pending entries left after every reply", n)
+			}
+		})
+	}
+}
+
+func TestRequestCancelFreesSlot(t *testing.T) {
+	release := make(chan struct{})
+	// The cancelled request's reply is routed to nobody and lands in
+	// PopMessage; the buffer keeps it from blocking the receive loop.
+	sdk := openSDK(t, stallingEcho(t, release), WithMaxInFlight(1), WithReceiveBuffer(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := sdk.Request(ctx, 9, nil)
+		cancelled <- err
+	}()
+	waitPending(t, sdk, 1)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := sdk.pendingRequests(); n != 0 {
+		t.Fatalf("%d pending entries left after cancelling", n)
+	}
+
+	close(release)
+	if _, err := sdk.Request(context.Background(), 9, nil); err != nil {
+		t.Fatalf("request after the cancelled one freed its slot: %v", err)
+	}
+}
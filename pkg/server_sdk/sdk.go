@@ -2,28 +2,102 @@ package server_sdk
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/responses"
 )
 
 type ServerSDK struct {
-	serverAddress       string
+	// serverAddresses are tried in order, or shuffled with
+	// shuffleAddresses, until one accepts.
+	serverAddresses     []string
+	shuffleAddresses    bool
 	maxMessageSizeBytes int
 	popMessageTimeout   time.Duration
 
-	ctx  context.Context
-	conn net.Conn
+	ctx    context.Context
+	conn   net.Conn
+	connMu sync.RWMutex
 
 	messagesCh  chan []byte
-	connCloseCh chan error
+	connCloseCh chan struct{}
 	errCh       chan error
+	reconnectCh chan ReconnectEvent
+	eventsCh    chan Event
 
-	closed atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+
+	doneCh        chan struct{}
+	doneOnce      sync.Once
+	doneErr       error
+	stopDoneWatch func() bool
+
+	popMu sync.Mutex
+	// writeMu keeps concurrent senders, heartbeats included, from
+	// interleaving frames or each other's write deadlines.
+	writeMu sync.Mutex
+
+	pending       map[uint32]chan []byte
+	pendingMu     sync.Mutex
+	maxInFlight   int
+	nextRequestID atomic.Uint32
+
+	reconnectPolicy *ReconnectPolicy
+	handshake       func(s *ServerSDK) error
+	tlsConfig       *tls.Config
+	dialTimeout     time.Duration
+	network         string
+	transport       Transport
+	frameTap        FrameTap
+	capture         *capture
+	tracer          Tracer
+	clock           Clock
+	logger          *slog.Logger
+	bufferPool      *sync.Pool
+
+	skipRawValidation bool
+	challengeHandler  func(protocol.Challenge) error
+
+	syncMode   bool
+	syncReader *framedReader
+
+	writeBufferSize int
+	writeBuf        *writeBuffer
+
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+	pongCh            chan struct{}
+
+	idleTimeout  time.Duration
+	lastActivity atomic.Int64
+
+	readDeadline  atomic.Int64
+	writeDeadline atomic.Int64
+
+	stats statsCounters
+
+	receiveBuffer  int
+	overflowPolicy OverflowPolicy
+
+	closed       atomic.Bool
+	reconnecting atomic.Bool
+	shuttingDown atomic.Bool
+	inflight     atomic.Int32
+	drained      atomic.Int32
 }
 
 func NewServerSDK(
@@ -31,45 +105,283 @@ func NewServerSDK(
 	address string,
 	maxMessageSizeBytes int,
 	popMessageTimeout time.Duration,
+	opts ...Option,
 ) *ServerSDK {
-	return &ServerSDK{
-		serverAddress:       address,
+	s := &ServerSDK{
+		serverAddresses:     []string{address},
 		ctx:                 ctx,
 		maxMessageSizeBytes: maxMessageSizeBytes,
 		popMessageTimeout:   popMessageTimeout,
-		messagesCh:          make(chan []byte),
-		connCloseCh:         make(chan error),
+		connCloseCh:         make(chan struct{}),
+		doneCh:              make(chan struct{}),
 		errCh:               make(chan error),
+		reconnectCh:         make(chan ReconnectEvent, 16),
+		eventsCh:            make(chan Event, eventBuffer),
+		logger:              newNoopLogger(),
+		tracer:              noopTracer{},
+		clock:               realClock{},
+		pending:             make(map[uint32]chan []byte),
+		pongCh:              make(chan struct{}, 1),
+		network:             "tcp",
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.writeBufferSize > 0 {
+		s.writeBuf = newWriteBuffer(s.writeBufferSize, s.clock, s.flushFailed)
+	}
+	s.messagesCh = make(chan []byte, s.receiveBuffer)
+	s.watchDone()
+
+	return s
 }
 
 var (
 	ErrConnectionClosed     = errors.New("connection closed")
 	ErrConnectionFailed     = errors.New("connection failed")
+	ErrConnectionTimeout    = errors.New("connection timeout")
 	ErrMessageTooShort      = errors.New("message is too short")
 	ErrFailedToWaitMessage  = errors.New("failed to wait message")
 	ErrFailedToSendMessage  = errors.New("failed to send message")
 	ErrFailedToBuildMessage = errors.New("failed to build message")
 	ErrPopMessageTimeout    = errors.New("pop message timeout")
+	ErrShuttingDown         = errors.New("sdk is shutting down")
+	ErrWriteTimeout         = errors.New("write timeout")
+	ErrInvalidRawFrame      = errors.New("invalid raw frame")
+	ErrInvalidAddress       = errors.New("invalid server address")
 )
 
+// OpenConnection dials the server and starts receiving messages. If the SDK
+// context is cancelled while dialing it returns the context error and no
+// background goroutines are started.
 func (s *ServerSDK) OpenConnection() error {
-	conn, err := net.Dial("tcp", s.serverAddress)
+	_, span := s.startSpan(s.ctx, SpanOpenConnection, slog.String("remote_addr", s.addressList()))
+	err := s.openConnection()
+	span.end(err)
+	return err
+}
+
+func (s *ServerSDK) openConnection() error {
+	if s.transport == nil {
+		for _, address := range s.serverAddresses {
+			if err := validateAddress(s.network, address); err != nil {
+				return err
+			}
+		}
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.setConn(conn)
+	s.emitEvent(EventConnected, nil, conn.RemoteAddr().String())
+
+	if s.syncMode {
+		s.syncReader = newFramedReader(conn, s.maxMessageSizeBytes)
+	} else {
+		go s.startReceivingMessages()
+		if s.heartbeatInterval > 0 {
+			go s.runHeartbeat()
+		}
+		if s.idleTimeout > 0 {
+			go s.runIdleWatch()
+		}
+	}
+
+	if s.challengeHandler != nil {
+		if err := s.receiveChallenge(); err != nil {
+			s.CloseConnection()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ServerSDK) receiveChallenge() error {
+	pop := s.PopMessage
+	if s.syncMode {
+		pop = s.ReadMessage
+	}
+
+	msg, err := pop()
 	if err != nil {
+		return err
+	}
+	if err := DecodeServerError(msg); err != nil {
+		return err
+	}
+	if msg.Opcode != responses.RES_CODE_POW_CHALLENGE {
+		return ErrUnexpectedResponse
+	}
+
+	challenge := protocol.Challenge{}
+	if err := msg.DecodePayload(&challenge); err != nil {
+		return err
+	}
+
+	return s.challengeHandler(challenge)
+}
+
+// dial connects to the first server address that accepts. If all of them
+// fail, the errors of every attempt are joined.
+func (s *ServerSDK) dial() (net.Conn, error) {
+	if s.transport != nil {
+		return s.dialAddress("")
+	}
+
+	addresses := s.serverAddresses
+	if s.shuffleAddresses {
+		addresses = slices.Clone(addresses)
+		rand.Shuffle(len(addresses), func(i, j int) {
+			addresses[i], addresses[j] = addresses[j], addresses[i]
+		})
+	}
+
+	var errs []error
+	for _, address := range addresses {
+		conn, err := s.dialAddress(address)
+		if err == nil {
+			return conn, nil
+		}
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if len(addresses) == 1 {
+			return nil, err
+		}
+		s.logger.Warn("Server address unreachable, trying the next one",
+			slog.String("remote_addr", address),
+			slog.Any("error", err),
+		)
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (s *ServerSDK) dialAddress(address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.transport != nil {
+		conn, err = s.dialTransport()
+	} else if s.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.clientTLSConfig(address)}
+		conn, err = tlsDialer.DialContext(s.ctx, s.network, address)
+	} else {
+		conn, err = dialer.DialContext(s.ctx, s.network, address)
+	}
+	if err != nil {
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		if errors.Is(err, net.ErrClosed) {
-			return ErrConnectionClosed
+			return nil, ErrConnectionClosed
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, errors.Join(err, ErrConnectionTimeout)
 		}
-		return errors.Join(err, ErrConnectionFailed)
+		return nil, errors.Join(err, ErrConnectionFailed)
 	}
-	s.conn = conn
 
-	go s.startReceivingMessages()
+	return conn, nil
+}
+
+// validateAddress rejects malformed host:port addresses before dialing so
+// they fail with ErrInvalidAddress rather than a dial error. IPv6 hosts must
+// be bracketed, e.g. [::1]:8080. Non-IP networks are not checked.
+func validateAddress(network, address string) error {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w %q: %w", ErrInvalidAddress, address, err)
+	}
+	if port == "" {
+		return fmt.Errorf("%w %q: missing port", ErrInvalidAddress, address)
+	}
+	if n, err := strconv.Atoi(port); err == nil && (n < 0 || n > 65535) {
+		return fmt.Errorf("%w %q: port out of range", ErrInvalidAddress, address)
+	}
 
 	return nil
 }
 
+func (s *ServerSDK) dialTransport() (net.Conn, error) {
+	ctx := s.ctx
+	if s.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(s.ctx, s.dialTimeout)
+		defer cancel()
+	}
+
+	return s.transport.Dial(ctx)
+}
+
+func (s *ServerSDK) clientTLSConfig(address string) *tls.Config {
+	config := s.tlsConfig.Clone()
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	return config
+}
+
+// addressList is the server addresses for logs.
+func (s *ServerSDK) addressList() string {
+	return strings.Join(s.serverAddresses, ",")
+}
+
+func (s *ServerSDK) getConn() net.Conn {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	return s.conn
+}
+
+// RemoteAddr returns the address of the server currently connected to, or
+// nil before OpenConnection. It follows reconnects.
+func (s *ServerSDK) RemoteAddr() net.Addr {
+	conn := s.getConn()
+	if conn == nil {
+		return nil
+	}
+	return conn.RemoteAddr()
+}
+
+// LocalAddr returns the local address of the current connection, or nil
+// before OpenConnection.
+func (s *ServerSDK) LocalAddr() net.Addr {
+	conn := s.getConn()
+	if conn == nil {
+		return nil
+	}
+	return conn.LocalAddr()
+}
+
+func (s *ServerSDK) setConn(conn net.Conn) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.conn = conn
+	if s.writeBuf != nil {
+		s.writeBuf.reset(conn)
+	}
+}
+
 func (s *ServerSDK) startReceivingMessages() {
-	messageBuff := make([]byte, s.maxMessageSizeBytes)
+	conn := s.getConn()
+	reader := newFramedReader(conn, s.maxMessageSizeBytes)
+	retryDelay := minReadRetryDelay
 
 	for {
 		select {
@@ -78,69 +390,383 @@ func (s *ServerSDK) startReceivingMessages() {
 		default:
 		}
 
-		bytesMessage, err := s.conn.Read(messageBuff)
+		// Re-arming after Shutdown's wake-up deadline would leave the read
+		// blocked for a whole poll interval.
+		if s.shuttingDown.Load() {
+			return
+		}
+		// The poll deadline only wakes the loop up to notice ctx being
+		// done; the partial frame is kept and reading resumes.
+		conn.SetReadDeadline(s.pollDeadline(time.Now()))
+		// Shutdown sets shuttingDown before its deadline, so if it is still
+		// unset here that deadline replaces ours.
+		if s.shuttingDown.Load() {
+			return
+		}
+		frame, err := reader.ReadFrame()
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				s.connCloseCh <- ErrConnectionClosed
-				s.errCh <- err
-
-				s.closed.Store(true)
-				close(s.connCloseCh)
-				close(s.errCh)
-				close(s.messagesCh)
+			// Closed on purpose, from either side of the SDK.
+			if s.shuttingDown.Load() || s.closed.Load() {
+				return
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				if s.readDeadlinePassed(time.Now()) && !s.deliverError(readDeadlineError()) {
+					return
+				}
+				continue
+			}
+			sdkErr := classifyReadError(err)
+			if sdkErr.Kind == ErrorKindProtocol {
+				s.logger.Error("Server declared a frame above the size limit, closing connection",
+					slog.String("remote_addr", conn.RemoteAddr().String()),
+					slog.Int("max_bytes", s.maxMessageSizeBytes),
+				)
+				s.shutdown(protocol.ErrMessageTooLarge)
+				conn.Close()
+				return
+			}
+			if sdkErr.Fatal {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					s.logger.Info("Connection closed by server",
+						slog.String("remote_addr", conn.RemoteAddr().String()),
+					)
+				} else {
+					s.logger.Error("Connection broken",
+						slog.String("remote_addr", conn.RemoteAddr().String()),
+						slog.Any("error", err),
+					)
+				}
+				if s.reconnectPolicy != nil {
+					if reconnected, err := s.reconnect(); err == nil {
+						conn = reconnected
+						reader = newFramedReader(conn, s.maxMessageSizeBytes)
+						continue
+					}
+				}
 
+				s.shutdown(errors.Join(ErrConnectionClosed, err))
+				return
+			}
+			s.logger.Error("Failed to read message from server",
+				slog.String("remote_addr", conn.RemoteAddr().String()),
+				slog.Any("error", err),
+			)
+			if !s.deliverError(sdkErr) {
 				return
 			}
-			s.errCh <- errors.Join(err, ErrFailedToWaitMessage)
+			if !s.waitReadRetry(retryDelay) {
+				return
+			}
+			retryDelay = min(retryDelay*2, maxReadRetryDelay)
 			continue
 		}
+		retryDelay = minReadRetryDelay
+
+		s.stats.recordReceived(len(frame))
+		s.tapFrame(DirectionReceived, frame)
+
+		opcode, _ := protocol.PeekOpcode(frame)
+		s.logger.Debug("Received message from server",
+			slog.Int("bytes", len(frame)),
+			slog.String("remote_addr", conn.RemoteAddr().String()),
+			slog.Uint64("opcode", uint64(opcode)),
+		)
+
+		if s.handleControlFrame(frame) {
+			continue
+		}
+		s.touch()
+
+		exact := s.acquireBuffer(len(frame))
+		copy(exact, frame)
+
+		if s.deliverReply(exact) {
+			continue
+		}
+		if !s.deliverMessage(exact) {
+			return
+		}
+	}
+}
+
+// shutdown marks the connection closed exactly once. Channels are never
+// closed for sending; consumers observe the closure through connCloseCh.
+func (s *ServerSDK) shutdown(cause error) {
+	s.closeOnce.Do(func() {
+		s.closeErr = cause
+		s.closed.Store(true)
+		close(s.connCloseCh)
+		s.stopDoneWatch()
+		s.finish(cause)
+		s.emitEvent(EventClosed, cause, "")
+	})
+}
+
+// markBroken closes a connection that can no longer be written to so that
+// later sends fail fast with ErrConnectionClosed. A reconnect in flight
+// replaces the connection instead.
+func (s *ServerSDK) markBroken(conn net.Conn) {
+	if s.reconnecting.Load() {
+		return
+	}
+
+	s.shutdown(ErrConnectionClosed)
+	conn.Close()
+}
 
-		log.Printf("Received message from server, %d bytes", bytesMessage)
+const (
+	readPollInterval  = time.Second
+	minReadRetryDelay = 10 * time.Millisecond
+	maxReadRetryDelay = time.Second
+)
 
-		exact := make([]byte, bytesMessage)
-		copy(exact, messageBuff[:bytesMessage])
+// waitReadRetry backs off before retrying a failed read, so a failing
+// socket does not spin the receive loop. It returns false if the SDK closed
+// meanwhile.
+func (s *ServerSDK) waitReadRetry(delay time.Duration) bool {
+	timer := s.clock.NewTimer(delay)
+	defer timer.Stop()
 
-		s.messagesCh <- exact
+	select {
+	case <-timer.C():
+		return true
+	case <-s.connCloseCh:
+		return false
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *ServerSDK) deliverError(err error) bool {
+	select {
+	case s.errCh <- err:
+		return true
+	case <-s.connCloseCh:
+		return false
+	case <-s.ctx.Done():
+		return false
 	}
 }
 
 func (s *ServerSDK) CloseConnection() error {
-	return s.conn.Close()
+	if s.writeBuf != nil && !s.closed.Load() {
+		s.writeBuf.Flush()
+	}
+	s.shutdown(ErrConnectionClosed)
+	return s.getConn().Close()
 }
 
 func (s *ServerSDK) WaitForClose() error {
-	return <-s.connCloseCh
+	return s.WaitForCloseContext(s.ctx)
+}
+
+// WaitForCloseContext waits for the connection to close and returns the
+// close cause, or ctx.Err() if ctx is done first.
+func (s *ServerSDK) WaitForCloseContext(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.connCloseCh:
+		return s.closeErr
+	}
 }
 
 func (s *ServerSDK) SendMessage(success bool, opcode uint32, payload protocol.MessageEncoder) error {
-	rawMessage, err := protocol.BuildRawMessage(success, opcode, payload)
+	return s.SendMessageContext(s.ctx, success, opcode, payload)
+}
+
+// SendMessageContext is like SendMessage but gives up writing once ctx is
+// done, returning ErrWriteTimeout.
+func (s *ServerSDK) SendMessageContext(ctx context.Context, success bool, opcode uint32, payload protocol.MessageEncoder) error {
+	return s.sendMessage(ctx, success, opcode, payload)
+}
+
+func (s *ServerSDK) sendMessage(ctx context.Context, success bool, opcode uint32, payload protocol.MessageEncoder, opts ...protocol.BuildOption) error {
+	if s.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if s.closed.Load() {
+		return ErrConnectionClosed
+	}
+
+	rawMessage, err := protocol.BuildRawMessage(success, opcode, payload, opts...)
 	if err != nil {
 		return errors.Join(err, ErrFailedToBuildMessage)
 	}
 
-	_, err = s.conn.Write(rawMessage)
+	return s.writeFrame(ctx, rawMessage)
+}
+
+// SendMessageRaw writes an already serialized frame as is, e.g. one
+// captured with a frame tap. The frame is checked to parse first unless the
+// SDK was created WithoutRawFrameValidation.
+func (s *ServerSDK) SendMessageRaw(frame []byte) error {
+	if s.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if s.closed.Load() {
+		return ErrConnectionClosed
+	}
+	if !s.skipRawValidation {
+		if _, err := protocol.ParseRawMessage(frame); err != nil {
+			return errors.Join(err, ErrInvalidRawFrame)
+		}
+	}
+
+	return s.writeFrame(s.ctx, frame)
+}
+
+func (s *ServerSDK) writeFrame(ctx context.Context, rawMessage []byte) error {
+	opcode, _ := protocol.PeekOpcode(rawMessage)
+
+	s.writeMu.Lock()
+	conn := s.getConn()
+	deadline := s.writeDeadlineFor(ctx.Deadline())
+	conn.SetWriteDeadline(deadline)
+	// Unblocks the write if ctx is cancelled before its deadline.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Now())
+	})
+	var written int
+	var err error
+	if s.writeBuf != nil {
+		written, err = s.writeBuf.Write(rawMessage)
+	} else {
+		written, err = conn.Write(rawMessage)
+	}
+	if stop() {
+		conn.SetWriteDeadline(nanosDeadline(s.writeDeadline.Load()))
+	}
+	s.writeMu.Unlock()
 	if err != nil {
+		// A timeout before anything was written leaves the stream intact.
+		if errors.Is(err, os.ErrDeadlineExceeded) && written == 0 {
+			return errors.Join(err, ErrWriteTimeout)
+		}
+		s.logger.Error("Failed to send message to server",
+			slog.String("remote_addr", conn.RemoteAddr().String()),
+			slog.Uint64("opcode", uint64(opcode)),
+			slog.Any("error", err),
+		)
+		s.markBroken(conn)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return errors.Join(err, ErrWriteTimeout)
+		}
 		return errors.Join(err, ErrFailedToSendMessage)
 	}
+	s.stats.recordSent(len(rawMessage))
+	s.tapFrame(DirectionSent, rawMessage)
+	if opcode != protocol.OpcodePing {
+		s.touch()
+	}
 
 	return nil
 }
 
+// PopMessage waits for the next message from the server. Concurrent callers
+// are served one at a time, in the order they acquire the internal lock;
+// once the connection is closed all of them return ErrConnectionClosed.
 func (s *ServerSDK) PopMessage() (*protocol.RawMessage, error) {
-	if s.closed.Load() {
-		return nil, ErrConnectionClosed
+	return s.PopMessageContext(s.ctx)
+}
+
+// PopMessageContext is like PopMessage but also gives up when ctx is done,
+// returning ctx.Err(). Whichever of the ctx deadline and the pop timeout
+// comes first applies.
+func (s *ServerSDK) PopMessageContext(ctx context.Context) (*protocol.RawMessage, error) {
+	if s.syncMode {
+		return nil, ErrSyncMode
+	}
+
+	s.popMu.Lock()
+	defer s.popMu.Unlock()
+
+	return s.popMessage(ctx)
+}
+
+// PopMessages waits for at least one message like PopMessage, then returns
+// it together with up to maxMessages-1 further messages that are already waiting,
+// without blocking for more.
+func (s *ServerSDK) PopMessages(maxMessages int) ([]*protocol.RawMessage, error) {
+	if s.syncMode {
+		return nil, ErrSyncMode
 	}
-	timeout := time.After(s.popMessageTimeout)
 
+	s.popMu.Lock()
+	defer s.popMu.Unlock()
+
+	first, err := s.popMessage(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*protocol.RawMessage{first}
+	for len(messages) < maxMessages {
+		select {
+		case message := <-s.messagesCh:
+			s.countDrained()
+			parsed, err := s.parseDelivered(message)
+			if err != nil {
+				return messages, err
+			}
+			messages = append(messages, parsed)
+		default:
+			return messages, nil
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *ServerSDK) popMessage(ctx context.Context) (*protocol.RawMessage, error) {
+	// Buffered messages are still delivered after the connection closes.
 	select {
-	case <-s.ctx.Done():
-		return nil, s.ctx.Err()
-	case <-timeout:
-		return nil, ErrPopMessageTimeout
 	case message := <-s.messagesCh:
-		return protocol.ParseRawMessage(message)
+		s.countDrained()
+		return s.parseDelivered(message)
+	default:
+	}
 
-	case err := <-s.errCh:
-		return nil, errors.Join(err, ErrFailedToWaitMessage)
+	if s.closed.Load() {
+		return nil, closedError()
+	}
+	timeout := s.clock.NewTimer(s.popMessageTimeout)
+	defer timeout.Stop()
+
+	// Retryable receive errors do not end the wait; the last one is
+	// reported if nothing arrives in time.
+	var lastErr error
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.connCloseCh:
+			return nil, closedError()
+		case <-timeout.C():
+			// A reconnect in flight is not the server being slow.
+			if s.reconnecting.Load() {
+				timeout.Reset(s.popMessageTimeout)
+				continue
+			}
+			if lastErr != nil {
+				return nil, errors.Join(lastErr, ErrPopMessageTimeout)
+			}
+			return nil, ErrPopMessageTimeout
+		case message := <-s.messagesCh:
+			s.countDrained()
+			return s.parseDelivered(message)
+
+		case err := <-s.errCh:
+			if errors.Is(err, ErrReadDeadline) {
+				return nil, err
+			}
+			if !IsFatal(err) {
+				lastErr = errors.Join(err, ErrFailedToWaitMessage)
+				continue
+			}
+			return nil, errors.Join(err, ErrFailedToWaitMessage)
+		}
 	}
 }
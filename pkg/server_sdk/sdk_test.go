@@ -0,0 +1,1160 @@
+package server_sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+// testMaxMessageSize is the message size limit of SDKs under test.
+const testMaxMessageSize = 1024
+
+// listen serves every accepted connection with serve until the test ends,
+// returning the listener address.
+func listen(t *testing.T, serve func(conn net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// writeFrame sends one frame to conn, failing the test on error.
+func writeFrame(t *testing.T, conn net.Conn, success bool, opcode uint32, payload protocol.MessageEncoder) {
+	t.Helper()
+
+	frame, err := protocol.BuildRawMessage(success, opcode, payload)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Errorf("write: %v", err)
+	}
+}
+
+// openSDK connects a new SDK to address and closes it when the test ends.
+func openSDK(t *testing.T, address string, opts ...Option) *ServerSDK {
+	t.Helper()
+
+	sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, 2*time.Second, opts...)
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { sdk.CloseConnection() })
+
+	return sdk
+}
+
+// pipeSDK connects a new SDK over a PipeTransport whose server side is
+// handled by serve. Pipe writes block until the other side reads.
+func pipeSDK(t *testing.T, serve func(conn net.Conn), opts ...Option) *ServerSDK {
+	t.Helper()
+
+	transport := NewPipeTransport()
+	t.Cleanup(func() { transport.Close() })
+	go func() {
+		conn, err := transport.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	return openSDK(t, "pipe", append(opts, WithTransport(transport))...)
+}
+
+// selfSignedCert returns a certificate for localhost and 127.0.0.1 and a
+// pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+func TestOpenConnectionOverTLS(t *testing.T) {
+	cert, roots := selfSignedCert(t)
+
+	sni := make(chan string, 1)
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			select {
+			case sni <- hello.ServerName:
+			default:
+			}
+			return nil, nil
+		},
+	}
+	address := listen(t, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, serverConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		writeFrame(t, tlsConn, true, 1, protocol.StringEncoder("over tls"))
+		tlsConn.Read(make([]byte, 1))
+	})
+	_, port, _ := net.SplitHostPort(address)
+
+	tests := []struct {
+		name    string
+		address string
+		config  *tls.Config
+		sni     string
+		err     error
+	}{
+		{name: "trusted", address: "localhost:" + port, config: &tls.Config{RootCAs: roots}, sni: "localhost"},
+		{name: "untrusted by default", address: address, config: &tls.Config{}, err: ErrConnectionFailed},
+		{name: "insecure skip verify", address: address, config: &tls.Config{InsecureSkipVerify: true}},
+		{name: "server name mismatch", address: address, config: &tls.Config{RootCAs: roots, ServerName: "example.com"}, err: ErrConnectionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for len(sni) > 0 {
+				<-sni
+			}
+
+			sdk := NewServerSDK(context.Background(), tt.address, testMaxMessageSize, 2*time.Second, WithTLS(tt.config))
+			err := sdk.OpenConnection()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("open: got %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			defer sdk.CloseConnection()
+
+			if tt.sni != "" {
+				if got := <-sni; got != tt.sni {
+					t.Fatalf("server saw SNI %q, want %q", got, tt.sni)
+				}
+			}
+			msg, err := sdk.PopMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if err := msg.DecodePayload(protocol.StringDecoder{Value: &got}); err != nil || got != "over tls" {
+				t.Fatalf("got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestOpenConnectionDialTimeout(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		address string
+		opts    []Option
+		err     error
+	}{
+		{
+			// 10.255.255.1 is not routed, so the SYN goes unanswered.
+			name:    "non-routable address",
+			ctx:     context.Background(),
+			address: "10.255.255.1:12345",
+			err:     ErrConnectionTimeout,
+		},
+		{
+			// A pipe transport nobody accepts from never completes the dial.
+			name: "server never accepts",
+			ctx:  context.Background(),
+			opts: []Option{WithTransport(NewPipeTransport())},
+			err:  ErrConnectionTimeout,
+		},
+		{
+			name:    "context canceled",
+			ctx:     canceled,
+			address: "10.255.255.1:12345",
+			err:     context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithDialTimeout(50 * time.Millisecond)}, tt.opts...)
+			sdk := NewServerSDK(tt.ctx, tt.address, testMaxMessageSize, time.Second, opts...)
+
+			start := time.Now()
+			err := sdk.OpenConnection()
+			if err == nil {
+				sdk.CloseConnection()
+				t.Skip("the sandbox network answered for a non-routable address")
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if errors.Is(err, ErrConnectionTimeout) && errors.Is(err, ErrConnectionFailed) {
+				t.Fatalf("timeout is also reported as a failure: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("dial took %v despite a 50ms timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestEOFRacesPopAndWaitForClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(conn net.Conn)
+	}{
+		{name: "server closes at once", serve: func(net.Conn) {}},
+		{name: "server closes after a message", serve: func(conn net.Conn) {
+			writeFrame(t, conn, true, 1, nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := listen(t, tt.serve)
+
+			for range 20 {
+				sdk := openSDK(t, address)
+
+				var wg sync.WaitGroup
+				for range 4 {
+					wg.Add(3)
+					go func() {
+						defer wg.Done()
+						for {
+							if _, err := sdk.PopMessage(); err != nil {
+								if !errors.Is(err, ErrConnectionClosed) {
+									t.Errorf("pop: %v", err)
+								}
+								return
+							}
+						}
+					}()
+					go func() {
+						defer wg.Done()
+						if err := sdk.WaitForClose(); !errors.Is(err, ErrConnectionClosed) {
+							t.Errorf("wait for close: %v", err)
+						}
+					}()
+					go func() {
+						defer wg.Done()
+						sdk.CloseConnection()
+					}()
+				}
+				wg.Wait()
+			}
+		})
+	}
+}
+
+func TestConcurrentPopMessageDuringTeardown(t *testing.T) {
+	const poppers = 50
+
+	tests := []struct {
+		name     string
+		teardown func(sdk *ServerSDK, serverConn net.Conn)
+	}{
+		{name: "client closes", teardown: func(sdk *ServerSDK, _ net.Conn) { sdk.CloseConnection() }},
+		{name: "server closes", teardown: func(_ *ServerSDK, serverConn net.Conn) { serverConn.Close() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted := make(chan net.Conn, 1)
+			release := make(chan struct{})
+			address := listen(t, func(conn net.Conn) {
+				accepted <- conn
+				<-release
+			})
+			defer close(release)
+
+			// A pop timeout longer than the test, so only the teardown can
+			// end the wait.
+			sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, time.Minute)
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+			serverConn := <-accepted
+
+			errs := make(chan error, poppers)
+			var started sync.WaitGroup
+			for range poppers {
+				started.Add(1)
+				go func() {
+					started.Done()
+					_, err := sdk.PopMessage()
+					errs <- err
+				}()
+			}
+			started.Wait()
+			tt.teardown(sdk, serverConn)
+
+			timeout := time.After(5 * time.Second)
+			for range poppers {
+				select {
+				case err := <-errs:
+					if !errors.Is(err, ErrConnectionClosed) {
+						t.Fatalf("pop: got %v, want ErrConnectionClosed", err)
+					}
+				case <-timeout:
+					t.Fatal("PopMessage still blocked after the connection closed")
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrentSendsKeepFramesWhole(t *testing.T) {
+	const (
+		senders = 16
+		frames  = 50
+	)
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "plain"},
+		{name: "with heartbeats", opts: []Option{WithHeartbeat(time.Millisecond, time.Minute)}},
+		{name: "write buffer", opts: []Option{WithWriteBuffer(512)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan error, 1)
+			address := listen(t, func(conn net.Conn) {
+				counts := make([]int, senders)
+				for total := 0; total < senders*frames; {
+					msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+					if err != nil {
+						received <- err
+						return
+					}
+					if msg.Opcode == protocol.OpcodePing {
+						continue
+					}
+					sender := int(msg.Opcode) - 100
+					payload, err := protocol.DecodeString(msg.Data)
+					if err != nil || sender < 0 || sender >= senders || payload != senderPayload(sender) {
+						received <- fmt.Errorf("garbled frame: opcode %d, payload %q, %v", msg.Opcode, payload, err)
+						return
+					}
+					counts[sender]++
+					total++
+				}
+				for sender, n := range counts {
+					if n != frames {
+						received <- fmt.Errorf("sender %d: got %d frames, want %d", sender, n, frames)
+						return
+					}
+				}
+				received <- nil
+			})
+			sdk := openSDK(t, address, append(tt.opts, WithTransport(choppyTransport{address: address}))...)
+
+			var wg sync.WaitGroup
+			for sender := range senders {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range frames {
+						if err := sdk.SendMessage(true, uint32(100+sender), protocol.StringEncoder(senderPayload(sender))); err != nil {
+							t.Errorf("sender %d: %v", sender, err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			sdk.Flush()
+
+			select {
+			case err := <-received:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not receive every frame")
+			}
+		})
+	}
+}
+
+// choppyConn writes a few bytes at a time and yields in between, so writes
+// that are not serialized interleave mid-frame.
+type choppyConn struct {
+	net.Conn
+}
+
+func (c choppyConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		chunk := b[:min(3, len(b))]
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+		runtime.Gosched()
+	}
+	return written, nil
+}
+
+type choppyTransport struct {
+	address string
+}
+
+func (t choppyTransport) Dial(ctx context.Context) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return nil, err
+	}
+	return choppyConn{Conn: conn}, nil
+}
+
+// senderPayload gives each sender a payload of its own length, so a frame
+// spliced from two senders cannot parse as either.
+func senderPayload(sender int) string {
+	return strings.Repeat(string(rune('a'+sender)), 10+sender*7)
+}
+
+func TestSendMessageContextWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(sdk *ServerSDK) error
+	}{
+		{name: "context deadline", send: func(sdk *ServerSDK) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			return sdk.SendMessageContext(ctx, true, 1, nil)
+		}},
+		{name: "context canceled", send: func(sdk *ServerSDK) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return sdk.SendMessageContext(ctx, true, 1, nil)
+		}},
+		{name: "write deadline", send: func(sdk *ServerSDK) error {
+			sdk.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+			return sdk.SendMessage(true, 1, nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+			// The server never reads, so the write cannot complete.
+			sdk := pipeSDK(t, func(net.Conn) { <-release })
+
+			start := time.Now()
+			if err := tt.send(sdk); !errors.Is(err, ErrWriteTimeout) {
+				t.Fatalf("got %v, want ErrWriteTimeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("send took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestReceiveClosesOnOversizedFrame(t *testing.T) {
+	address := listen(t, func(conn net.Conn) {
+		frame, err := protocol.BuildRawMessage(true, 1, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// Declares 1GB but never sends it.
+		binary.BigEndian.PutUint32(frame[10:14], 1<<30)
+		conn.Write(frame)
+		conn.Read(make([]byte, 1))
+	})
+	sdk := openSDK(t, address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sdk.WaitForCloseContext(ctx); !errors.Is(err, protocol.ErrMessageTooLarge) {
+		t.Fatalf("got %v, want ErrMessageTooLarge", err)
+	}
+	if _, err := sdk.PopMessage(); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("pop after close: got %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestOpenConnectionCanceledMidDial(t *testing.T) {
+	tests := []struct {
+		name    string
+		address func(t *testing.T) string
+		opts    func(t *testing.T) []Option
+	}{
+		{
+			// A pipe transport nobody accepts from.
+			name:    "never accepted",
+			address: func(*testing.T) string { return "pipe" },
+			opts: func(t *testing.T) []Option {
+				transport := NewPipeTransport()
+				t.Cleanup(func() { transport.Close() })
+				return []Option{WithTransport(transport)}
+			},
+		},
+		{
+			// Accepted by TCP, but the TLS handshake is never answered.
+			name: "handshake stalled",
+			address: func(t *testing.T) string {
+				return listen(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+			},
+			opts: func(*testing.T) []Option {
+				return []Option{WithTLS(&tls.Config{InsecureSkipVerify: true})}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			sdk := NewServerSDK(ctx, tt.address(t), testMaxMessageSize, time.Second, tt.opts(t)...)
+
+			start := time.Now()
+			if err := sdk.OpenConnection(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %v, want context.Canceled", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("cancellation took %v", elapsed)
+			}
+			if sdk.getConn() != nil {
+				t.Fatal("a connection was kept after the dial was cancelled")
+			}
+			if _, err := sdk.PopMessage(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("pop: got %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestAddrsFollowReconnect(t *testing.T) {
+	// The server sees each client by the SDK's local address; it hangs up
+	// on the first connection to force a reconnect.
+	clients := make(chan string, 2)
+	var served atomic.Bool
+	address := listen(t, func(conn net.Conn) {
+		clients <- conn.RemoteAddr().String()
+		if served.Swap(true) {
+			io.Copy(io.Discard, conn)
+		}
+	})
+
+	sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, time.Second,
+		WithReconnect(ReconnectPolicy{InitialDelay: time.Millisecond, Multiplier: 1, MaxAttempts: 5}))
+	if sdk.RemoteAddr() != nil || sdk.LocalAddr() != nil {
+		t.Fatal("addresses set before OpenConnection")
+	}
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseConnection()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if sdk.RemoteAddr().String() != address {
+					t.Errorf("remote address %v, want %s", sdk.RemoteAddr(), address)
+					return
+				}
+				sdk.LocalAddr()
+			}
+		}()
+	}
+
+	firstLocal := <-clients
+	select {
+	case event := <-sdk.ReconnectEvents():
+		if event.Err != nil {
+			t.Fatalf("reconnect: %v", event.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SDK did not reconnect")
+	}
+	secondLocal := <-clients
+	close(stop)
+	readers.Wait()
+
+	if got := sdk.LocalAddr().String(); got != secondLocal || got == firstLocal {
+		t.Fatalf("local address %s, want the reconnected %s (first was %s)", got, secondLocal, firstLocal)
+	}
+	if got := sdk.RemoteAddr().String(); got != address {
+		t.Fatalf("remote address %s, want %s", got, address)
+	}
+}
+
+func TestSendMessageAfterClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(t *testing.T, sdk *ServerSDK)
+	}{
+		{name: "closed by client", close: func(t *testing.T, sdk *ServerSDK) {
+			sdk.CloseConnection()
+		}},
+		{name: "closed by server", close: func(t *testing.T, sdk *ServerSDK) {
+			if err := sdk.WaitForClose(); !errors.Is(err, ErrConnectionClosed) {
+				t.Fatalf("wait for close: %v", err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hangUp := make(chan struct{})
+			sdk := openSDK(t, listen(t, func(net.Conn) { <-hangUp }))
+			close(hangUp)
+			tt.close(t, sdk)
+
+			for range 2 {
+				if err := sdk.SendMessage(true, 1, nil); !errors.Is(err, ErrConnectionClosed) {
+					t.Fatalf("got %v, want ErrConnectionClosed", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFailedWriteMarksConnectionClosed(t *testing.T) {
+	// The pipe is closed underneath the SDK, so only the write notices.
+	release := make(chan struct{})
+	defer close(release)
+	sdk := pipeSDK(t, func(net.Conn) { <-release })
+	sdk.getConn().Close()
+
+	err := sdk.SendMessage(true, 1, nil)
+	if !errors.Is(err, ErrFailedToSendMessage) && !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("first send: got %v", err)
+	}
+	if err := sdk.SendMessage(true, 1, nil); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("second send: got %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestOpenConnectionUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wisdom.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+		if err != nil {
+			return
+		}
+		writeFrame(t, conn, true, msg.Opcode+1, nil)
+		conn.Read(make([]byte, 1))
+	}()
+
+	tests := []struct {
+		name    string
+		address string
+		err     error
+	}{
+		{name: "listening socket", address: path},
+		{name: "missing socket", address: filepath.Join(t.TempDir(), "missing.sock"), err: ErrConnectionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := NewServerSDK(context.Background(), tt.address, testMaxMessageSize, 2*time.Second, WithNetwork("unix"))
+			err := sdk.OpenConnection()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("open: got %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			defer sdk.CloseConnection()
+
+			if got := sdk.RemoteAddr(); got.Network() != "unix" || got.String() != path {
+				t.Fatalf("remote address %s %s", got.Network(), got)
+			}
+			if err := sdk.SendMessage(true, 41, nil); err != nil {
+				t.Fatal(err)
+			}
+			msg, err := sdk.PopMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Opcode != 42 {
+				t.Fatalf("got opcode %d, want 42", msg.Opcode)
+			}
+		})
+	}
+}
+
+func TestPopMessageContextDeadlines(t *testing.T) {
+	tests := []struct {
+		name       string
+		sdkTimeout time.Duration
+		ctx        func() (context.Context, context.CancelFunc)
+		err        error
+	}{
+		{
+			name:       "context deadline first",
+			sdkTimeout: 5 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			err: context.DeadlineExceeded,
+		},
+		{
+			name:       "pop timeout first",
+			sdkTimeout: 50 * time.Millisecond,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 5*time.Second)
+			},
+			err: ErrPopMessageTimeout,
+		},
+		{
+			name:       "context without deadline",
+			sdkTimeout: 50 * time.Millisecond,
+			ctx:        func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			err:        ErrPopMessageTimeout,
+		},
+		{
+			name:       "context canceled",
+			sdkTimeout: 5 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			err: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			defer close(release)
+			// The server never sends anything.
+			address := listen(t, func(net.Conn) { <-release })
+			sdk := NewServerSDK(context.Background(), address, testMaxMessageSize, tt.sdkTimeout)
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			if _, err := sdk.PopMessageContext(ctx); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("pop returned after %v, the later of the two deadlines", elapsed)
+			}
+		})
+	}
+}
+
+func TestSendMessageRawReplaysTappedFrame(t *testing.T) {
+	tapped := make(chan []byte, 1)
+	source := openSDK(t, listen(t, func(conn net.Conn) { io.Copy(io.Discard, conn) }),
+		WithFrameTap(func(dir Direction, frame []byte) {
+			if dir == DirectionSent {
+				tapped <- bytes.Clone(frame)
+			}
+		}))
+	if err := source.SendMessage(true, 7, protocol.StringEncoder("relayed")); err != nil {
+		t.Fatal(err)
+	}
+	frame := <-tapped
+	badVersion := append([]byte{0xff}, frame[1:]...)
+
+	tests := []struct {
+		name  string
+		frame []byte
+		opts  []Option
+		err   error
+	}{
+		{name: "tapped frame", frame: frame},
+		{name: "unsupported version", frame: badVersion, err: ErrInvalidRawFrame},
+		{name: "truncated frame", frame: frame[:len(frame)-1], err: ErrInvalidRawFrame},
+		{name: "unvalidated", frame: badVersion, opts: []Option{WithoutRawFrameValidation()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Records exactly the bytes that were written, parseable or not.
+			received := make(chan []byte, 1)
+			relay := openSDK(t, listen(t, func(conn net.Conn) {
+				buf := make([]byte, len(tt.frame))
+				if _, err := io.ReadFull(conn, buf); err == nil {
+					received <- buf
+				}
+			}), tt.opts...)
+
+			err := relay.SendMessageRaw(tt.frame)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SendMessageRaw = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			select {
+			case got := <-received:
+				if !bytes.Equal(got, tt.frame) {
+					t.Fatalf("server got % x, want % x", got, tt.frame)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("replayed frame never arrived")
+			}
+		})
+	}
+}
+
+func TestReceiveLoopExitsOnCancelAgainstSilentServer(t *testing.T) {
+	tests := []struct {
+		name string
+		// sent is what the server writes before going silent.
+		sent []byte
+	}{
+		{name: "nothing sent"},
+		{name: "half a header", sent: []byte{protocol.CurrentVersion, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hungUp := make(chan struct{})
+			address := listen(t, func(conn net.Conn) {
+				conn.Write(tt.sent)
+				io.Copy(io.Discard, conn)
+				close(hungUp)
+			})
+
+			// Sync mode starts no receive loop, so the test can run its own
+			// and see it return.
+			ctx, cancel := context.WithCancel(context.Background())
+			sdk := NewServerSDK(ctx, address, testMaxMessageSize, time.Minute, WithSyncMode())
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+			exited := make(chan struct{})
+			go func() {
+				sdk.startReceivingMessages()
+				close(exited)
+			}()
+
+			// Let the loop block in Read before cancelling.
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			select {
+			case <-exited:
+			case <-time.After(readPollInterval + time.Second):
+				t.Fatal("receive loop still blocked after ctx was cancelled")
+			}
+			select {
+			case <-hungUp:
+				t.Fatal("connection was closed instead of the loop noticing ctx")
+			default:
+			}
+		})
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address string
+		valid   bool
+	}{
+		{name: "ipv4", network: "tcp", address: "127.0.0.1:8080", valid: true},
+		{name: "ipv6", network: "tcp", address: "[::1]:8080", valid: true},
+		{name: "ipv6 with zone", network: "tcp6", address: "[fe80::1%eth0]:8080", valid: true},
+		{name: "hostname", network: "tcp", address: "wisdom.example.com:8080", valid: true},
+		{name: "named port", network: "tcp", address: "localhost:http", valid: true},
+		{name: "empty host", network: "tcp", address: ":8080", valid: true},
+		{name: "missing port", network: "tcp", address: "127.0.0.1"},
+		{name: "empty port", network: "tcp", address: "127.0.0.1:"},
+		{name: "port out of range", network: "tcp", address: "127.0.0.1:65536"},
+		{name: "unbracketed ipv6", network: "tcp", address: "::1:8080"},
+		{name: "empty", network: "tcp", address: ""},
+		{name: "unix socket unchecked", network: "unix", address: "/tmp/wisdom.sock", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAddress(tt.network, tt.address)
+			if tt.valid && err != nil {
+				t.Fatalf("validateAddress(%q) = %v, want nil", tt.address, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidAddress) {
+				t.Fatalf("validateAddress(%q) = %v, want ErrInvalidAddress", tt.address, err)
+			}
+		})
+	}
+}
+
+func TestOpenConnectionIPv6Loopback(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			writeFrame(t, conn, true, 1, protocol.StringEncoder("over ipv6"))
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	sdk := openSDK(t, ln.Addr().String())
+	msg, err := sdk.PopMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := protocol.DecodeString(msg.Data); err != nil || got != "over ipv6" {
+		t.Fatalf("got %q (%v) over IPv6", got, err)
+	}
+}
+
+func TestOpenConnectionRejectsMalformedAddress(t *testing.T) {
+	sdk := NewServerSDK(context.Background(), "127.0.0.1", testMaxMessageSize, time.Second)
+	if err := sdk.OpenConnection(); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("OpenConnection = %v, want ErrInvalidAddress", err)
+	}
+}
+
+func TestOpenConnectionExpectChallenge(t *testing.T) {
+	errHandler := errors.New("handler refused")
+	challenge, err := protocol.NewChallenge(4, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// first is the frame the server opens with.
+		first      func(conn net.Conn)
+		handlerErr error
+		err        error
+	}{
+		{
+			name:  "handler solves and responds",
+			first: func(conn net.Conn) { writeFrame(t, conn, true, responses.RES_CODE_POW_CHALLENGE, challenge) },
+		},
+		{
+			name:       "handler fails",
+			first:      func(conn net.Conn) { writeFrame(t, conn, true, responses.RES_CODE_POW_CHALLENGE, challenge) },
+			handlerErr: errHandler,
+			err:        errHandler,
+		},
+		{
+			name: "first message is not a challenge",
+			first: func(conn net.Conn) {
+				writeFrame(t, conn, true, responses.RES_CODE_WISDOM, protocol.StringEncoder(testQuote))
+			},
+			err: ErrUnexpectedResponse,
+		},
+		{
+			name: "server refuses",
+			first: func(conn net.Conn) {
+				writeFrame(t, conn, false, protocol.ERR_CODE_SERVER_BUSY, protocol.ErrorResponse{Code: protocol.ERR_CODE_SERVER_BUSY})
+			},
+			err: ErrCodeServerBusy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Answers a valid solution with a quote.
+			address := listen(t, func(conn net.Conn) {
+				tt.first(conn)
+				msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+				if err != nil {
+					return
+				}
+				solution := protocol.Solution{}
+				if msg.Opcode != requests.OPCODE_SUBMIT_SOLUTION || msg.DecodePayload(&solution) != nil ||
+					protocol.VerifySolution(challenge, solution, time.Now()) != nil {
+					return
+				}
+				writeFrame(t, conn, true, responses.RES_CODE_WISDOM, protocol.StringEncoder(testQuote))
+				io.Copy(io.Discard, conn)
+			})
+
+			var sdk *ServerSDK
+			var handled protocol.Challenge
+			sdk = NewServerSDK(context.Background(), address, testMaxMessageSize, 2*time.Second,
+				WithExpectChallenge(func(c protocol.Challenge) error {
+					handled = c
+					if tt.handlerErr != nil {
+						return tt.handlerErr
+					}
+					solution, err := protocol.SolveChallenge(c, 0)
+					if err != nil {
+						return err
+					}
+					return sdk.SendMessage(true, requests.OPCODE_SUBMIT_SOLUTION, solution)
+				}))
+			err := sdk.OpenConnection()
+			defer sdk.CloseConnection()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("OpenConnection = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if !sdk.closed.Load() {
+					t.Fatal("connection left open after a failed challenge")
+				}
+				return
+			}
+
+			if handled.Nonce != challenge.Nonce {
+				t.Fatal("handler did not get the server's challenge")
+			}
+			msg, err := sdk.PopMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote, _ := protocol.DecodeString(msg.Data); msg.Opcode != responses.RES_CODE_WISDOM || quote != testQuote {
+				t.Fatalf("got opcode %d %q, want the quote", msg.Opcode, quote)
+			}
+		})
+	}
+}
+
+// BenchmarkPopMessages drains 100k queued frames one PopMessage at a time
+// and in PopMessages batches. The frames are queued straight into the
+// receive buffer, so only the draining is timed.
+func BenchmarkPopMessages(b *testing.B) {
+	const queued = 100_000
+
+	frame, err := protocol.BuildRawMessage(true, responses.RES_CODE_WISDOM, protocol.StringEncoder(testQuote))
+	if err != nil {
+		b.Fatal(err)
+	}
+	drains := []struct {
+		name  string
+		drain func(sdk *ServerSDK) (int, error)
+	}{
+		{name: "PopMessage", drain: func(sdk *ServerSDK) (int, error) {
+			_, err := sdk.PopMessage()
+			return 1, err
+		}},
+		{name: "PopMessages(64)", drain: func(sdk *ServerSDK) (int, error) {
+			msgs, err := sdk.PopMessages(64)
+			return len(msgs), err
+		}},
+		{name: "PopMessages(1024)", drain: func(sdk *ServerSDK) (int, error) {
+			msgs, err := sdk.PopMessages(1024)
+			return len(msgs), err
+		}},
+	}
+
+	for _, d := range drains {
+		b.Run(d.name, func(b *testing.B) {
+			sdk := NewServerSDK(context.Background(), "127.0.0.1:1", testMaxMessageSize, time.Second, WithReceiveBuffer(queued))
+
+			b.ReportAllocs()
+			for range b.N {
+				b.StopTimer()
+				for range queued {
+					sdk.messagesCh <- frame
+				}
+				b.StartTimer()
+
+				for drained := 0; drained < queued; {
+					n, err := d.drain(sdk)
+					if err != nil {
+						b.Fatal(err)
+					}
+					drained += n
+				}
+			}
+		})
+	}
+}
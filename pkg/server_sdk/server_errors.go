@@ -0,0 +1,63 @@
+package server_sdk
+
+import (
+	"errors"
+	"fmt"
+	"wordofwisdom/pkg/protocol"
+)
+
+// Errors for the standard server error codes, matched by errors.Is on the
+// error returned by DecodeServerError.
+var (
+	ErrCodeInvalidOpcode        = errors.New("invalid opcode")
+	ErrCodeBadSolution          = errors.New("bad challenge solution")
+	ErrCodeUnsupportedAlgorithm = errors.New("unsupported pow algorithm")
+	ErrCodeRateLimited          = errors.New("rate limited")
+	ErrCodeInvalidSession       = errors.New("invalid session")
+	ErrCodeServerBusy           = errors.New("server busy")
+	ErrCodeUnknownCategory      = errors.New("unknown quote category")
+	ErrCodeInvalidBatch         = errors.New("invalid batch request")
+)
+
+var serverErrorCodes = map[uint32]error{
+	protocol.ERR_CODE_INVALID_OPCODE:          ErrCodeInvalidOpcode,
+	protocol.ERR_CODE_INVALID_CHALLENGE_PROOF: ErrCodeBadSolution,
+	protocol.ERR_CODE_UNSUPPORTED_ALGORITHM:   ErrCodeUnsupportedAlgorithm,
+	protocol.ERR_CODE_RATE_LIMITED:            ErrCodeRateLimited,
+	protocol.ERR_CODE_INVALID_SESSION:         ErrCodeInvalidSession,
+	protocol.ERR_CODE_SERVER_BUSY:             ErrCodeServerBusy,
+	protocol.ERR_CODE_UNKNOWN_CATEGORY:        ErrCodeUnknownCategory,
+	protocol.ERR_CODE_INVALID_BATCH:           ErrCodeInvalidBatch,
+}
+
+// ServerError is a failure response from the server. It matches
+// ErrServerRejected and, for known codes, the matching ErrCode error.
+type ServerError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: code %d", ErrServerRejected, e.Code)
+	}
+	return fmt.Sprintf("%s: code %d: %s", ErrServerRejected, e.Code, e.Message)
+}
+
+func (e *ServerError) Unwrap() []error {
+	if err, ok := serverErrorCodes[e.Code]; ok {
+		return []error{ErrServerRejected, err}
+	}
+	return []error{ErrServerRejected}
+}
+
+// DecodeServerError turns a failure frame into a *ServerError. It returns
+// nil for success frames.
+func DecodeServerError(msg *protocol.RawMessage) error {
+	if !msg.IsError() {
+		return nil
+	}
+
+	code, message := msg.ErrorPayload()
+	return &ServerError{Code: code, Message: message}
+}
@@ -0,0 +1,69 @@
+package server_sdk
+
+import (
+	"errors"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestDecodeServerErrorMapsCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		code uint32
+		want error
+	}{
+		{name: "invalid opcode", code: protocol.ERR_CODE_INVALID_OPCODE, want: ErrCodeInvalidOpcode},
+		{name: "bad solution", code: protocol.ERR_CODE_INVALID_CHALLENGE_PROOF, want: ErrCodeBadSolution},
+		{name: "unsupported algorithm", code: protocol.ERR_CODE_UNSUPPORTED_ALGORITHM, want: ErrCodeUnsupportedAlgorithm},
+		{name: "rate limited", code: protocol.ERR_CODE_RATE_LIMITED, want: ErrCodeRateLimited},
+		{name: "invalid session", code: protocol.ERR_CODE_INVALID_SESSION, want: ErrCodeInvalidSession},
+		{name: "server busy", code: protocol.ERR_CODE_SERVER_BUSY, want: ErrCodeServerBusy},
+		{name: "unknown category", code: protocol.ERR_CODE_UNKNOWN_CATEGORY, want: ErrCodeUnknownCategory},
+		{name: "invalid batch", code: protocol.ERR_CODE_INVALID_BATCH, want: ErrCodeInvalidBatch},
+		{name: "unknown code", code: 9999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := protocol.BuildRawMessage(false, tt.code, protocol.ErrorResponse{Code: tt.code, Message: "detail"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := protocol.ParseRawMessage(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = DecodeServerError(msg)
+			if !errors.Is(err, ErrServerRejected) {
+				t.Fatalf("%v does not match ErrServerRejected", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("%v does not match %v", err, tt.want)
+			}
+			var serverErr *ServerError
+			if !errors.As(err, &serverErr) || serverErr.Code != tt.code || serverErr.Message != "detail" {
+				t.Fatalf("got %#v, want code %d with its message", err, tt.code)
+			}
+			for _, other := range serverErrorCodes {
+				if other != tt.want && errors.Is(err, other) {
+					t.Fatalf("%v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeServerErrorIgnoresSuccess(t *testing.T) {
+	frame, err := protocol.BuildRawMessage(true, protocol.ERR_CODE_RATE_LIMITED, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := protocol.ParseRawMessage(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeServerError(msg); err != nil {
+		t.Fatalf("success frame decoded as %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+)
+
+// Session keeps one connection open for several quotes. When the server
+// hands out a session token, quotes are fetched with it instead of solving
+// a new challenge until it expires.
+type Session struct {
+	client *Client
+	sdk    *ServerSDK
+	token  *protocol.SessionToken
+	// started is set once the first exchange has gone through.
+	started bool
+}
+
+// Session opens a connection the returned handle reuses for every GetWisdom
+// call. The caller must Close it.
+func (c *Client) Session(ctx context.Context) (*Session, error) {
+	sdk := NewServerSDK(ctx, c.cfg.ServerAddress, c.cfg.MaxMessageSizeBytes, c.cfg.PopMessageTimeout, c.cfg.Options...)
+	if err := sdk.OpenConnection(); err != nil {
+		return nil, err
+	}
+
+	return &Session{client: c, sdk: sdk}, nil
+}
+
+// GetWisdom returns a quote, redeeming the session token if there is a
+// valid one and solving a challenge otherwise.
+func (s *Session) GetWisdom(ctx context.Context) (string, error) {
+	if s.token != nil && s.sdk.now().Before(s.token.Expiry) {
+		quote, ok, err := s.redeem(ctx)
+		if err != nil || ok {
+			return quote, err
+		}
+	}
+
+	quote, token, err := s.client.fetchWisdom(ctx, s.sdk, !s.started)
+	s.started = true
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+
+	return quote, nil
+}
+
+func (s *Session) Close() error {
+	return s.sdk.CloseConnection()
+}
+
+// redeem asks for a quote with the session token. It reports false without
+// an error when the server rejected the token. A server that wants the
+// session refreshed answers with a challenge instead, which is solved for
+// the quote and a new token.
+func (s *Session) redeem(ctx context.Context) (string, bool, error) {
+	req := requests.SessionWisdomRequest{Token: *s.token, Category: s.client.cfg.Category}
+	if err := s.sdk.SendMessageContext(ctx, true, requests.OPCODE_REQUEST_SESSION_WISDOM, req); err != nil {
+		return "", false, err
+	}
+
+	msg, err := s.sdk.PopMessageContext(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if err := DecodeServerError(msg); err != nil {
+		if errors.Is(err, ErrCodeInvalidSession) {
+			s.token = nil
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if isChallenge(msg.Opcode) {
+		quote, token, err := s.client.answerChallenge(ctx, s.sdk, msg)
+		if err != nil {
+			return "", false, err
+		}
+		s.token = token
+		return quote, true, nil
+	}
+	if msg.Opcode != responses.RES_CODE_WISDOM {
+		return "", false, ErrUnexpectedResponse
+	}
+
+	wisdom := responses.WisdomResponse{}
+	if err := msg.DecodePayload(&wisdom); err != nil {
+		return "", false, err
+	}
+
+	return wisdom.Quote, true, nil
+}
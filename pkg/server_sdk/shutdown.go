@@ -0,0 +1,68 @@
+package server_sdk
+
+import (
+	"context"
+	"time"
+)
+
+const shutdownPollInterval = 10 * time.Millisecond
+
+// wakeDeadline is a read deadline that has always passed, whatever the clock.
+var wakeDeadline = time.Unix(1, 0)
+
+type ShutdownResult struct {
+	// Drained is how many messages were handed to PopMessage while shutting down.
+	Drained int
+	// Dropped is how many received messages were still undelivered when ctx expired.
+	Dropped int
+}
+
+// Shutdown stops accepting new sends and new frames from the server, waits
+// until already received messages are popped or ctx expires, then closes
+// the connection.
+func (s *ServerSDK) Shutdown(ctx context.Context) (ShutdownResult, error) {
+	s.shuttingDown.Store(true)
+
+	conn := s.getConn()
+	// Wakes the receive loop out of a blocking read without losing frames
+	// that were already assembled.
+	conn.SetReadDeadline(wakeDeadline)
+
+	poll := s.clock.NewTimer(shutdownPollInterval)
+	defer poll.Stop()
+
+	var ctxErr error
+	for s.undelivered() > 0 && ctxErr == nil {
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-poll.C():
+			poll.Reset(shutdownPollInterval)
+		}
+	}
+
+	result := ShutdownResult{
+		Drained: int(s.drained.Load()),
+		Dropped: s.undelivered(),
+	}
+
+	if s.writeBuf != nil {
+		s.writeBuf.Flush()
+	}
+	s.shutdown(ErrConnectionClosed)
+	if err := conn.Close(); err != nil && ctxErr == nil {
+		return result, err
+	}
+
+	return result, ctxErr
+}
+
+func (s *ServerSDK) undelivered() int {
+	return int(s.inflight.Load()) + len(s.messagesCh)
+}
+
+func (s *ServerSDK) countDrained() {
+	if s.shuttingDown.Load() {
+		s.drained.Add(1)
+	}
+}
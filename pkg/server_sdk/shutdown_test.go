@@ -0,0 +1,109 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+func TestShutdownDrainsQueuedMessages(t *testing.T) {
+	const queued = 5
+
+	tests := []struct {
+		name    string
+		pop     bool
+		timeout time.Duration
+		want    ShutdownResult
+		err     error
+	}{
+		{name: "all popped", pop: true, timeout: 5 * time.Second, want: ShutdownResult{Drained: queued}},
+		{name: "nobody pops", timeout: 50 * time.Millisecond, want: ShutdownResult{Dropped: queued}, err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := listen(t, func(conn net.Conn) {
+				for i := range queued {
+					writeFrame(t, conn, true, uint32(i+1), nil)
+				}
+				conn.Read(make([]byte, 1))
+			})
+			sdk := openSDK(t, address, WithReceiveBuffer(queued))
+
+			deadline := time.Now().Add(5 * time.Second)
+			for sdk.undelivered() < queued {
+				if time.Now().After(deadline) {
+					t.Fatalf("only %d of %d messages queued", sdk.undelivered(), queued)
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			type shutdown struct {
+				result ShutdownResult
+				err    error
+			}
+			done := make(chan shutdown, 1)
+			go func() {
+				result, err := sdk.Shutdown(ctx)
+				done <- shutdown{result, err}
+			}()
+
+			if tt.pop {
+				// Only pops after Shutdown started count as drained.
+				for !sdk.shuttingDown.Load() {
+					time.Sleep(time.Millisecond)
+				}
+				for i := range queued {
+					msg, err := sdk.PopMessage()
+					if err != nil {
+						t.Fatalf("pop %d: %v", i, err)
+					}
+					if msg.Opcode != uint32(i+1) {
+						t.Fatalf("pop %d: got opcode %d, want %d", i, msg.Opcode, i+1)
+					}
+				}
+			}
+
+			got := <-done
+			if !errors.Is(got.err, tt.err) {
+				t.Fatalf("shutdown: got %v, want %v", got.err, tt.err)
+			}
+			if got.result != tt.want {
+				t.Fatalf("shutdown: got %+v, want %+v", got.result, tt.want)
+			}
+			if err := sdk.SendMessage(true, requests.OPCODE_REQUEST_WISDOM, nil); !errors.Is(err, ErrShuttingDown) {
+				t.Fatalf("send after shutdown: got %v, want ErrShuttingDown", err)
+			}
+		})
+	}
+}
+
+func TestReceiveLoopExitsOnShutdownWithoutRearming(t *testing.T) {
+	// Sync mode starts no receive loop, so the test can run its own.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sdk := NewServerSDK(ctx, silentServer(t), testMaxMessageSize, time.Minute, WithSyncMode())
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseConnection()
+
+	// As if Shutdown's wake-up deadline had already been overwritten.
+	sdk.shuttingDown.Store(true)
+	exited := make(chan struct{})
+	go func() {
+		sdk.startReceivingMessages()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(readPollInterval / 2):
+		t.Fatal("receive loop re-armed its poll deadline after Shutdown started")
+	}
+}
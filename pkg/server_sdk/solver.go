@@ -0,0 +1,87 @@
+package server_sdk
+
+import (
+	"context"
+	"math"
+	"sync"
+	"wordofwisdom/pkg/protocol"
+)
+
+// SolveParallel shards the counter space of a challenge across workers and
+// returns the first solution found, cancelling the remaining workers and
+// waiting for them to stop. Workers check ctx every
+// protocol.Algorithm.CheckInterval counters. A
+// non-zero maxAttempts caps the counters tried across all workers, after
+// which it returns ErrSolveExhausted.
+func SolveParallel(ctx context.Context, c protocol.Challenge, workers int, maxAttempts uint64) (protocol.Solution, error) {
+	if c.Difficulty < 0 || c.Difficulty > protocol.MaxDifficulty {
+		return protocol.Solution{}, protocol.ErrInvalidDifficulty
+	}
+	if c.Algorithm == protocol.AlgoArgon2id {
+		if err := c.Argon2.Validate(); err != nil {
+			return protocol.Solution{}, err
+		}
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	solutionCh := make(chan protocol.Solution, workers)
+	exhaustedCh := make(chan struct{}, workers)
+	interval := c.Algorithm.CheckInterval()
+
+	for i := range workers {
+		wg.Add(1)
+		go func(start uint64) {
+			defer wg.Done()
+			step := uint64(workers)
+			for counter := start; ; counter += step {
+				if maxAttempts > 0 && counter >= maxAttempts {
+					exhaustedCh <- struct{}{}
+					return
+				}
+
+				if (counter-start)/step%interval == 0 {
+					select {
+					case <-ctx.Done():
+						return
+					default:
+					}
+				}
+
+				solution := protocol.Solution{Nonce: c.Nonce, Counter: counter}
+				if c.Satisfies(solution) {
+					solutionCh <- solution
+					return
+				}
+
+				if counter > math.MaxUint64-step {
+					exhaustedCh <- struct{}{}
+					return
+				}
+			}
+		}(uint64(i))
+	}
+
+	exhausted := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return protocol.Solution{}, ctx.Err()
+		case solution := <-solutionCh:
+			return solution, nil
+		case <-exhaustedCh:
+			exhausted++
+			if exhausted == workers {
+				return protocol.Solution{}, protocol.ErrSolveExhausted
+			}
+		}
+	}
+}
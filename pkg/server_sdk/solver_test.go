@@ -0,0 +1,118 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+func TestSolveParallelMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name        string
+		difficulty  int
+		workers     int
+		maxAttempts uint64
+		err         error
+	}{
+		{name: "difficulty 30 capped, one worker", difficulty: 30, workers: 1, maxAttempts: 1000, err: protocol.ErrSolveExhausted},
+		{name: "difficulty 30 capped, uneven workers", difficulty: 30, workers: 7, maxAttempts: 1000, err: protocol.ErrSolveExhausted},
+		{name: "more workers than attempts", difficulty: 30, workers: 8, maxAttempts: 3, err: protocol.ErrSolveExhausted},
+		{name: "cap above the work needed", difficulty: 4, workers: 4, maxAttempts: 1 << 20},
+		{name: "no cap", difficulty: 4, workers: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := protocol.NewChallenge(tt.difficulty, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			solution, err := SolveParallel(context.Background(), challenge, tt.workers, tt.maxAttempts)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if err := protocol.VerifySolution(challenge, solution, time.Now()); err != nil {
+				t.Fatalf("solution does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestSolveParallelCancelled(t *testing.T) {
+	tests := []struct {
+		name   string
+		algo   protocol.Algorithm
+		params protocol.Argon2Params
+	}{
+		{name: "sha256", algo: protocol.AlgoSHA256},
+		{name: "argon2id", algo: protocol.AlgoArgon2id, params: protocol.DefaultArgon2Params},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := protocol.NewChallenge(protocol.MaxDifficulty, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			challenge.Algorithm = tt.algo
+			challenge.Argon2 = tt.params
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			// Without a cap only the context stops a solve this hard. The
+			// workers have stopped by the time SolveParallel returns, so the
+			// wait only covers the hash each was in.
+			started := time.Now()
+			if _, err := SolveParallel(ctx, challenge, 4, 0); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Fatalf("took %v to stop after a 20ms deadline", elapsed)
+			}
+		})
+	}
+}
+
+// BenchmarkSolveParallel compares the single-threaded solver with one worker
+// per CPU on the same seeded challenges.
+func BenchmarkSolveParallel(b *testing.B) {
+	const difficulty = 22
+	solvers := []struct {
+		name  string
+		solve func(c protocol.Challenge) (protocol.Solution, error)
+	}{
+		{name: "single", solve: func(c protocol.Challenge) (protocol.Solution, error) {
+			return protocol.SolveChallenge(c, 0)
+		}},
+		{name: fmt.Sprintf("workers=%d", runtime.GOMAXPROCS(0)), solve: func(c protocol.Challenge) (protocol.Solution, error) {
+			return SolveParallel(context.Background(), c, runtime.GOMAXPROCS(0), 0)
+		}},
+	}
+
+	for _, s := range solvers {
+		b.Run(s.name, func(b *testing.B) {
+			rng := rand.New(rand.NewSource(42))
+			challenges := make([]protocol.Challenge, b.N)
+			for i := range challenges {
+				challenges[i] = protocol.Challenge{Difficulty: difficulty, Expiry: time.Now().Add(time.Hour)}
+				rng.Read(challenges[i].Nonce[:])
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				if _, err := s.solve(challenges[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package server_sdk
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type Stats struct {
+	MessagesSent     uint64
+	MessagesReceived uint64
+	BytesSent        uint64
+	BytesReceived    uint64
+	Reconnects       uint64
+	// MessagesDropped counts messages discarded by the overflow policy.
+	MessagesDropped uint64
+	// RTT is a smoothed round-trip estimate from heartbeats; zero until the
+	// first pong arrives.
+	RTT time.Duration
+}
+
+type statsCounters struct {
+	messagesSent     atomic.Uint64
+	messagesReceived atomic.Uint64
+	bytesSent        atomic.Uint64
+	bytesReceived    atomic.Uint64
+	reconnects       atomic.Uint64
+	messagesDropped  atomic.Uint64
+	rtt              atomic.Int64
+}
+
+// Stats returns a snapshot of connection counters. Safe for concurrent use.
+func (s *ServerSDK) Stats() Stats {
+	return Stats{
+		MessagesSent:     s.stats.messagesSent.Load(),
+		MessagesReceived: s.stats.messagesReceived.Load(),
+		BytesSent:        s.stats.bytesSent.Load(),
+		BytesReceived:    s.stats.bytesReceived.Load(),
+		Reconnects:       s.stats.reconnects.Load(),
+		MessagesDropped:  s.stats.messagesDropped.Load(),
+		RTT:              time.Duration(s.stats.rtt.Load()),
+	}
+}
+
+func (c *statsCounters) recordSent(bytes int) {
+	c.messagesSent.Add(1)
+	c.bytesSent.Add(uint64(bytes))
+}
+
+func (c *statsCounters) recordReceived(bytes int) {
+	c.messagesReceived.Add(1)
+	c.bytesReceived.Add(uint64(bytes))
+}
+
+// recordRTT folds a sample into the estimate the way TCP does (alpha = 1/8).
+func (c *statsCounters) recordRTT(sample time.Duration) {
+	for {
+		old := c.rtt.Load()
+		updated := int64(sample)
+		if old != 0 {
+			updated = old + (int64(sample)-old)/8
+		}
+		if c.rtt.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"wordofwisdom/pkg/protocol"
+)
+
+var (
+	ErrSyncMode    = errors.New("not available in sync mode")
+	ErrNotSyncMode = errors.New("sdk is not in sync mode")
+)
+
+// ReadMessage reads the next message from the server on the caller's
+// goroutine. It is only available WithSyncMode. Timeouts come from
+// SetReadDeadline and the SDK context; after a timeout the partial frame is
+// kept and the next call resumes it.
+func (s *ServerSDK) ReadMessage() (*protocol.RawMessage, error) {
+	if !s.syncMode {
+		return nil, ErrNotSyncMode
+	}
+
+	s.popMu.Lock()
+	defer s.popMu.Unlock()
+
+	if s.closed.Load() {
+		return nil, closedError()
+	}
+
+	conn := s.getConn()
+	stop := context.AfterFunc(s.ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	for {
+		frame, err := s.syncReader.ReadFrame()
+		if err != nil {
+			if ctxErr := s.ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			sdkErr := classifyReadError(err)
+			if sdkErr.Kind == ErrorKindProtocol {
+				s.shutdown(protocol.ErrMessageTooLarge)
+				conn.Close()
+			} else if sdkErr.Fatal {
+				s.shutdown(errors.Join(ErrConnectionClosed, err))
+				conn.Close()
+			}
+			return nil, sdkErr
+		}
+
+		s.stats.recordReceived(len(frame))
+		s.tapFrame(DirectionReceived, frame)
+
+		opcode, _ := protocol.PeekOpcode(frame)
+		s.logger.Debug("Received message from server",
+			slog.Int("bytes", len(frame)),
+			slog.String("remote_addr", conn.RemoteAddr().String()),
+			slog.Uint64("opcode", uint64(opcode)),
+		)
+
+		if s.handleControlFrame(frame) {
+			continue
+		}
+		s.touch()
+
+		return protocol.ParseRawMessage(append([]byte(nil), frame...))
+	}
+}
@@ -0,0 +1,120 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/protocol/requests"
+	"wordofwisdom/pkg/protocol/responses"
+	"wordofwisdom/pkg/server"
+)
+
+func TestSyncModeHandshake(t *testing.T) {
+	cfg := startServer(t, server.Config{})
+	goroutines := runtime.NumGoroutine()
+	sdk := NewServerSDK(context.Background(), cfg.ServerAddress, cfg.MaxMessageSizeBytes, cfg.PopMessageTimeout,
+		append(cfg.Options, WithSyncMode())...)
+	if err := sdk.OpenConnection(); err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseConnection()
+	// The server serves the accepted pipe on one new goroutine; the SDK
+	// must not add any of its own.
+	if got := runtime.NumGoroutine(); got > goroutines+1 {
+		t.Fatalf("%d goroutines after OpenConnection, want at most %d", got, goroutines+1)
+	}
+
+	msg, err := sdk.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := protocol.Challenge{}
+	if msg.Opcode != responses.RES_CODE_POW_CHALLENGE || msg.DecodePayload(&challenge) != nil {
+		t.Fatalf("got opcode %d, want a challenge", msg.Opcode)
+	}
+	solution, err := protocol.SolveChallenge(challenge, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sdk.SendMessage(true, requests.OPCODE_SUBMIT_SOLUTION, solution); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err = sdk.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wisdom := responses.WisdomResponse{}
+	if err := msg.DecodePayload(&wisdom); err != nil || wisdom.Quote != testQuote {
+		t.Fatalf("got opcode %d %q (%v), want the quote", msg.Opcode, wisdom.Quote, err)
+	}
+
+	// Closing ends the read path too.
+	sdk.CloseConnection()
+	if _, err := sdk.ReadMessage(); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("read after close = %v, want ErrConnectionClosed", err)
+	}
+}
+
+func TestSyncModeGuards(t *testing.T) {
+	cfg := startServer(t, server.Config{})
+
+	tests := []struct {
+		name string
+		sync bool
+		call func(sdk *ServerSDK) error
+		err  error
+	}{
+		{name: "ReadMessage without sync mode", call: func(sdk *ServerSDK) error {
+			_, err := sdk.ReadMessage()
+			return err
+		}, err: ErrNotSyncMode},
+		{name: "PopMessage in sync mode", sync: true, call: func(sdk *ServerSDK) error {
+			_, err := sdk.PopMessage()
+			return err
+		}, err: ErrSyncMode},
+		{name: "Request in sync mode", sync: true, call: func(sdk *ServerSDK) error {
+			_, err := sdk.Request(context.Background(), requests.OPCODE_REQUEST_WISDOM, nil)
+			return err
+		}, err: ErrSyncMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := cfg.Options
+			if tt.sync {
+				opts = append(opts, WithSyncMode())
+			}
+			sdk := NewServerSDK(context.Background(), cfg.ServerAddress, cfg.MaxMessageSizeBytes, cfg.PopMessageTimeout, opts...)
+			if err := sdk.OpenConnection(); err != nil {
+				t.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			if err := tt.call(sdk); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSyncModeServerHangUp(t *testing.T) {
+	sdk := pipeSDK(t, func(conn net.Conn) {
+		writeFrame(t, conn, true, 1, protocol.StringEncoder("last words"))
+	}, WithSyncMode())
+
+	if _, err := sdk.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := sdk.ReadMessage()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("read after hang up = %v, want EOF", err)
+	}
+	if !sdk.closed.Load() {
+		t.Fatal("connection not marked closed after the server hung up")
+	}
+}
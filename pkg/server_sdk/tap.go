@@ -0,0 +1,33 @@
+package server_sdk
+
+type Direction int
+
+const (
+	DirectionSent Direction = iota
+	DirectionReceived
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionSent:
+		return "sent"
+	case DirectionReceived:
+		return "received"
+	default:
+		return "unknown"
+	}
+}
+
+type FrameTap func(dir Direction, frame []byte)
+
+// tapFrame hands the tap its own copy so it cannot corrupt SDK buffers.
+func (s *ServerSDK) tapFrame(dir Direction, frame []byte) {
+	s.captureFrame(dir, frame)
+	if s.frameTap == nil {
+		return
+	}
+
+	frameCopy := make([]byte, len(frame))
+	copy(frameCopy, frame)
+	s.frameTap(dir, frameCopy)
+}
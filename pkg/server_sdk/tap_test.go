@@ -0,0 +1,75 @@
+package server_sdk
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+)
+
+type tapped struct {
+	dir   Direction
+	frame []byte
+}
+
+func TestFrameTapSeesBothDirections(t *testing.T) {
+	// Echoes every frame back.
+	address := listen(t, func(conn net.Conn) {
+		for {
+			msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+			if err != nil {
+				return
+			}
+			conn.Write(msg.Frame)
+		}
+	})
+
+	var mu sync.Mutex
+	var frames []tapped
+	sdk := openSDK(t, address, WithFrameTap(func(dir Direction, frame []byte) {
+		mu.Lock()
+		frames = append(frames, tapped{dir: dir, frame: bytes.Clone(frame)})
+		mu.Unlock()
+		// Scribbling on the copy must not reach the SDK.
+		clear(frame)
+	}))
+
+	want, err := protocol.BuildRawMessage(true, 7, protocol.StringEncoder("tapped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sdk.SendMessage(true, 7, protocol.StringEncoder("tapped")); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sdk.PopMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Frame, want) {
+		t.Fatalf("popped frame %x was altered by the tap, want %x", msg.Frame, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	tests := []struct {
+		name string
+		dir  Direction
+	}{
+		{name: "sent", dir: DirectionSent},
+		{name: "received", dir: DirectionReceived},
+	}
+	if len(frames) != len(tests) {
+		t.Fatalf("tap fired %d times, want %d", len(frames), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if frames[i].dir != tt.dir || frames[i].dir.String() != tt.name {
+				t.Fatalf("tap %d: direction %v, want %v", i, frames[i].dir, tt.dir)
+			}
+			if !bytes.Equal(frames[i].frame, want) {
+				t.Fatalf("tap %d: frame %x, want %x", i, frames[i].frame, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package server_sdk
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer starts spans around OpenConnection, the PoW handshake, solving and
+// Request. Its shape follows OpenTelemetry so an adapter over an otel
+// trace.Tracer is a few lines, while the SDK itself depends on no tracing
+// library.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...slog.Attr)
+	RecordError(err error)
+	End()
+}
+
+// Span names.
+const (
+	SpanOpenConnection = "server_sdk.OpenConnection"
+	SpanHandshake      = "server_sdk.Handshake"
+	SpanSolve          = "server_sdk.Solve"
+	SpanRequest        = "server_sdk.Request"
+)
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// sdkSpan adds the bytes sent and received on the connection while it was
+// open. Traffic of concurrent callers on the same SDK is included.
+type sdkSpan struct {
+	Span
+	sdk      *ServerSDK
+	sent     uint64
+	received uint64
+}
+
+func (s *ServerSDK) startSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *sdkSpan) {
+	ctx, span := s.tracer.Start(ctx, name)
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+
+	return ctx, &sdkSpan{
+		Span:     span,
+		sdk:      s,
+		sent:     s.stats.bytesSent.Load(),
+		received: s.stats.bytesReceived.Load(),
+	}
+}
+
+func (sp *sdkSpan) end(err error) {
+	sp.SetAttributes(
+		slog.Uint64("bytes_sent", sp.sdk.stats.bytesSent.Load()-sp.sent),
+		slog.Uint64("bytes_received", sp.sdk.stats.bytesReceived.Load()-sp.received),
+	)
+	if err != nil {
+		sp.RecordError(err)
+	}
+	sp.End()
+}
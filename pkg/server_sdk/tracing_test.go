@@ -0,0 +1,172 @@
+package server_sdk
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"wordofwisdom/pkg/protocol"
+	"wordofwisdom/pkg/server"
+)
+
+type spanKey struct{}
+
+// recordingTracer keeps every span it starts, in start order.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	tracer *recordingTracer
+	name   string
+	parent string
+	attrs  map[string]slog.Value
+	err    error
+	ended  bool
+}
+
+func (tr *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{tracer: tr, name: name, attrs: map[string]slog.Value{}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+
+	tr.mu.Lock()
+	tr.spans = append(tr.spans, span)
+	tr.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (sp *recordedSpan) SetAttributes(attrs ...slog.Attr) {
+	sp.tracer.mu.Lock()
+	defer sp.tracer.mu.Unlock()
+	for _, attr := range attrs {
+		sp.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (sp *recordedSpan) RecordError(err error) {
+	sp.tracer.mu.Lock()
+	defer sp.tracer.mu.Unlock()
+	sp.err = err
+}
+
+func (sp *recordedSpan) End() {
+	sp.tracer.mu.Lock()
+	defer sp.tracer.mu.Unlock()
+	sp.ended = true
+}
+
+// span returns the only span named name.
+func (tr *recordingTracer) span(t *testing.T, name string) *recordedSpan {
+	t.Helper()
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var found *recordedSpan
+	for _, span := range tr.spans {
+		if span.name == name {
+			if found != nil {
+				t.Fatalf("span %s started twice", name)
+			}
+			found = span
+		}
+	}
+	if found == nil {
+		t.Fatalf("no span %s", name)
+	}
+	if !found.ended {
+		t.Fatalf("span %s never ended", name)
+	}
+	return found
+}
+
+func TestTracerRecordsClientSpans(t *testing.T) {
+	tracer := &recordingTracer{}
+	cfg := startServer(t, server.Config{})
+	cfg.Options = append(cfg.Options, WithTracer(tracer))
+	if _, err := NewClient(cfg).GetWisdom(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		parent string
+		// attrs must be present; a non-nil value must also match.
+		attrs map[string]any
+	}{
+		{name: SpanOpenConnection, attrs: map[string]any{"remote_addr": "pipe"}},
+		{name: SpanHandshake, attrs: map[string]any{"bytes_sent": nil, "bytes_received": nil}},
+		{name: SpanSolve, parent: SpanHandshake, attrs: map[string]any{
+			"difficulty":     int64(4),
+			"algorithm":      protocol.AlgoSHA256.String(),
+			"solve_duration": nil,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := tracer.span(t, tt.name)
+			if span.parent != tt.parent {
+				t.Fatalf("parent %q, want %q", span.parent, tt.parent)
+			}
+			if span.err != nil {
+				t.Fatalf("recorded error %v", span.err)
+			}
+			for key, want := range tt.attrs {
+				got, ok := span.attrs[key]
+				if !ok {
+					t.Fatalf("no %s attribute in %v", key, span.attrs)
+				}
+				if want != nil && got.Any() != want {
+					t.Fatalf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+
+	handshake := tracer.span(t, SpanHandshake)
+	if handshake.attrs["bytes_sent"].Uint64() == 0 || handshake.attrs["bytes_received"].Uint64() == 0 {
+		t.Fatalf("handshake moved no bytes: %v", handshake.attrs)
+	}
+}
+
+func TestTracerRecordsRequestSpan(t *testing.T) {
+	// Echoes every frame back, request id included.
+	address := listen(t, func(conn net.Conn) {
+		for {
+			msg, err := protocol.ReadMessage(conn, testMaxMessageSize)
+			if err != nil {
+				return
+			}
+			conn.Write(msg.Frame)
+		}
+	})
+	tracer := &recordingTracer{}
+	sdk := openSDK(t, address, WithTracer(tracer))
+
+	if _, err := sdk.Request(context.Background(), 9, protocol.StringEncoder("traced")); err != nil {
+		t.Fatal(err)
+	}
+	span := tracer.span(t, SpanRequest)
+	if got := span.attrs["opcode"].Uint64(); got != 9 {
+		t.Fatalf("opcode attribute %d, want 9", got)
+	}
+	frameSize := uint64(protocol.HeaderSize + protocol.StringEncoder("traced").EncodedLen())
+	if span.attrs["bytes_sent"].Uint64() != frameSize || span.attrs["bytes_received"].Uint64() != frameSize {
+		t.Fatalf("bytes %v, want %d each way", span.attrs, frameSize)
+	}
+}
+
+func TestTracerRecordsOpenConnectionError(t *testing.T) {
+	tracer := &recordingTracer{}
+	sdk := NewServerSDK(context.Background(), "127.0.0.1", testMaxMessageSize, 0, WithTracer(tracer))
+	err := sdk.OpenConnection()
+	if err == nil {
+		t.Fatal("OpenConnection succeeded on a malformed address")
+	}
+	if span := tracer.span(t, SpanOpenConnection); span.err != err {
+		t.Fatalf("span recorded %v, want %v", span.err, err)
+	}
+}
@@ -0,0 +1,77 @@
+package server_sdk
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Transport establishes the connection the SDK talks over. Without one the
+// SDK dials the server address with the configured network, TLS settings
+// and dial timeout.
+type Transport interface {
+	Dial(ctx context.Context) (net.Conn, error)
+}
+
+// PipeTransport connects SDKs to a server in the same process over
+// net.Pipe, without sockets. It is also a net.Listener: hand it to the
+// server's Serve and every Dial shows up there as an accepted connection.
+type PipeTransport struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewPipeTransport() *PipeTransport {
+	return &PipeTransport{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Dial waits until the server side accepts the connection.
+func (p *PipeTransport) Dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case p.conns <- server:
+		return client, nil
+	case <-ctx.Done():
+	case <-p.done:
+	}
+
+	client.Close()
+	server.Close()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, net.ErrClosed
+}
+
+func (p *PipeTransport) Accept() (net.Conn, error) {
+	select {
+	case conn := <-p.conns:
+		return conn, nil
+	case <-p.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting connections. Connections already established stay
+// open.
+func (p *PipeTransport) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+
+	return nil
+}
+
+func (p *PipeTransport) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -0,0 +1,72 @@
+package server_sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPipeTransportDial(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// closed closes the transport before dialling.
+		closed bool
+		err    error
+	}{
+		{name: "closed transport", ctx: context.Background(), closed: true, err: net.ErrClosed},
+		{name: "cancelled ctx", ctx: cancelled, err: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := NewPipeTransport()
+			if tt.closed {
+				transport.Close()
+			}
+			// Nobody accepts, so only the transport or ctx can end the dial.
+			if _, err := transport.Dial(tt.ctx); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPipeTransportConnectsDialToAccept(t *testing.T) {
+	transport := NewPipeTransport()
+	defer transport.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := transport.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	client, err := transport.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server := <-accepted
+	defer server.Close()
+
+	go client.Write([]byte("ping"))
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 4)
+	if _, err := server.Read(buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("read %q, %v; want ping", buf, err)
+	}
+
+	transport.Close()
+	if _, err := transport.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Accept after Close = %v, want net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,93 @@
+package server_sdk
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// writeFlushDelay is how long a partially filled write buffer may hold
+// frames before it is flushed.
+const writeFlushDelay = 5 * time.Millisecond
+
+// writeBuffer coalesces frames into fewer writes on the connection. It is
+// flushed when full, writeFlushDelay after the first buffered frame, or on
+// Flush.
+type writeBuffer struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	w       *bufio.Writer
+	pending bool
+	clock   Clock
+	onError func(conn net.Conn, err error)
+}
+
+func newWriteBuffer(size int, clock Clock, onError func(conn net.Conn, err error)) *writeBuffer {
+	return &writeBuffer{
+		w:       bufio.NewWriterSize(nil, size),
+		clock:   clock,
+		onError: onError,
+	}
+}
+
+// reset points the buffer at a new connection. Frames still buffered for
+// the old one are dropped.
+func (b *writeBuffer) reset(conn net.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conn = conn
+	b.w.Reset(conn)
+}
+
+func (b *writeBuffer) Write(frame []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n, err := b.w.Write(frame)
+	if err == nil && b.w.Buffered() > 0 && !b.pending {
+		b.pending = true
+		b.clock.AfterFunc(writeFlushDelay, b.flushLater)
+	}
+	return n, err
+}
+
+func (b *writeBuffer) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Flush()
+}
+
+func (b *writeBuffer) flushLater() {
+	b.mu.Lock()
+	b.pending = false
+	conn := b.conn
+	err := b.w.Flush()
+	b.mu.Unlock()
+
+	if err != nil {
+		b.onError(conn, err)
+	}
+}
+
+// Flush writes out frames held back by WithWriteBuffer. Without a write
+// buffer it does nothing.
+func (s *ServerSDK) Flush() error {
+	if s.writeBuf == nil {
+		return nil
+	}
+	if err := s.writeBuf.Flush(); err != nil {
+		s.markBroken(s.getConn())
+		return err
+	}
+	return nil
+}
+
+func (s *ServerSDK) flushFailed(conn net.Conn, err error) {
+	s.logger.Error("Failed to flush buffered messages to server",
+		slog.String("remote_addr", conn.RemoteAddr().String()),
+		slog.Any("error", err),
+	)
+	s.markBroken(conn)
+}
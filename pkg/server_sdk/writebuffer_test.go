@@ -0,0 +1,61 @@
+package server_sdk
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"wordofwisdom/pkg/protocol/requests"
+)
+
+// benchWriteBufferSize is the write buffer of the buffered send benchmark.
+const benchWriteBufferSize = 32 << 10
+
+// BenchmarkSendMessage sends empty frames to a loopback server that
+// discards them, with and without a write buffer.
+func BenchmarkSendMessage(b *testing.B) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "unbuffered"},
+		{name: "buffered", opts: []Option{WithWriteBuffer(benchWriteBufferSize)}},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer ln.Close()
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			sdk := NewServerSDK(ctx, ln.Addr().String(), 1024, 0, tt.opts...)
+			if err := sdk.OpenConnection(); err != nil {
+				b.Fatal(err)
+			}
+			defer sdk.CloseConnection()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := sdk.SendMessage(true, requests.OPCODE_REQUEST_WISDOM, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := sdk.Flush(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}